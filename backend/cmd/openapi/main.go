@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"tourism_recommendor/openapi"
+)
+
+func main() {
+	root := flag.String("root", ".", "backend root directory containing the annotated handlers")
+	out := flag.String("o", "docs/openapi.json", "output file")
+	flag.Parse()
+
+	// Build the document from the handler annotations
+	dirs := make([]string, 0, len(openapi.SourceDirs))
+	for _, dir := range openapi.SourceDirs {
+		dirs = append(dirs, filepath.Join(*root, dir))
+	}
+	doc, err := openapi.Generate(dirs...)
+	if err != nil {
+		log.Fatalf("Failed to generate OpenAPI document: %v", err)
+	}
+
+	data, err := openapi.Marshal(doc)
+	if err != nil {
+		log.Fatalf("Failed to encode OpenAPI document: %v", err)
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	log.Printf("Wrote %s (%d paths)", *out, doc.Paths.Len())
+}
@@ -6,20 +6,23 @@ import (
 	"os"
 
 	"tourism_recommendor/config"
+	"tourism_recommendor/logging"
 	"tourism_recommendor/models"
-
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+	// Load configuration from defaults, config file, .env and environment
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Route log output through the redacting structured logger
+	logging.Setup(cfg.Log.Level, "text")
+
 	// Initialize database
 	log.Println("Initializing database connection...")
-	if err := config.InitDatabaseFromEnv(); err != nil {
+	if err := config.InitDatabase(cfg.Database); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer config.CloseDatabase()
@@ -66,7 +69,6 @@ func main() {
 
 			log.Printf("✓ Admin user created successfully!")
 			log.Printf("  Username: %s", newAdmin.Username)
-			log.Printf("  Password: %s", password)
 			log.Printf("  Email: %s", newAdmin.Email)
 			log.Printf("  Role: %s", newAdmin.Role)
 			log.Println("\n⚠️  IMPORTANT: Please change the default password after first login!")
@@ -89,7 +91,6 @@ func main() {
 
 	log.Printf("✓ Password updated successfully!")
 	log.Printf("  Username: %s", admin.Username)
-	log.Printf("  New Password: %s", password)
 	log.Printf("  Email: %s", admin.Email)
 	log.Printf("  Role: %s", admin.Role)
 	log.Println("\n⚠️  IMPORTANT: Please change the default password after first login!")
@@ -3,41 +3,38 @@ package main
 import (
 	"fmt"
 	"log"
-	"os"
 
 	"tourism_recommendor/config"
+	"tourism_recommendor/logging"
 	"tourism_recommendor/models"
-
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+	// Load configuration from defaults, config file, .env and environment
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Route log output through the redacting structured logger
+	logging.Setup(cfg.Log.Level, "text")
+
 	// Initialize database
 	log.Println("Initializing database connection...")
-	if err := config.InitDatabaseFromEnv(); err != nil {
+	if err := config.InitDatabase(cfg.Database); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer config.CloseDatabase()
 
 	// Auto-migrate database tables
 	log.Println("Running database migrations...")
-	if err := config.DB.AutoMigrate(
-		&models.Admin{},
-		&models.Region{},
-		&models.Recommendor{},
-		&models.Destination{},
-	); err != nil {
+	if err := config.DB.AutoMigrate(models.All()...); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 	log.Println("Database migrations completed successfully")
 
 	// Create default admin user
-	if err := createDefaultAdmin(); err != nil {
+	if err := createDefaultAdmin(cfg.Admin); err != nil {
 		log.Fatalf("Failed to create default admin: %v", err)
 	}
 
@@ -45,22 +42,10 @@ func main() {
 }
 
 // createDefaultAdmin creates a default super admin user if it doesn't exist
-func createDefaultAdmin() error {
-	// Get default admin credentials from environment or use defaults
-	defaultUsername := os.Getenv("DEFAULT_ADMIN_USERNAME")
-	if defaultUsername == "" {
-		defaultUsername = "admin"
-	}
-
-	defaultPassword := os.Getenv("DEFAULT_ADMIN_PASSWORD")
-	if defaultPassword == "" {
-		defaultPassword = "admin123456"
-	}
-
-	defaultEmail := os.Getenv("DEFAULT_ADMIN_EMAIL")
-	if defaultEmail == "" {
-		defaultEmail = "admin@tourism.com"
-	}
+func createDefaultAdmin(adminConfig config.AdminConfig) error {
+	defaultUsername := adminConfig.Username
+	defaultPassword := adminConfig.Password.Value()
+	defaultEmail := adminConfig.Email
 
 	// Check if admin already exists
 	var existingAdmin models.Admin
@@ -97,7 +82,7 @@ func createDefaultAdmin() error {
 
 	log.Printf("✓ Default admin user created successfully")
 	log.Printf("  Username: %s", defaultUsername)
-	log.Printf("  Password: %s", defaultPassword)
+	log.Printf("  Password: %s", adminConfig.Password)
 	log.Printf("  Email: %s", defaultEmail)
 	log.Printf("  Role: %s", admin.Role)
 	log.Println("\n⚠️  IMPORTANT: Please change the default password after first login!")
@@ -0,0 +1,598 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tourism_recommendor/i18n"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// hexColorPattern matches the #rgb and #rrggbb colors accepted for QR codes
+var hexColorPattern = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// DefaultJWTSecret is the placeholder JWT secret that must never be used in release mode
+const DefaultJWTSecret = "your-secret-key-change-this-in-production"
+
+// Secret is a string value that is redacted whenever it is printed or serialized
+type Secret string
+
+// String returns a redacted representation of the secret
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "***"
+}
+
+// Value returns the raw secret value
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// MarshalJSON redacts the secret when encoded as JSON
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(s.String())), nil
+}
+
+// MarshalYAML redacts the secret when encoded as YAML
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// Config holds the complete application configuration
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	QRCode   QRCodeConfig   `yaml:"qrcode"`
+	Admin    AdminConfig    `yaml:"admin"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	I18n     I18nConfig     `yaml:"i18n"`
+	OpenAPI  OpenAPIConfig  `yaml:"openapi"`
+}
+
+// ServerConfig holds HTTP server parameters
+type ServerConfig struct {
+	Port string `yaml:"port"`
+	Mode string `yaml:"mode"` // debug, release, test
+
+	// ShutdownDrainDelay keeps serving after readiness starts failing so load balancers can drain
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay"`
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout"`
+}
+
+// JWTConfig holds token signing parameters
+type JWTConfig struct {
+	Secret            Secret        `yaml:"secret"`
+	Expiration        time.Duration `yaml:"expiration"`
+	TouristExpiration time.Duration `yaml:"tourist_expiration"` // tokens of Mini Program users
+}
+
+// QRCodeConfig holds QR code and WeChat Mini Program parameters
+type QRCodeConfig struct {
+	BaseURL     string        `yaml:"base_url"`
+	WxAppID     string        `yaml:"wx_app_id"`
+	WxAppPath   string        `yaml:"wx_app_path"`
+	WxAppSecret Secret        `yaml:"wx_app_secret"`
+	WxAPIURL    string        `yaml:"wx_api_url"` // WeChat API root, changed for proxies and test servers
+	WxTimeout   time.Duration `yaml:"wx_timeout"` // per WeChat API request
+	WxRetries   int           `yaml:"wx_retries"` // extra attempts after a transient WeChat API failure
+	Level       string        `yaml:"level"`      // error correction level: L, M, Q or H
+	Foreground  string        `yaml:"foreground"` // module color as #rrggbb
+	Background  string        `yaml:"background"` // background color as #rrggbb
+	LogoPath    string        `yaml:"logo_path"`  // image drawn in the center of QR codes, optional
+	BadgeFont   string        `yaml:"badge_font"` // TrueType font for badge text, replaces the embedded CJK font
+}
+
+// AdminConfig holds the default admin account seeded at startup
+type AdminConfig struct {
+	Username string `yaml:"username"`
+	Password Secret `yaml:"password"`
+	Email    string `yaml:"email"`
+}
+
+// LogConfig holds structured logging parameters
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, error; derived from GIN_MODE when empty
+	Format string `yaml:"format"` // json, text
+}
+
+// I18nConfig holds localization parameters
+type I18nConfig struct {
+	DefaultLocale string `yaml:"default_locale"` // used when Accept-Language matches no supported locale
+}
+
+// OpenAPIConfig holds OpenAPI contract validation parameters
+type OpenAPIConfig struct {
+	Validation string `yaml:"validation"` // off, log; derived from GIN_MODE when empty
+}
+
+// TracingConfig holds OpenTelemetry tracing parameters
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Endpoint    string  `yaml:"endpoint"` // OTLP/HTTP collector host:port
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// MetricsConfig holds Prometheus endpoint parameters
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	Token   Secret `yaml:"token"` // Optional bearer token required to scrape metrics
+}
+
+// Default returns a configuration populated with development defaults
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            "8080",
+			Mode:            "debug",
+			ShutdownTimeout: 10 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    "5432",
+			User:    "postgres",
+			DBName:  "tourism_recommender",
+			SSLMode: "disable",
+
+			MaxOpenConns:       10,
+			MaxIdleConns:       5,
+			ConnMaxLifetime:    30 * time.Minute,
+			ConnMaxIdleTime:    5 * time.Minute,
+			ConnectRetries:     5,
+			ConnectRetryDelay:  time.Second,
+			SlowQueryThreshold: 200 * time.Millisecond,
+		},
+		JWT: JWTConfig{
+			Secret:            DefaultJWTSecret,
+			Expiration:        24 * time.Hour,
+			TouristExpiration: 30 * 24 * time.Hour,
+		},
+		QRCode: QRCodeConfig{
+			BaseURL:    "http://localhost:8080",
+			WxAppID:    "your_miniprogram_appid",
+			WxAppPath:  "/",
+			WxAPIURL:   "https://api.weixin.qq.com",
+			WxTimeout:  10 * time.Second,
+			WxRetries:  2,
+			Level:      "M",
+			Foreground: "#000000",
+			Background: "#ffffff",
+		},
+		Admin: AdminConfig{
+			Username: "admin",
+			Password: "admin123456",
+			Email:    "admin@tourism.com",
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Log: LogConfig{
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "tourism-recommender-api",
+			SampleRatio: 1,
+		},
+		I18n: I18nConfig{
+			DefaultLocale: i18n.LocaleZH,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, an optional YAML file, the .env file
+// and environment variables (in increasing order of precedence), then validates it.
+// The YAML file is read from CONFIG_FILE, or config.yaml in the working directory if present.
+func Load() (*Config, error) {
+	// .env never overrides variables that are already set in the environment
+	_ = godotenv.Load()
+
+	cfg := Default()
+
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		if _, err := os.Stat("config.yaml"); err == nil {
+			path = "config.yaml"
+		}
+	}
+	if path != "" {
+		if err := cfg.loadYAML(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	// SQL statement logging follows the server mode unless set explicitly
+	if cfg.Database.LogLevel == "" {
+		cfg.Database.LogLevel = defaultDBLogLevel(cfg.Server.Mode)
+	}
+	if cfg.Log.Level == "" {
+		cfg.Log.Level = defaultLogLevel(cfg.Server.Mode)
+	}
+	if cfg.OpenAPI.Validation == "" {
+		cfg.OpenAPI.Validation = defaultContractValidation(cfg.Server.Mode)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadYAML overlays values from a YAML file onto the configuration
+func (c *Config) loadYAML(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv overlays values from environment variables onto the configuration
+func (c *Config) loadEnv() error {
+	setString(&c.Server.Port, "SERVER_PORT")
+	setString(&c.Server.Mode, "GIN_MODE")
+	if err := setDuration(&c.Server.ShutdownDrainDelay, "SHUTDOWN_DRAIN_DELAY"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Server.ShutdownTimeout, "SHUTDOWN_TIMEOUT"); err != nil {
+		return err
+	}
+
+	setSecret(&c.Database.URL, "DATABASE_URL")
+	setString(&c.Database.Host, "DB_HOST")
+	setString(&c.Database.Port, "DB_PORT")
+	setString(&c.Database.User, "DB_USER")
+	setSecret(&c.Database.Password, "DB_PASSWORD")
+	setString(&c.Database.DBName, "DB_NAME")
+	setString(&c.Database.SSLMode, "DB_SSLMODE")
+	setString(&c.Database.LogLevel, "DB_LOG_LEVEL")
+
+	if err := setInt(&c.Database.MaxOpenConns, "DB_MAX_OPEN_CONNS"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Database.MaxIdleConns, "DB_MAX_IDLE_CONNS"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Database.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Database.ConnMaxIdleTime, "DB_CONN_MAX_IDLE_TIME"); err != nil {
+		return err
+	}
+	if err := setInt(&c.Database.ConnectRetries, "DB_CONNECT_RETRIES"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Database.ConnectRetryDelay, "DB_CONNECT_RETRY_DELAY"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.Database.SlowQueryThreshold, "DB_SLOW_QUERY_THRESHOLD"); err != nil {
+		return err
+	}
+
+	setSecret(&c.JWT.Secret, "JWT_SECRET")
+	if err := setDuration(&c.JWT.Expiration, "JWT_EXPIRATION"); err != nil {
+		return err
+	}
+	if err := setDuration(&c.JWT.TouristExpiration, "JWT_TOURIST_EXPIRATION"); err != nil {
+		return err
+	}
+
+	setString(&c.QRCode.BaseURL, "BASE_URL")
+	setString(&c.QRCode.WxAppID, "WX_APP_ID")
+	setString(&c.QRCode.WxAppPath, "WX_APP_PATH")
+	setSecret(&c.QRCode.WxAppSecret, "WX_APP_SECRET")
+	setString(&c.QRCode.WxAPIURL, "WX_API_URL")
+	if err := setDuration(&c.QRCode.WxTimeout, "WX_TIMEOUT"); err != nil {
+		return err
+	}
+	if err := setInt(&c.QRCode.WxRetries, "WX_RETRIES"); err != nil {
+		return err
+	}
+	setString(&c.QRCode.Level, "QRCODE_LEVEL")
+	setString(&c.QRCode.Foreground, "QRCODE_FOREGROUND")
+	setString(&c.QRCode.Background, "QRCODE_BACKGROUND")
+	setString(&c.QRCode.LogoPath, "QRCODE_LOGO_PATH")
+	setString(&c.QRCode.BadgeFont, "BADGE_FONT_PATH")
+
+	setString(&c.Admin.Username, "DEFAULT_ADMIN_USERNAME")
+	setSecret(&c.Admin.Password, "DEFAULT_ADMIN_PASSWORD")
+	setString(&c.Admin.Email, "DEFAULT_ADMIN_EMAIL")
+
+	if err := setBool(&c.Metrics.Enabled, "METRICS_ENABLED"); err != nil {
+		return err
+	}
+	setString(&c.Metrics.Path, "METRICS_PATH")
+	setSecret(&c.Metrics.Token, "METRICS_TOKEN")
+
+	setString(&c.Log.Level, "LOG_LEVEL")
+	setString(&c.Log.Format, "LOG_FORMAT")
+
+	if err := setBool(&c.Tracing.Enabled, "TRACING_ENABLED"); err != nil {
+		return err
+	}
+	setString(&c.Tracing.Endpoint, "OTEL_EXPORTER_OTLP_ENDPOINT")
+	if err := setBool(&c.Tracing.Insecure, "OTEL_EXPORTER_OTLP_INSECURE"); err != nil {
+		return err
+	}
+	setString(&c.Tracing.ServiceName, "OTEL_SERVICE_NAME")
+	if err := setFloat(&c.Tracing.SampleRatio, "TRACING_SAMPLE_RATIO"); err != nil {
+		return err
+	}
+
+	setString(&c.I18n.DefaultLocale, "DEFAULT_LOCALE")
+
+	setString(&c.OpenAPI.Validation, "OPENAPI_VALIDATION")
+
+	return nil
+}
+
+// Validate checks the configuration and reports every problem found
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		errs = append(errs, fmt.Errorf("GIN_MODE must be one of debug, release, test (got %q)", c.Server.Mode))
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("SERVER_PORT must be a valid TCP port (got %q)", c.Server.Port))
+	}
+
+	if c.Server.ShutdownTimeout <= 0 || c.Server.ShutdownDrainDelay < 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive and SHUTDOWN_DRAIN_DELAY must not be negative"))
+	}
+
+	if c.Database.URL == "" && c.Database.Password == "" {
+		errs = append(errs, errors.New("either DATABASE_URL or DB_PASSWORD must be set"))
+	}
+	if scheme, _, ok := strings.Cut(c.Database.URL.Value(), "://"); ok && c.Database.Driver() == DriverPostgres && scheme != "postgres" && scheme != "postgresql" {
+		errs = append(errs, fmt.Errorf("DATABASE_URL scheme must be postgres, postgresql or sqlite (got %q)", scheme))
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS"))
+	}
+	if c.Database.ConnectRetries < 0 {
+		errs = append(errs, errors.New("DB_CONNECT_RETRIES must not be negative"))
+	}
+	switch c.Database.LogLevel {
+	case "", "silent", "error", "warn", "info":
+	default:
+		errs = append(errs, fmt.Errorf("DB_LOG_LEVEL must be one of silent, error, warn, info (got %q)", c.Database.LogLevel))
+	}
+
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("JWT_SECRET must not be empty"))
+	}
+	if c.IsRelease() && c.JWT.Secret == DefaultJWTSecret {
+		errs = append(errs, errors.New("JWT_SECRET must be changed from the default value in release mode"))
+	}
+	if c.JWT.Expiration <= 0 {
+		errs = append(errs, errors.New("JWT_EXPIRATION must be positive"))
+	}
+	if c.JWT.TouristExpiration <= 0 {
+		errs = append(errs, errors.New("JWT_TOURIST_EXPIRATION must be positive"))
+	}
+
+	if !strings.HasPrefix(c.QRCode.BaseURL, "http://") && !strings.HasPrefix(c.QRCode.BaseURL, "https://") {
+		errs = append(errs, fmt.Errorf("BASE_URL must start with http:// or https:// (got %q)", c.QRCode.BaseURL))
+	}
+	if !strings.HasPrefix(c.QRCode.WxAPIURL, "http://") && !strings.HasPrefix(c.QRCode.WxAPIURL, "https://") {
+		errs = append(errs, fmt.Errorf("WX_API_URL must start with http:// or https:// (got %q)", c.QRCode.WxAPIURL))
+	}
+	if c.QRCode.WxTimeout <= 0 || c.QRCode.WxRetries < 0 {
+		errs = append(errs, errors.New("WX_TIMEOUT must be positive and WX_RETRIES must not be negative"))
+	}
+	switch c.QRCode.Level {
+	case "L", "M", "Q", "H":
+	default:
+		errs = append(errs, fmt.Errorf("QRCODE_LEVEL must be one of L, M, Q, H (got %q)", c.QRCode.Level))
+	}
+	if !hexColorPattern.MatchString(c.QRCode.Foreground) {
+		errs = append(errs, fmt.Errorf("QRCODE_FOREGROUND must be a color such as #000000 (got %q)", c.QRCode.Foreground))
+	}
+	if !hexColorPattern.MatchString(c.QRCode.Background) {
+		errs = append(errs, fmt.Errorf("QRCODE_BACKGROUND must be a color such as #ffffff (got %q)", c.QRCode.Background))
+	}
+	if c.QRCode.LogoPath != "" {
+		if _, err := os.Stat(c.QRCode.LogoPath); err != nil {
+			errs = append(errs, fmt.Errorf("QRCODE_LOGO_PATH: %v", err))
+		}
+	}
+	if c.QRCode.BadgeFont != "" {
+		if _, err := os.Stat(c.QRCode.BadgeFont); err != nil {
+			errs = append(errs, fmt.Errorf("BADGE_FONT_PATH: %v", err))
+		}
+	}
+
+	if c.Admin.Username == "" || c.Admin.Password == "" {
+		errs = append(errs, errors.New("DEFAULT_ADMIN_USERNAME and DEFAULT_ADMIN_PASSWORD must not be empty"))
+	}
+
+	switch c.Log.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error (got %q)", c.Log.Level))
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be json or text (got %q)", c.Log.Format))
+	}
+
+	if c.Tracing.Enabled {
+		if c.Tracing.Endpoint == "" || strings.Contains(c.Tracing.Endpoint, "://") {
+			errs = append(errs, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT must be a host:port (got %q)", c.Tracing.Endpoint))
+		}
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1 (got %v)", c.Tracing.SampleRatio))
+		}
+	}
+
+	if c.OpenAPI.Validation != "off" && c.OpenAPI.Validation != "log" {
+		errs = append(errs, fmt.Errorf("OPENAPI_VALIDATION must be off or log (got %q)", c.OpenAPI.Validation))
+	}
+
+	if !i18n.IsSupported(c.I18n.DefaultLocale) {
+		errs = append(errs, fmt.Errorf("DEFAULT_LOCALE must be one of %s (got %q)", strings.Join(i18n.Locales(), ", "), c.I18n.DefaultLocale))
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		errs = append(errs, fmt.Errorf("METRICS_PATH must start with / (got %q)", c.Metrics.Path))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// IsRelease reports whether the server runs in release (production) mode
+func (c *Config) IsRelease() bool {
+	return c.Server.Mode == "release"
+}
+
+// String returns the configuration as YAML with all secrets redacted
+func (c *Config) String() string {
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("<config: %v>", err)
+	}
+	return string(out)
+}
+
+// defaultDBLogLevel returns the SQL log level for a server mode
+func defaultDBLogLevel(mode string) string {
+	switch mode {
+	case "debug":
+		return "info"
+	case "test":
+		return "silent"
+	default:
+		return "warn"
+	}
+}
+
+// defaultLogLevel returns the application log level for a server mode
+func defaultLogLevel(mode string) string {
+	switch mode {
+	case "debug":
+		return "debug"
+	case "test":
+		return "warn"
+	default:
+		return "info"
+	}
+}
+
+// defaultContractValidation validates API traffic against the OpenAPI document outside release
+func defaultContractValidation(mode string) string {
+	if mode == "release" {
+		return "off"
+	}
+	return "log"
+}
+
+// setString overrides dst with the environment variable if it is set
+func setString(dst *string, key string) {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		*dst = value
+	}
+}
+
+// setSecret overrides dst with the environment variable if it is set
+func setSecret(dst *Secret, key string) {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		*dst = Secret(value)
+	}
+}
+
+// setDuration overrides dst with the environment variable if it is set
+func setDuration(dst *time.Duration, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%s must be a duration such as 24h (got %q): %w", key, value, err)
+	}
+	*dst = d
+	return nil
+}
+
+// setInt overrides dst with the environment variable if it is set
+func setInt(dst *int, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s must be an integer (got %q): %w", key, value, err)
+	}
+	*dst = n
+	return nil
+}
+
+// setBool overrides dst with the environment variable if it is set
+func setBool(dst *bool, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s must be a boolean (got %q): %w", key, value, err)
+	}
+	*dst = b
+	return nil
+}
+
+// setFloat overrides dst with the environment variable if it is set
+func setFloat(dst *float64, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("%s must be a number (got %q): %w", key, value, err)
+	}
+	*dst = f
+	return nil
+}
@@ -1,10 +1,16 @@
 package config
 
 import (
+	"database/sql"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"strings"
+	"time"
 
+	"tourism_recommendor/logging"
+	"tourism_recommendor/tracing"
+
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -12,117 +18,207 @@ import (
 
 var DB *gorm.DB
 
+// Supported database drivers, selected by the DATABASE_URL scheme
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqlitePrefixes are the DATABASE_URL prefixes that select SQLite, longest first
+var sqlitePrefixes = []string{"sqlite3://", "sqlite://", "sqlite3:", "sqlite:"}
+
 // DatabaseConfig holds database connection parameters
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	URL      Secret `yaml:"url"` // Full connection string (provided by Render and other platforms), takes precedence
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password Secret `yaml:"password"`
+	DBName   string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+
+	// Connection pool settings
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// Startup retry settings (exponential back-off between attempts)
+	ConnectRetries    int           `yaml:"connect_retries"`
+	ConnectRetryDelay time.Duration `yaml:"connect_retry_delay"`
+
+	// Logging settings
+	LogLevel           string        `yaml:"log_level"` // silent, error, warn, info; derived from GIN_MODE when empty
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
 }
 
-// InitDatabase initializes database connection
-func InitDatabase(config DatabaseConfig) error {
-	log.Printf("Connecting to database...")
-	log.Printf("  Host: %s", config.Host)
-	log.Printf("  Port: %s", config.Port)
-	log.Printf("  User: %s", config.User)
-	log.Printf("  Database: %s", config.DBName)
-	log.Printf("  SSL Mode: %s", config.SSLMode)
-
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.Host,
-		config.Port,
-		config.User,
-		config.Password,
-		config.DBName,
-		config.SSLMode,
-	)
+// maxConnectRetryDelay caps the back-off between connection attempts
+const maxConnectRetryDelay = 30 * time.Second
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+// Driver returns the database driver selected by the DATABASE_URL scheme; Postgres is the default
+func (c DatabaseConfig) Driver() string {
+	url := c.URL.Value()
+	if strings.HasPrefix(url, "file:") {
+		return DriverSQLite
+	}
+	for _, prefix := range sqlitePrefixes {
+		if strings.HasPrefix(url, prefix) {
+			return DriverSQLite
+		}
+	}
+	return DriverPostgres
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to connect to database: %v", err)
+// DSN returns the connection string for the configured database
+func (c DatabaseConfig) DSN() string {
+	if c.URL != "" {
+		url := c.URL.Value()
+		// SQLite takes a file path (or :memory:), so drop the scheme
+		for _, prefix := range sqlitePrefixes {
+			if path, ok := strings.CutPrefix(url, prefix); ok {
+				return path
+			}
+		}
+		return url
 	}
 
-	log.Println("Database connection established successfully")
-	return nil
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.Host,
+		c.Port,
+		c.User,
+		c.Password.Value(),
+		c.DBName,
+		c.SSLMode,
+	)
+}
+
+// Dialector returns the GORM dialector for the configured driver
+func (c DatabaseConfig) Dialector() gorm.Dialector {
+	if c.Driver() == DriverSQLite {
+		return sqlite.Open(c.DSN())
+	}
+	return postgres.Open(c.DSN())
 }
 
-// InitDatabaseFromEnv initializes database connection from environment variables
-func InitDatabaseFromEnv() error {
-	// First, try to use DATABASE_URL (provided by Render and other platforms)
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL != "" {
-		log.Println("Connecting to database using DATABASE_URL...")
-		log.Println("  URL: *** (hidden)")
-
-		var err error
-		DB, err = gorm.Open(postgres.Open(databaseURL), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
-		})
-
-		if err != nil {
-			return fmt.Errorf("failed to connect to database using DATABASE_URL: %v", err)
+// InitDatabase initializes database connection, retrying with back-off until the database is reachable
+func InitDatabase(config DatabaseConfig) error {
+	if config.URL != "" {
+		slog.Info("connecting to database", "driver", config.Driver(), "url", config.URL)
+	} else {
+		slog.Info("connecting to database",
+			"host", config.Host,
+			"port", config.Port,
+			"user", config.User,
+			"database", config.DBName,
+			"sslmode", config.SSLMode,
+		)
+	}
+
+	gormConfig := &gorm.Config{
+		Logger: newGormLogger(config),
+	}
+
+	attempts := config.ConnectRetries + 1
+	delay := config.ConnectRetryDelay
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		DB, err = gorm.Open(config.Dialector(), gormConfig)
+		if err == nil {
+			break
 		}
 
-		log.Println("Database connection established successfully")
-		return nil
+		if attempt == attempts {
+			return fmt.Errorf("failed to connect to database after %d attempts: %v", attempts, err)
+		}
+
+		slog.Warn("database connection attempt failed",
+			"attempt", attempt,
+			"max_attempts", attempts,
+			"retry_in", delay.String(),
+			"error", err,
+		)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > maxConnectRetryDelay {
+			delay = maxConnectRetryDelay
+		}
 	}
 
-	// Fallback: load database configuration from individual environment variables
-	log.Println("DATABASE_URL not found, loading database configuration from individual environment variables...")
+	if err := configurePool(DB, config); err != nil {
+		return err
+	}
 
-	// Get environment variables with defaults for non-critical fields
-	host := getEnvWithDefault("DB_HOST", "localhost", true)
-	port := getEnvWithDefault("DB_PORT", "5432", true)
-	user := getEnvWithDefault("DB_USER", "postgres", true)
-	password := getEnvWithDefault("DB_PASSWORD", "", false)
-	dbName := getEnvWithDefault("DB_NAME", "tourism_recommender", true)
-	sslMode := getEnvWithDefault("DB_SSLMODE", "disable", true)
+	// Trace every query; spans are no-ops unless a tracer provider is installed
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %v", err)
+	}
 
-	// Validate required fields
-	if password == "" {
-		return fmt.Errorf("DB_PASSWORD environment variable is required but not set")
+	slog.Info("database connection established")
+	return nil
+}
+
+// configurePool applies connection pool limits to the underlying sql.DB
+func configurePool(db *gorm.DB, config DatabaseConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %v", err)
 	}
 
-	config := DatabaseConfig{
-		Host:     host,
-		Port:     port,
-		User:     user,
-		Password: password,
-		DBName:   dbName,
-		SSLMode:  sslMode,
+	// SQLite allows a single writer, and every :memory: connection is a separate database
+	// that is lost when the connection closes, so keep exactly one connection open
+	if config.Driver() == DriverSQLite {
+		config.MaxOpenConns, config.MaxIdleConns = 1, 1
+		config.ConnMaxLifetime, config.ConnMaxIdleTime = 0, 0
 	}
 
-	return InitDatabase(config)
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	slog.Info("database pool configured",
+		"max_open_conns", config.MaxOpenConns,
+		"max_idle_conns", config.MaxIdleConns,
+		"conn_max_lifetime", config.ConnMaxLifetime.String(),
+		"conn_max_idle_time", config.ConnMaxIdleTime.String(),
+	)
+
+	return nil
 }
 
-// getEnvWithDefault retrieves environment variable or returns default value
-// If logDefault is true, it logs when using the default value
-func getEnvWithDefault(key, defaultValue string, logDefault bool) string {
-	value := os.Getenv(key)
+// newGormLogger creates the GORM logger for the configured level and slow query threshold
+func newGormLogger(config DatabaseConfig) logger.Interface {
+	return logging.NewGormLogger(parseLogLevel(config.LogLevel), config.SlowQueryThreshold)
+}
 
-	if value == "" {
-		if logDefault {
-			log.Printf("  %s not set, using default: %s", key, defaultValue)
-		} else {
-			log.Printf("  %s not set", key)
-		}
-		return defaultValue
+// parseLogLevel converts a log level name into a GORM log level
+func parseLogLevel(level string) logger.LogLevel {
+	switch level {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "info":
+		return logger.Info
+	default:
+		return logger.Warn
+	}
+}
+
+// DBStats returns the connection pool statistics of the current database
+func DBStats() (sql.DBStats, error) {
+	if DB == nil {
+		return sql.DBStats{}, fmt.Errorf("database is not initialized")
 	}
 
-	if key == "DB_PASSWORD" {
-		log.Printf("  %s: *** (hidden)", key)
-	} else {
-		log.Printf("  %s: %s", key, value)
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return sql.DBStats{}, fmt.Errorf("failed to get database instance: %v", err)
 	}
 
-	return value
+	return sqlDB.Stats(), nil
 }
 
 // CloseDatabase closes the database connection
@@ -140,6 +236,6 @@ func CloseDatabase() error {
 		return fmt.Errorf("failed to close database connection: %v", err)
 	}
 
-	log.Println("Database connection closed successfully")
+	slog.Info("database connection closed")
 	return nil
 }
@@ -1,12 +1,14 @@
 package controllers
 
 import (
-	"log"
-	"net/http"
+	"errors"
+	"strings"
 	"time"
 
+	"tourism_recommendor/logging"
 	"tourism_recommendor/middleware"
 	"tourism_recommendor/models"
+	"tourism_recommendor/response"
 	"tourism_recommendor/utils"
 
 	"github.com/gin-gonic/gin"
@@ -55,85 +57,77 @@ type ChangePasswordRequest struct {
 }
 
 // Login handles admin login
+// @Summary Log in
+// @Description Authenticate an administrator and issue a JWT
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body LoginRequest true "Login credentials"
+// @Success 200 {object} response.MessageBody{data=LoginResponse}
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/auth/login [post]
+// @Router /api/auth/login [post]
 func (ac *AuthController) Login(c *gin.Context) {
 	var req LoginRequest
+	logger := logging.FromContext(c.Request.Context())
 
 	// Bind request body
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("❌ Login failed: Invalid request format - %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request format",
-			"details": err.Error(),
-		})
+		logger.Warn("login failed: invalid request format", "error", err)
+		response.BadRequest(c, err)
 		return
 	}
 
-	log.Printf("🔐 Login attempt received for username: %s", req.Username)
+	logger = logger.With("username", req.Username)
 
 	// Find admin by username
 	var admin models.Admin
-	if err := ac.DB.Where("username = ?", req.Username).First(&admin).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("❌ Login failed: User '%s' not found in database", req.Username)
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid username or password",
-			})
+	if err := ac.DB.WithContext(c.Request.Context()).Where("username = ?", req.Username).First(&admin).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warn("login failed: user not found")
+			response.Error(c, response.ErrInvalidCredentials)
 			return
 		}
-		log.Printf("❌ Login failed: Database error for user '%s' - %v", req.Username, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Database error",
-			"details": err.Error(),
-		})
+		logger.Error("login failed: database error", "error", err)
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	log.Printf("✅ User '%s' found in database - ID: %d, Role: %s, Status: %s", req.Username, admin.ID, admin.Role, admin.Status)
-
 	// Check if admin account is active
 	if !admin.IsActive() {
-		log.Printf("❌ Login failed: User '%s' account is not active - Status: %s", req.Username, admin.Status)
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":  "Account is not active",
-			"status": admin.Status,
-		})
+		logger.Warn("login failed: account is not active", "user_id", admin.ID, "status", admin.Status)
+		response.Error(c, response.ErrAccountInactive.WithDetail("status", admin.Status))
 		return
 	}
 
 	// Verify password
 	if err := admin.CheckPassword(req.Password); err != nil {
-		log.Printf("❌ Login failed: Invalid password for user '%s' - %v", req.Username, err)
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid username or password",
-		})
+		logger.Warn("login failed: invalid password", "user_id", admin.ID)
+		response.Error(c, response.ErrInvalidCredentials)
 		return
 	}
 
-	log.Printf("✅ Password verified successfully for user '%s'", req.Username)
-
 	// Generate JWT token
 	token, err := utils.GenerateToken(admin.ID, admin.Username, string(admin.Role))
 	if err != nil {
-		log.Printf("❌ Login failed: Failed to generate token for user '%s' - %v", req.Username, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to generate token",
-			"details": err.Error(),
-		})
+		logger.Error("login failed: token generation error", "user_id", admin.ID, "error", err)
+		response.Error(c, response.ErrInternal.WithCause(err))
 		return
 	}
 
-	log.Printf("✅ Token generated successfully for user '%s'", req.Username)
-
 	// Update last login time
 	now := time.Now().Unix()
 	admin.LastLogin = &now
-	if err := ac.DB.Save(&admin).Error; err != nil {
+	if err := ac.DB.WithContext(c.Request.Context()).Save(&admin).Error; err != nil {
 		// Log error but don't fail the login
-		// In production, you might want to log this properly
+		logger.Warn("failed to update last login time", "user_id", admin.ID, "error", err)
 	}
 
 	// Prepare response
-	response := LoginResponse{
+	resp := LoginResponse{
 		Token:     token,
 		ExpiresAt: time.Now().Add(utils.TokenExpiration).Unix(),
 		User: AdminInfo{
@@ -148,14 +142,20 @@ func (ac *AuthController) Login(c *gin.Context) {
 		},
 	}
 
-	log.Printf("🎉 Login successful for user '%s' (ID: %d, Role: %s)", req.Username, admin.ID, admin.Role)
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"data":    response,
-	})
+	logger.Info("login successful", "user_id", admin.ID, "role", admin.Role)
+	response.Message(c, "message.auth.login_success", resp)
 }
 
 // Logout handles admin logout (client-side token deletion recommended)
+// @Summary Log out
+// @Description Log out the current administrator (the client discards the token)
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.MessageBody
+// @Failure 401 {object} response.Body
+// @Router /api/v1/auth/logout [post]
+// @Router /api/auth/logout [post]
 func (ac *AuthController) Logout(c *gin.Context) {
 	// Since JWT tokens are stateless, we don't need to do anything server-side
 	// The client should delete the token
@@ -163,40 +163,38 @@ func (ac *AuthController) Logout(c *gin.Context) {
 	// 1. Add the token to a blacklist (using Redis)
 	// 2. Implement token rotation with refresh tokens
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Logout successful",
-	})
+	response.Message(c, "message.auth.logout_success", nil)
 }
 
 // GetCurrentUser returns the current logged-in user information
+// @Summary Get current user
+// @Description Retrieve the profile of the authenticated administrator
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.MessageBody{data=AdminInfo}
+// @Failure 401 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/auth/me [get]
+// @Router /api/auth/me [get]
 func (ac *AuthController) GetCurrentUser(c *gin.Context) {
 	// Get user ID from context
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+		response.Error(c, response.ErrUnauthorized)
 		return
 	}
 
 	// Find admin by ID
 	var admin models.Admin
-	if err := ac.DB.First(&admin, userID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "User not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Database error",
-			"details": err.Error(),
-		})
+	if err := ac.DB.WithContext(c.Request.Context()).First(&admin, userID).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrUserNotFound))
 		return
 	}
 
 	// Prepare response
-	response := AdminInfo{
+	resp := AdminInfo{
 		ID:       admin.ID,
 		Username: admin.Username,
 		Name:     admin.Name,
@@ -207,156 +205,131 @@ func (ac *AuthController) GetCurrentUser(c *gin.Context) {
 		Status:   admin.Status,
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Success",
-		"data":    response,
-	})
+	response.Message(c, "message.auth.current_user", resp)
 }
 
 // ChangePassword handles password change for the current user
+// @Summary Change password
+// @Description Change the password of the authenticated administrator
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param passwords body ChangePasswordRequest true "Old and new password"
+// @Success 200 {object} response.MessageBody
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/auth/change-password [put]
+// @Router /api/auth/change-password [put]
 func (ac *AuthController) ChangePassword(c *gin.Context) {
 	var req ChangePasswordRequest
 
 	// Bind request body
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request format",
-			"details": err.Error(),
-		})
+		response.BadRequest(c, err)
 		return
 	}
 
 	// Get user ID from context
 	userID, err := middleware.GetUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Unauthorized",
-		})
+		response.Error(c, response.ErrUnauthorized)
 		return
 	}
 
 	// Find admin by ID
 	var admin models.Admin
-	if err := ac.DB.First(&admin, userID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "User not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Database error",
-			"details": err.Error(),
-		})
+	if err := ac.DB.WithContext(c.Request.Context()).First(&admin, userID).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrUserNotFound))
 		return
 	}
 
 	// Verify old password
 	if err := admin.CheckPassword(req.OldPassword); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Old password is incorrect",
-		})
+		response.Error(c, response.ErrOldPasswordWrong)
 		return
 	}
 
 	// Check if new password is same as old password
 	if req.OldPassword == req.NewPassword {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "New password must be different from old password",
-		})
+		response.Error(c, response.ErrPasswordUnchanged)
 		return
 	}
 
 	// Set new password
 	if err := admin.SetPassword(req.NewPassword); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update password",
-			"details": err.Error(),
-		})
+		response.Error(c, response.ErrInternal.WithCause(err))
 		return
 	}
 
 	// Save to database
-	if err := ac.DB.Save(&admin).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update password",
-			"details": err.Error(),
-		})
+	if err := ac.DB.WithContext(c.Request.Context()).Save(&admin).Error; err != nil {
+		response.Error(c, response.ErrInternal.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Password changed successfully",
-	})
+	response.Message(c, "message.auth.password_changed", nil)
 }
 
 // RefreshToken generates a new token from an existing valid token
+// @Summary Refresh token
+// @Description Exchange a valid JWT for a new one with a fresh expiry
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.MessageBody{data=LoginResponse}
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/auth/refresh-token [post]
+// @Router /api/auth/refresh-token [post]
 func (ac *AuthController) RefreshToken(c *gin.Context) {
 	// Get token from Authorization header
 	authHeader := c.GetHeader(middleware.AuthorizationHeader)
 	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Authorization header is required",
-		})
+		response.Error(c, response.ErrAuthHeaderMissing)
 		return
 	}
 
 	// Extract token from header
-	tokenString := authHeader[len(middleware.BearerScheme)+1:]
-	if tokenString == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Invalid authorization header format",
-		})
+	tokenString := strings.TrimPrefix(authHeader, middleware.BearerScheme+" ")
+	if tokenString == authHeader || tokenString == "" {
+		response.Error(c, response.ErrAuthHeaderInvalid)
 		return
 	}
 
 	// Refresh token
 	newToken, err := utils.RefreshToken(tokenString)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Invalid or expired token",
-			"details": err.Error(),
-		})
+		response.Error(c, response.ErrTokenInvalid.WithCause(err))
 		return
 	}
 
 	// Get user info from new token for response
 	claims, err := utils.ValidateToken(newToken)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to validate new token",
-			"details": err.Error(),
-		})
+		response.Error(c, response.ErrInternal.WithCause(err))
 		return
 	}
 
 	// Find admin by ID
 	var admin models.Admin
-	if err := ac.DB.First(&admin, claims.UserID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "User not found",
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Database error",
-			"details": err.Error(),
-		})
+	if err := ac.DB.WithContext(c.Request.Context()).First(&admin, claims.UserID).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrUserNotFound))
 		return
 	}
 
 	// Check if admin account is still active
 	if !admin.IsActive() {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":  "Account is not active",
-			"status": admin.Status,
-		})
+		response.Error(c, response.ErrAccountInactive.WithDetail("status", admin.Status))
 		return
 	}
 
 	// Prepare response
-	response := LoginResponse{
+	resp := LoginResponse{
 		Token:     newToken,
 		ExpiresAt: time.Now().Add(utils.TokenExpiration).Unix(),
 		User: AdminInfo{
@@ -371,8 +344,5 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 		},
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Token refreshed successfully",
-		"data":    response,
-	})
+	response.Message(c, "message.auth.token_refreshed", resp)
 }
@@ -0,0 +1,231 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+)
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		username string
+		password string
+		code     int
+		errCode  string
+	}{
+		{name: "valid credentials", password: testutil.AdminPassword, code: http.StatusOK},
+		{name: "wrong password", password: "wrong-password", code: http.StatusUnauthorized, errCode: "INVALID_CREDENTIALS"},
+		{name: "unknown user", username: "nobody", password: testutil.AdminPassword, code: http.StatusUnauthorized, errCode: "INVALID_CREDENTIALS"},
+		{name: "inactive account", status: "locked", password: testutil.AdminPassword, code: http.StatusForbidden, errCode: "ACCOUNT_INACTIVE"},
+		{name: "missing password", code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			admin := h.CreateAdmin(func(a *models.Admin) {
+				if tt.status != "" {
+					a.Status = tt.status
+				}
+			})
+
+			username := admin.Username
+			if tt.username != "" {
+				username = tt.username
+			}
+			w := h.Do("POST", "/api/v1/auth/login", controllers.LoginRequest{Username: username, Password: tt.password}, "")
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var resp controllers.LoginResponse
+			h.Decode(w, &resp)
+			if resp.Token == "" || resp.User.ID != admin.ID || resp.User.Username != admin.Username {
+				t.Fatalf("unexpected login response: %+v", resp)
+			}
+
+			var stored models.Admin
+			if err := h.DB.First(&stored, admin.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.LastLogin == nil {
+				t.Fatal("last login was not recorded")
+			}
+		})
+	}
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   func(h *testutil.Harness, admin *models.Admin) string
+		code    int
+		errCode string
+	}{
+		{name: "authenticated", token: func(h *testutil.Harness, admin *models.Admin) string { return h.Token(admin) }, code: http.StatusOK},
+		{name: "missing token", token: func(*testutil.Harness, *models.Admin) string { return "" }, code: http.StatusUnauthorized, errCode: "AUTH_HEADER_MISSING"},
+		{name: "invalid token", token: func(*testutil.Harness, *models.Admin) string { return "not-a-jwt" }, code: http.StatusUnauthorized, errCode: "TOKEN_INVALID"},
+		{name: "deleted user", token: func(h *testutil.Harness, admin *models.Admin) string {
+			token := h.Token(admin)
+			h.DB.Delete(admin)
+			return token
+		}, code: http.StatusNotFound, errCode: "USER_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			admin := h.CreateAdmin()
+
+			w := h.Do("GET", "/api/v1/auth/me", nil, tt.token(h, admin))
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code == http.StatusOK {
+				var info controllers.AdminInfo
+				h.Decode(w, &info)
+				if info.ID != admin.ID || info.Email != admin.Email {
+					t.Fatalf("unexpected user: %+v", info)
+				}
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		oldPassword string
+		newPassword string
+		code        int
+		errCode     string
+	}{
+		{name: "valid change", oldPassword: testutil.AdminPassword, newPassword: "new-password", code: http.StatusOK},
+		{name: "wrong old password", oldPassword: "wrong-password", newPassword: "new-password", code: http.StatusBadRequest, errCode: "OLD_PASSWORD_INCORRECT"},
+		{name: "unchanged password", oldPassword: testutil.AdminPassword, newPassword: testutil.AdminPassword, code: http.StatusBadRequest, errCode: "PASSWORD_UNCHANGED"},
+		{name: "new password too short", oldPassword: testutil.AdminPassword, newPassword: "123", code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			admin := h.CreateAdmin()
+
+			w := h.Do("PUT", "/api/v1/auth/change-password", controllers.ChangePasswordRequest{
+				OldPassword: tt.oldPassword,
+				NewPassword: tt.newPassword,
+			}, h.Token(admin))
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+
+			var stored models.Admin
+			if err := h.DB.First(&stored, admin.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			want := testutil.AdminPassword
+			if tt.code == http.StatusOK {
+				want = tt.newPassword
+			}
+			if err := stored.CheckPassword(want); err != nil {
+				t.Fatalf("stored password does not match %q", want)
+			}
+		})
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  func(token string) string
+		status  string
+		code    int
+		errCode string
+	}{
+		{name: "valid token", header: func(token string) string { return "Bearer " + token }, code: http.StatusOK},
+		{name: "missing header", header: func(string) string { return "" }, code: http.StatusUnauthorized, errCode: "AUTH_HEADER_MISSING"},
+		{name: "not a bearer token", header: func(token string) string { return "Basic " + token }, code: http.StatusUnauthorized, errCode: "AUTH_HEADER_INVALID"},
+		{name: "invalid token", header: func(string) string { return "Bearer not-a-jwt" }, code: http.StatusUnauthorized, errCode: "TOKEN_INVALID"},
+		{name: "inactive account", header: func(token string) string { return "Bearer " + token }, status: "inactive", code: http.StatusForbidden, errCode: "ACCOUNT_INACTIVE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			admin := h.CreateAdmin(func(a *models.Admin) {
+				if tt.status != "" {
+					a.Status = tt.status
+				}
+			})
+
+			req := httptest.NewRequest("POST", "/api/v1/auth/refresh-token", nil)
+			if header := tt.header(h.Token(admin)); header != "" {
+				req.Header.Set("Authorization", header)
+			}
+			w := h.Serve(req)
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code == http.StatusOK {
+				var resp controllers.LoginResponse
+				h.Decode(w, &resp)
+				if resp.Token == "" || resp.User.ID != admin.ID {
+					t.Fatalf("unexpected refresh response: %+v", resp)
+				}
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		token bool
+		code  int
+	}{
+		{name: "v1", path: "/api/v1/auth/logout", token: true, code: http.StatusOK},
+		{name: "legacy", path: "/api/auth/logout", token: true, code: http.StatusOK},
+		{name: "unauthenticated", path: "/api/v1/auth/logout", code: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			token := ""
+			if tt.token {
+				token = h.AdminToken()
+			}
+
+			w := h.Do("POST", tt.path, nil, token)
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"errors"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dbWithContext returns the database handle bound to the request context,
+// so queries are cancelled with the request and traced under its span
+func dbWithContext(c *gin.Context) *gorm.DB {
+	return config.DB.WithContext(c.Request.Context())
+}
+
+// lookupError maps a failed single-record lookup to notFound, or to a database error
+func lookupError(err error, notFound *response.APIError) *response.APIError {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+	return response.ErrDatabase.WithCause(err)
+}
+
+// paginationRequest parses page, page_size and sort from the query string. sort is a list such as
+// "-rating,name"; the older sort_by/sort_order pair is still accepted. Fields outside sortable
+// are rejected with the allowed fields in the error details.
+func paginationRequest(c *gin.Context, sortable []string) (*utils.PaginationRequest, bool) {
+	pr := utils.ParsePaginationRequest(c.DefaultQuery("page", "1"), c.DefaultQuery("page_size", "10"))
+
+	expr := c.Query("sort")
+	if expr == "" {
+		expr = utils.LegacySort(c.Query("sort_by"), c.Query("sort_order"))
+	}
+
+	keys, err := utils.ParseSort(expr, sortable)
+	if err != nil {
+		apiErr := response.ErrInvalidSort.WithDetail("allowed", sortable)
+		var sortErr *utils.InvalidSortError
+		if errors.As(err, &sortErr) {
+			apiErr = apiErr.WithDetail("field", sortErr.Field)
+		}
+		response.Error(c, apiErr)
+		return nil, false
+	}
+
+	pr.Sort = keys
+	return pr, true
+}
+
+// cursorRequest switches pr to keyset pagination for pagination=cursor or when a cursor is given;
+// with_total=true also counts the matching rows, which cursor pages skip by default
+func cursorRequest(c *gin.Context, pr *utils.PaginationRequest) bool {
+	raw := c.Query("cursor")
+	if raw == "" && c.Query("pagination") != "cursor" {
+		return true
+	}
+
+	cursor, err := utils.DecodeCursor(raw, pr.Sort)
+	if err != nil {
+		response.Error(c, response.ErrInvalidCursor.WithCause(err))
+		return false
+	}
+
+	pr.Cursor = cursor
+	pr.WithTotal = c.Query("with_total") == "true"
+	return true
+}
+
+// listFilters parses the filter parameters of the query string against spec
+func listFilters(c *gin.Context, spec utils.FilterSpec) ([]utils.Filter, bool) {
+	filters, err := spec.Parse(c.Request.URL.Query())
+	if err != nil {
+		apiErr := response.ErrInvalidFilter.WithCause(err)
+		var filterErr *utils.FilterError
+		if errors.As(err, &filterErr) {
+			apiErr = apiErr.WithDetail("param", filterErr.Param).WithDetail("reason", filterErr.Reason)
+			if len(filterErr.Allowed) > 0 {
+				apiErr = apiErr.WithDetail("allowed", filterErr.Allowed)
+			}
+		}
+		response.Error(c, apiErr)
+		return nil, false
+	}
+	return filters, true
+}
+
+// projection parses the fields and include parameters against the resource's fieldset.
+// Relationships are only embedded on request, except on legacy routes, which keep
+// embedding legacyInclude when include is not given.
+func projection(c *gin.Context, fieldset *utils.Fieldset, legacyInclude string) (*utils.Projection, bool) {
+	include, ok := c.GetQuery("include")
+	if !ok && !response.UsesEnvelope(c) {
+		include = legacyInclude
+	}
+
+	p, err := fieldset.Parse(c.Query("fields"), include)
+	if err != nil {
+		apiErr := response.ErrInvalidFields.WithCause(err)
+		var fieldErr *utils.InvalidFieldError
+		if errors.As(err, &fieldErr) {
+			apiErr = apiErr.WithDetail("param", fieldErr.Param).WithDetail("field", fieldErr.Field).WithDetail("allowed", fieldErr.Allowed)
+		}
+		response.Error(c, apiErr)
+		return nil, false
+	}
+	return p, true
+}
+
+// respondPage responds with the page trimmed to the projection
+func respondPage(c *gin.Context, p *utils.Projection, page *utils.PaginationResponse) {
+	data, err := p.Render(page.Data)
+	if err != nil {
+		response.Error(c, response.ErrInternal.WithCause(err))
+		return
+	}
+	page.Data = data
+	response.Paginated(c, page)
+}
+
+// respondRecord responds with a single record trimmed to the projection
+func respondRecord(c *gin.Context, p *utils.Projection, record interface{}) {
+	data, err := p.Render(record)
+	if err != nil {
+		response.Error(c, response.ErrInternal.WithCause(err))
+		return
+	}
+	response.OK(c, data)
+}
@@ -0,0 +1,419 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/testutil"
+)
+
+func TestListSortValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		field string
+	}{
+		{name: "unlisted column", path: "/api/v1/recommendors?sort=id_number", field: "id_number"},
+		{name: "one bad key among valid ones", path: "/api/v1/destinations?sort=-rating,address", field: "address"},
+		{name: "injection through sort_by", path: "/api/v1/admin/regions?sort_by=name%3B+DROP+TABLE+regions", field: "name; DROP TABLE regions"},
+		{name: "empty key", path: "/api/v1/recommendors?sort=name,", field: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+
+			w := h.Do("GET", tt.path, nil, h.AdminToken())
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body.String())
+			}
+
+			envelope := h.Decode(w, nil)
+			if envelope.Error == nil || envelope.Error.Code != "INVALID_SORT" {
+				t.Fatalf("unexpected error: %s", w.Body.String())
+			}
+			if field := envelope.Error.Details["field"]; field != tt.field {
+				t.Fatalf("field = %v, want %q", field, tt.field)
+			}
+			if allowed := fmt.Sprint(envelope.Error.Details["allowed"]); allowed == "" || allowed == "<nil>" {
+				t.Fatal("allowed fields are missing from the error details")
+			}
+		})
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		query string
+		want  []string
+	}{
+		{name: "recommendors by id", path: "/api/v1/recommendors", query: "page_size=2", want: []string{"a", "b", "c", "d", "e"}},
+		{name: "recommendors by rating with ties", path: "/api/v1/recommendors", query: "page_size=2&sort=-rating", want: []string{"c", "a", "e", "b", "d"}},
+		{name: "recommendors by rating then name", path: "/api/v1/recommendors", query: "page_size=3&sort=rating,-name", want: []string{"d", "b", "e", "a", "c"}},
+		{name: "recommendors by timestamp", path: "/api/v1/recommendors", query: "page_size=2&sort=valid_from", want: []string{"e", "d", "c", "b", "a"}},
+		{name: "destinations by creation time", path: "/api/v1/destinations", query: "page_size=2&sort=-created_at", want: []string{"e", "d", "c", "b", "a"}},
+		{name: "destinations of a recommendor", path: "/api/v1/recommendors/{id}/destinations", query: "page_size=4", want: []string{"a", "b", "c", "d", "e"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			validFrom := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			var owner *models.Recommendor
+			for i, rating := range []float64{4, 3, 5, 3, 4} {
+				name := string(rune('a' + i))
+				r := h.CreateRecommendor(func(r *models.Recommendor) {
+					r.Name, r.Rating, r.ValidFrom = name, rating, validFrom.Add(time.Duration(5-i)*time.Hour)
+				})
+				if owner == nil {
+					owner = r
+				}
+				h.CreateDestination(owner, func(d *models.Destination) { d.Name, d.CreatedAt = name, int64(1700000000+i) })
+			}
+			path := strings.Replace(tt.path, "{id}", fmt.Sprint(owner.ID), 1)
+
+			// Walk forward to the end, then back to the start with the prev cursors
+			var forward []string
+			var pages []string
+			cursor := ""
+			for {
+				names, meta := cursorPage(t, h, path+"?pagination=cursor&"+tt.query+"&cursor="+url.QueryEscape(cursor))
+				forward = append(forward, names...)
+				pages = append(pages, fmt.Sprint(names))
+				if meta.NextCursor == "" {
+					cursor = meta.PrevCursor
+					break
+				}
+				cursor = meta.NextCursor
+			}
+			if fmt.Sprint(forward) != fmt.Sprint(tt.want) {
+				t.Fatalf("forward = %v, want %v", forward, tt.want)
+			}
+
+			for i := len(pages) - 2; i >= 0; i-- {
+				names, meta := cursorPage(t, h, path+"?pagination=cursor&"+tt.query+"&cursor="+url.QueryEscape(cursor))
+				if fmt.Sprint(names) != pages[i] {
+					t.Fatalf("page %d backwards = %v, want %s", i, names, pages[i])
+				}
+				if (i == 0) != (meta.PrevCursor == "") {
+					t.Fatalf("page %d has prev cursor %q", i, meta.PrevCursor)
+				}
+				cursor = meta.PrevCursor
+			}
+		})
+	}
+}
+
+func TestCursorRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   func(next string) string
+		code    int
+		errCode string
+		total   bool
+	}{
+		{name: "first page without total", query: func(string) string { return "pagination=cursor" }, code: http.StatusOK},
+		{name: "first page with total", query: func(string) string { return "pagination=cursor&with_total=true" }, code: http.StatusOK, total: true},
+		{name: "next page", query: func(next string) string { return "cursor=" + url.QueryEscape(next) }, code: http.StatusOK},
+		{name: "tampered cursor", query: func(next string) string { return "cursor=x" + url.QueryEscape(next) }, code: http.StatusBadRequest, errCode: "INVALID_CURSOR"},
+		{name: "cursor for another sort", query: func(next string) string { return "sort=-rating&cursor=" + url.QueryEscape(next) }, code: http.StatusBadRequest, errCode: "INVALID_CURSOR"},
+		{name: "garbage", query: func(string) string { return "cursor=not-a-cursor" }, code: http.StatusBadRequest, errCode: "INVALID_CURSOR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			for i := 0; i < 3; i++ {
+				h.CreateRecommendor()
+			}
+			_, first := cursorPage(t, h, "/api/v1/recommendors?page_size=2&pagination=cursor")
+
+			w := h.Do("GET", "/api/v1/recommendors?page_size=2&"+tt.query(first.NextCursor), nil, "")
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			meta := h.Decode(w, nil).Meta
+			if meta.Page != 0 || (meta.Total != nil) != tt.total {
+				t.Fatalf("unexpected meta: page %d, total %v", meta.Page, meta.Total)
+			}
+			if tt.total && *meta.Total != 3 {
+				t.Fatalf("total = %d, want 3", *meta.Total)
+			}
+		})
+	}
+}
+
+func TestListFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		code    int
+		errCode string
+		param   string
+		want    []string
+	}{
+		{name: "bare value uses the default operator", path: "/api/v1/recommendors?gender=male", code: http.StatusOK, want: []string{"a", "c"}},
+		{name: "in list", path: "/api/v1/recommendors?gender[in]=male,other", code: http.StatusOK, want: []string{"a", "c", "e"}},
+		{name: "not equal", path: "/api/v1/recommendors?gender[ne]=female", code: http.StatusOK, want: []string{"a", "c", "e"}},
+		{name: "range", path: "/api/v1/recommendors?age[gte]=30&age[lt]=50", code: http.StatusOK, want: []string{"b", "c"}},
+		{name: "combined operators", path: "/api/v1/recommendors?age[gte]=30&gender[in]=male,female", code: http.StatusOK, want: []string{"b", "c"}},
+		{name: "legacy min_age and max_age", path: "/api/v1/recommendors?min_age=30&max_age=40", code: http.StatusOK, want: []string{"b", "c"}},
+		{name: "number range", path: "/api/v1/recommendors?rating[gt]=4", code: http.StatusOK, want: []string{"e"}},
+		{name: "explicit status lists inactive", path: "/api/v1/recommendors?status=inactive", code: http.StatusOK, want: []string{"d"}},
+		{name: "admin region name", path: "/api/v1/admin/recommendors?province=上海", code: http.StatusOK, want: []string{"e"}},
+		{name: "destination in list", path: "/api/v1/destinations?category[in]=food,accommodation", code: http.StatusOK, want: []string{"b", "c"}},
+		{name: "unknown field", path: "/api/v1/recommendors?id_number[eq]=1", code: http.StatusBadRequest, errCode: "INVALID_FILTER", param: "id_number[eq]"},
+		{name: "unsupported operator", path: "/api/v1/recommendors?name[gt]=a", code: http.StatusBadRequest, errCode: "INVALID_FILTER", param: "name[gt]"},
+		{name: "value outside the enum", path: "/api/v1/recommendors?gender[in]=male,robot", code: http.StatusBadRequest, errCode: "INVALID_FILTER", param: "gender[in]"},
+		{name: "malformed number", path: "/api/v1/recommendors?age[gte]=thirty", code: http.StatusBadRequest, errCode: "INVALID_FILTER", param: "age[gte]"},
+		{name: "malformed time", path: "/api/v1/admin/recommendors?valid_until[lt]=tomorrow", code: http.StatusBadRequest, errCode: "INVALID_FILTER", param: "valid_until[lt]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			people := []struct {
+				gender models.Gender
+				age    int
+				rating float64
+				status string
+			}{
+				{models.GenderMale, 25, 3, "active"},
+				{models.GenderFemale, 30, 4, "active"},
+				{models.GenderMale, 35, 4, "active"},
+				{models.GenderFemale, 55, 3, "inactive"},
+				{models.GenderOther, 60, 5, "active"},
+			}
+			categories := []string{"scenic_spot", "food", "accommodation", "scenic_spot", "scenic_spot"}
+			for i, p := range people {
+				name := string(rune('a' + i))
+				r := h.CreateRecommendor(func(r *models.Recommendor) {
+					r.Name, r.Gender, r.Age, r.Rating, r.Status = name, p.gender, p.age, p.rating, p.status
+					if name == "e" {
+						r.RegionAddress = "上海市/上海市/黄浦区"
+					}
+				})
+				h.CreateDestination(r, func(d *models.Destination) { d.Name, d.Category = name, categories[i] })
+			}
+
+			w := h.Do("GET", tt.path, nil, h.AdminToken())
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code != http.StatusOK {
+				if param := h.Decode(w, nil).Error.Details["param"]; param != tt.param {
+					t.Fatalf("param = %v, want %q", param, tt.param)
+				}
+				return
+			}
+
+			var rows []struct {
+				Name string `json:"name"`
+			}
+			h.Decode(w, &rows)
+			names := make([]string, 0, len(rows))
+			for _, row := range rows {
+				names = append(names, row.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Fatalf("names = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldsAndInclude(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		code    int
+		errCode string
+		param   string
+		keys    []string
+	}{
+		{name: "list in requested order", path: "/api/v1/recommendors?fields=name,id", code: http.StatusOK, keys: []string{"name", "id"}},
+		{name: "sort column not requested", path: "/api/v1/recommendors?fields=name&sort=-rating", code: http.StatusOK, keys: []string{"name"}},
+		{name: "cursor page with total", path: "/api/v1/recommendors?fields=name&sort=-rating&pagination=cursor&with_total=true", code: http.StatusOK, keys: []string{"name"}},
+		{name: "admin list", path: "/api/v1/admin/recommendors?fields=id,status", code: http.StatusOK, keys: []string{"id", "status"}},
+		{name: "detail with destinations", path: "/api/v1/recommendors/{id}?fields=name&include=destinations", code: http.StatusOK, keys: []string{"name", "destinations"}},
+		{name: "detail without include", path: "/api/v1/recommendors/{id}?fields=name,destinations", code: http.StatusBadRequest, errCode: "INVALID_FIELDS", param: "fields"},
+		{name: "destinations with recommendor", path: "/api/v1/destinations?fields=name&include=recommendor", code: http.StatusOK, keys: []string{"name", "recommendor"}},
+		{name: "destinations of a recommendor", path: "/api/v1/recommendors/{id}/destinations?fields=id,name", code: http.StatusOK, keys: []string{"id", "name"}},
+		{name: "destination detail", path: "/api/v1/destinations/{destination}?fields=category&include=recommendor", code: http.StatusOK, keys: []string{"category", "recommendor"}},
+		{name: "unknown field", path: "/api/v1/recommendors?fields=id,password", code: http.StatusBadRequest, errCode: "INVALID_FIELDS", param: "fields"},
+		{name: "unknown include", path: "/api/v1/destinations?include=recommendor,admin", code: http.StatusBadRequest, errCode: "INVALID_FIELDS", param: "include"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			h.CreateRecommendor()
+			destination := h.CreateDestination(recommendor)
+
+			path := strings.Replace(tt.path, "{id}", fmt.Sprint(recommendor.ID), 1)
+			path = strings.Replace(path, "{destination}", fmt.Sprint(destination.ID), 1)
+			w := h.Do("GET", path, nil, h.AdminToken())
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			envelope := h.Decode(w, nil)
+			if tt.code != http.StatusOK {
+				if param := envelope.Error.Details["param"]; param != tt.param {
+					t.Fatalf("param = %v, want %q", param, tt.param)
+				}
+				return
+			}
+
+			record := envelope.Data
+			if strings.HasPrefix(string(record), "[") {
+				var rows []json.RawMessage
+				h.Decode(w, &rows)
+				if len(rows) == 0 {
+					t.Fatal("no rows")
+				}
+				record = rows[0]
+			}
+			if keys := objectKeys(t, record); fmt.Sprint(keys) != fmt.Sprint(tt.keys) {
+				t.Fatalf("keys = %v, want %v", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestLegacyRoutesEmbedRelationships(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+	destination := h.CreateDestination(recommendor)
+
+	w := h.Do("GET", fmt.Sprintf("/api/recommendors/%d", recommendor.ID), nil, "")
+	var gotRecommendor models.Recommendor
+	if err := json.Unmarshal(w.Body.Bytes(), &gotRecommendor); err != nil || len(gotRecommendor.Destinations) != 1 {
+		t.Fatalf("legacy recommendor detail should embed destinations: %s", w.Body.String())
+	}
+
+	w = h.Do("GET", fmt.Sprintf("/api/destinations/%d", destination.ID), nil, "")
+	var gotDestination models.Destination
+	if err := json.Unmarshal(w.Body.Bytes(), &gotDestination); err != nil || gotDestination.Recommendor == nil {
+		t.Fatalf("legacy destination detail should embed the recommendor: %s", w.Body.String())
+	}
+
+	w = h.Do("GET", fmt.Sprintf("/api/v1/destinations/%d", destination.ID), nil, "")
+	if strings.Contains(w.Body.String(), `"recommendor"`) {
+		t.Fatalf("v1 destination detail embeds the recommendor without include: %s", w.Body.String())
+	}
+}
+
+func TestLegacyRoutesKeepEnglishMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		language string
+		want     string
+	}{
+		{name: "legacy in chinese", method: "GET", path: "/api/recommendors/999999", language: "zh-CN", want: "Recommendor not found"},
+		{name: "legacy default locale", method: "GET", path: "/api/recommendors/999999", want: "Recommendor not found"},
+		{name: "legacy validation", method: "POST", path: "/api/auth/login", body: `{}`, language: "zh-CN", want: "Request validation failed: username is a required field; password is a required field"},
+		{name: "v1 follows the locale", method: "GET", path: "/api/v1/recommendors/999999", language: "zh-CN", want: "推荐官不存在"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.language != "" {
+				req.Header.Set("Accept-Language", tt.language)
+			}
+			w := h.Serve(req)
+
+			var body struct {
+				Error json.RawMessage `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			var message string
+			if err := json.Unmarshal(body.Error, &message); err != nil {
+				var envelope response.ErrorBody
+				if err := json.Unmarshal(body.Error, &envelope); err != nil {
+					t.Fatalf("unexpected body: %s", w.Body.String())
+				}
+				message = envelope.Message
+			}
+			if message != tt.want {
+				t.Fatalf("message = %q, want %q", message, tt.want)
+			}
+		})
+	}
+}
+
+// objectKeys returns the keys of a JSON object in document order
+func objectKeys(t *testing.T, data json.RawMessage) []string {
+	t.Helper()
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key.(string))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keys
+}
+
+// cursorPage fetches a cursor page and returns the names on it
+func cursorPage(t *testing.T, h *testutil.Harness, path string) ([]string, *response.PageMeta) {
+	t.Helper()
+
+	w := h.Do("GET", path, nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var rows []struct {
+		Name string `json:"name"`
+	}
+	envelope := h.Decode(w, &rows)
+	names := make([]string, 0, len(rows))
+	for _, row := range rows {
+		names = append(names, row.Name)
+	}
+	return names, envelope.Meta
+}
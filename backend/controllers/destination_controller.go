@@ -1,19 +1,41 @@
 package controllers
 
 import (
-	"net/http"
 	"strconv"
 
-	"tourism_recommendor/config"
+	"tourism_recommendor/i18n"
 	"tourism_recommendor/models"
+	"tourism_recommendor/response"
 	"tourism_recommendor/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // DestinationController handles destination-related requests
 type DestinationController struct{}
 
+// destinationSortFields are the columns clients may sort destinations by
+var destinationSortFields = []string{"id", "name", "category", "rating", "created_at", "updated_at"}
+
+// destinationFieldset lists the fields clients may select and the relationships they may include
+var destinationFieldset = &utils.Fieldset{
+	Fields: []string{
+		"id", "recommendor_id", "name", "description", "image", "address", "category", "rating", "status",
+		"created_at", "updated_at",
+	},
+	Includes: []utils.Include{{Name: "recommendor", Association: "Recommendor", Column: "recommendor_id"}},
+}
+
+// DestinationFilters are the filters accepted by the destination lists
+var DestinationFilters = utils.FilterSpec{
+	{Name: "name", Ops: []utils.FilterOp{utils.OpLike, utils.OpEq}, Description: "Name"},
+	{Name: "category", Ops: []utils.FilterOp{utils.OpEq, utils.OpNe, utils.OpIn}, Description: "Category, e.g. scenic_spot, food, accommodation"},
+	{Name: "recommendor_id", Type: utils.FilterInt, Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Description: "Recommendor ID"},
+	{Name: "status", Ops: []utils.FilterOp{utils.OpEq, utils.OpNe, utils.OpIn}, Values: i18n.EnumValues("status"), Description: "Status (public lists default to active)"},
+	{Name: "rating", Type: utils.FilterNumber, Ops: []utils.FilterOp{utils.OpEq, utils.OpRange}, Description: "Rating"},
+}
+
 // CreateDestinationRequest holds the request data for creating a destination
 type CreateDestinationRequest struct {
 	RecommendorID uint    `json:"recommendor_id" binding:"required"`
@@ -43,23 +65,27 @@ type UpdateDestinationRequest struct {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param destination body CreateDestinationRequest true "Destination data"
 // @Success 201 {object} models.Destination
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/destinations [post]
 // @Router /api/admin/destinations [post]
 func (dc *DestinationController) CreateDestination(c *gin.Context) {
 	var req CreateDestinationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		response.BadRequest(c, err)
 		return
 	}
 
 	// Validate that recommendor exists
 	var recommendor models.Recommendor
-	if err := config.DB.First(&recommendor, req.RecommendorID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recommendor not found"})
+	if err := dbWithContext(c).First(&recommendor, req.RecommendorID).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
 		return
 	}
 
@@ -80,78 +106,85 @@ func (dc *DestinationController) CreateDestination(c *gin.Context) {
 		Status:        status,
 	}
 
-	if err := config.DB.Create(&destination).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create destination: " + err.Error()})
+	if err := dbWithContext(c).Create(&destination).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, destination)
+	response.Created(c, destination)
 }
 
 // GetDestinations retrieves a paginated list of destinations with filtering
 // @Summary Get all destinations
-// @Description Retrieve a paginated list of destinations with optional filtering
+// @Description Retrieve a paginated list of active destinations with optional filtering
 // @Tags destinations
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Param sort_by query string false "Sort by field" default(id)
-// @Param sort_order query string false "Sort order (asc/desc)" default(asc)
-// @Param name query string false "Filter by name"
-// @Param category query string false "Filter by category"
-// @Param recommendor_id query int false "Filter by recommendor ID"
-// @Param region_id query int false "Filter by region ID"
-// @Param status query string false "Filter by status"
-// @Success 200 {object} utils.PaginationResponse
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma-separated sort fields, prefix - for descending (id, name, category, rating, created_at, updated_at)"
+// @Param sort_by query string false "Sort by field (superseded by sort)" default(id)
+// @Param sort_order query string false "Sort order (asc/desc, superseded by sort)" default(asc)
+// @Param pagination query string false "Pagination mode; cursor pages return next_cursor/prev_cursor and ignore page" Enums(page,cursor) default(page)
+// @Param cursor query string false "next_cursor or prev_cursor of a previous cursor page"
+// @Param with_total query bool false "Also count the total in cursor mode" default(false)
+// @Param fields query string false "Comma-separated fields to return (id, recommendor_id, name, description, image, address, category, rating, status, created_at, updated_at)"
+// @Param include query string false "Relationships to embed (recommendor)" Enums(recommendor)
+// @Filters DestinationFilters
+// @Success 200 {object} utils.PaginationResponse{data=[]models.Destination}
+// @Failure 400 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/destinations [get]
 // @Router /api/destinations [get]
 func (dc *DestinationController) GetDestinations(c *gin.Context) {
-	// Parse pagination parameters
-	pr := utils.ParsePaginationRequest(
-		c.DefaultQuery("page", "1"),
-		c.DefaultQuery("page_size", "10"),
-		c.DefaultQuery("sort_by", "id"),
-		c.DefaultQuery("sort_order", "asc"),
-	)
-
-	// Build query with preloads
-	query := config.DB.Model(&models.Destination{}).
-		Preload("Recommendor")
-
-	// Apply filters
-	if name := c.Query("name"); name != "" {
-		query = utils.ApplyFilter(query, "name", name)
+	// Parse pagination and sorting parameters
+	pr, ok := paginationRequest(c, destinationSortFields)
+	if !ok {
+		return
 	}
-
-	if category := c.Query("category"); category != "" {
-		query = utils.ApplyEqualFilter(query, "category", category)
+	if !cursorRequest(c, pr) {
+		return
 	}
 
-	if recommendorID := c.Query("recommendor_id"); recommendorID != "" {
-		if id, err := strconv.Atoi(recommendorID); err == nil {
-			query = utils.ApplyEqualFilter(query, "recommendor_id", id)
-		}
+	// Parse the fields and include parameters
+	p, ok := projection(c, destinationFieldset, "recommendor")
+	if !ok {
+		return
 	}
 
-	if status := c.Query("status"); status != "" {
-		query = utils.ApplyEqualFilter(query, "status", status)
+	// Build query
+	query := dbWithContext(c).Model(&models.Destination{})
+
+	// Apply filters
+	filters, ok := listFilters(c, DestinationFilters)
+	if !ok {
+		return
 	}
+	query = utils.ApplyFilters(query, filters)
 
 	// Filter only active destinations by default, unless status is explicitly set
-	if c.Query("status") == "" {
+	if !utils.HasFilter(filters, "status") {
 		query = query.Where("status = ?", "active")
 	}
 
+	// Select the requested fields and preload the included relationships
+	query = p.Apply(query, pr.Sort)
+
 	var destinations []models.Destination
 
 	// Get paginated results
-	response, err := utils.Paginate(query, pr, &destinations, &models.Destination{})
+	page, err := utils.Paginate(query, pr, &destinations, &models.Destination{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve destinations: " + err.Error()})
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	// Return translated content in the request locale
+	if err := localizeDestinations(c, destinations); err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondPage(c, p, page)
 }
 
 // GetDestinationByID retrieves a single destination by ID
@@ -160,27 +193,43 @@ func (dc *DestinationController) GetDestinations(c *gin.Context) {
 // @Tags destinations
 // @Produce json
 // @Param id path int true "Destination ID"
+// @Param fields query string false "Comma-separated fields to return (id, recommendor_id, name, description, image, address, category, rating, status, created_at, updated_at)"
+// @Param include query string false "Relationships to embed (recommendor)" Enums(recommendor)
 // @Success 200 {object} models.Destination
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/destinations/{id} [get]
 // @Router /api/destinations/{id} [get]
+// @Router /api/v1/admin/destinations/{id} [get]
+// @Router /api/admin/destinations/{id} [get]
 func (dc *DestinationController) GetDestinationByID(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination ID"})
+		response.Error(c, response.ErrInvalidID)
+		return
+	}
+
+	// Parse the fields and include parameters
+	p, ok := projection(c, destinationFieldset, "recommendor")
+	if !ok {
 		return
 	}
 
 	var destination models.Destination
-	if err := config.DB.
-		Preload("Recommendor").
-		First(&destination, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Destination not found"})
+	if err := p.Apply(dbWithContext(c), nil).First(&destination, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrDestinationNotFound))
 		return
 	}
 
-	c.JSON(http.StatusOK, destination)
+	// Return translated content in the request locale
+	localized := []models.Destination{destination}
+	if err := localizeDestinations(c, localized); err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	respondRecord(c, p, localized[0])
 }
 
 // UpdateDestination updates an existing destination
@@ -189,29 +238,33 @@ func (dc *DestinationController) GetDestinationByID(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Destination ID"
 // @Param destination body UpdateDestinationRequest true "Destination data"
 // @Success 200 {object} models.Destination
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/destinations/{id} [put]
 // @Router /api/admin/destinations/{id} [put]
 func (dc *DestinationController) UpdateDestination(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	var req UpdateDestinationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		response.BadRequest(c, err)
 		return
 	}
 
 	var destination models.Destination
-	if err := config.DB.First(&destination, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Destination not found"})
+	if err := dbWithContext(c).First(&destination, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrDestinationNotFound))
 		return
 	}
 
@@ -238,12 +291,12 @@ func (dc *DestinationController) UpdateDestination(c *gin.Context) {
 		destination.Status = *req.Status
 	}
 
-	if err := config.DB.Save(&destination).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update destination: " + err.Error()})
+	if err := dbWithContext(c).Save(&destination).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, destination)
+	response.OK(c, destination)
 }
 
 // DeleteDestination deletes a destination (soft delete)
@@ -251,84 +304,120 @@ func (dc *DestinationController) UpdateDestination(c *gin.Context) {
 // @Description Soft delete a destination by its ID
 // @Tags admin
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Destination ID"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} response.MessageBody
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/destinations/{id} [delete]
 // @Router /api/admin/destinations/{id} [delete]
 func (dc *DestinationController) DeleteDestination(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	var destination models.Destination
-	if err := config.DB.First(&destination, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Destination not found"})
+	if err := dbWithContext(c).First(&destination, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrDestinationNotFound))
 		return
 	}
 
-	// Soft delete (GORM will set deleted_at)
-	if err := config.DB.Delete(&destination).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete destination: " + err.Error()})
+	// Soft delete (GORM will set deleted_at) together with the translations of the record
+	err = dbWithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&destination).Error; err != nil {
+			return err
+		}
+		return deleteTranslations(tx, models.TranslationEntityDestination, destination.ID)
+	})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Destination deleted successfully"})
+	response.Message(c, "message.destination.deleted", nil)
 }
 
 // GetDestinationsByRecommendor retrieves all destinations for a specific recommendor
 // @Summary Get destinations by recommender
-// @Description Retrieve all destinations recommended by a specific recommender
+// @Description Retrieve the active destinations recommended by a specific recommender
 // @Tags recommendors
 // @Produce json
-// @Param recommendor_id path int true "Recommender ID"
+// @Param id path int true "Recommendor ID"
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Success 200 {object} utils.PaginationResponse
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
-// @Router /api/recommendors/{recommendor_id}/destinations [get]
+// @Param sort query string false "Comma-separated sort fields, prefix - for descending (id, name, category, rating, created_at, updated_at)"
+// @Param sort_by query string false "Sort by field (superseded by sort)" default(id)
+// @Param sort_order query string false "Sort order (asc/desc, superseded by sort)" default(asc)
+// @Param pagination query string false "Pagination mode; cursor pages return next_cursor/prev_cursor and ignore page" Enums(page,cursor) default(page)
+// @Param cursor query string false "next_cursor or prev_cursor of a previous cursor page"
+// @Param with_total query bool false "Also count the total in cursor mode" default(false)
+// @Param fields query string false "Comma-separated fields to return (id, recommendor_id, name, description, image, address, category, rating, status, created_at, updated_at)"
+// @Param include query string false "Relationships to embed (recommendor)" Enums(recommendor)
+// @Success 200 {object} utils.PaginationResponse{data=[]models.Destination}
+// @Failure 400 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/recommendors/{id}/destinations [get]
+// @Router /api/recommendors/{id}/destinations [get]
+// @Router /api/admin/recommendors/{id}/destinations [get]
 func (dc *DestinationController) GetDestinationsByRecommendor(c *gin.Context) {
 	recommendorID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recommender ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	// Check if recommendor exists
 	var recommendor models.Recommendor
-	if err := config.DB.First(&recommendor, recommendorID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recommender not found"})
+	if err := dbWithContext(c).First(&recommendor, recommendorID).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
+		return
+	}
+
+	// Parse pagination and sorting parameters
+	pr, ok := paginationRequest(c, destinationSortFields)
+	if !ok {
+		return
+	}
+	if !cursorRequest(c, pr) {
 		return
 	}
 
-	// Parse pagination parameters
-	pr := utils.ParsePaginationRequest(
-		c.DefaultQuery("page", "1"),
-		c.DefaultQuery("page_size", "10"),
-		c.DefaultQuery("sort_by", "id"),
-		c.DefaultQuery("sort_order", "asc"),
-	)
+	// Parse the fields and include parameters
+	p, ok := projection(c, destinationFieldset, "")
+	if !ok {
+		return
+	}
 
 	// Build query
-	query := config.DB.Model(&models.Destination{}).
+	query := dbWithContext(c).Model(&models.Destination{}).
 		Where("recommendor_id = ?", recommendorID).
 		Where("status = ?", "active")
 
+	// Select the requested fields and preload the included relationships
+	query = p.Apply(query, pr.Sort)
+
 	var destinations []models.Destination
 
 	// Get paginated results
-	response, err := utils.Paginate(query, pr, &destinations, &models.Destination{})
+	page, err := utils.Paginate(query, pr, &destinations, &models.Destination{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve destinations: " + err.Error()})
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	// Return translated content in the request locale
+	if err := localizeDestinations(c, destinations); err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondPage(c, p, page)
 }
 
 // GetAdminDestinations retrieves destinations for admin (includes deleted ones and all statuses)
@@ -336,58 +425,56 @@ func (dc *DestinationController) GetDestinationsByRecommendor(c *gin.Context) {
 // @Description Retrieve all destinations including inactive ones for admin management
 // @Tags admin
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Param sort_by query string false "Sort by field" default(id)
-// @Param sort_order query string false "Sort order (asc/desc)" default(asc)
-// @Param name query string false "Filter by name"
-// @Param category query string false "Filter by category"
-// @Param recommendor_id query int false "Filter by recommendor ID"
-// @Param region_id query int false "Filter by region ID"
-// @Param status query string false "Filter by status"
-// @Success 200 {object} utils.PaginationResponse
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma-separated sort fields, prefix - for descending (id, name, category, rating, created_at, updated_at)"
+// @Param sort_by query string false "Sort by field (superseded by sort)" default(id)
+// @Param sort_order query string false "Sort order (asc/desc, superseded by sort)" default(asc)
+// @Param fields query string false "Comma-separated fields to return (id, recommendor_id, name, description, image, address, category, rating, status, created_at, updated_at)"
+// @Param include query string false "Relationships to embed (recommendor)" Enums(recommendor)
+// @Filters DestinationFilters
+// @Success 200 {object} utils.PaginationResponse{data=[]models.Destination}
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/destinations [get]
 // @Router /api/admin/destinations [get]
 func (dc *DestinationController) GetAdminDestinations(c *gin.Context) {
-	// Parse pagination parameters
-	pr := utils.ParsePaginationRequest(
-		c.DefaultQuery("page", "1"),
-		c.DefaultQuery("page_size", "10"),
-		c.DefaultQuery("sort_by", "id"),
-		c.DefaultQuery("sort_order", "asc"),
-	)
-
-	// Build query with preloads
-	query := config.DB.Model(&models.Destination{}).
-		Preload("Recommendor")
-
-	// Apply filters
-	if name := c.Query("name"); name != "" {
-		query = utils.ApplyFilter(query, "name", name)
+	// Parse pagination and sorting parameters
+	pr, ok := paginationRequest(c, destinationSortFields)
+	if !ok {
+		return
 	}
 
-	if category := c.Query("category"); category != "" {
-		query = utils.ApplyEqualFilter(query, "category", category)
+	// Parse the fields and include parameters
+	p, ok := projection(c, destinationFieldset, "recommendor")
+	if !ok {
+		return
 	}
 
-	if recommendorID := c.Query("recommendor_id"); recommendorID != "" {
-		if id, err := strconv.Atoi(recommendorID); err == nil {
-			query = utils.ApplyEqualFilter(query, "recommendor_id", id)
-		}
-	}
+	// Build query
+	query := dbWithContext(c).Model(&models.Destination{})
 
-	if status := c.Query("status"); status != "" {
-		query = utils.ApplyEqualFilter(query, "status", status)
+	// Apply filters
+	filters, ok := listFilters(c, DestinationFilters)
+	if !ok {
+		return
 	}
+	query = utils.ApplyFilters(query, filters)
+
+	// Select the requested fields and preload the included relationships
+	query = p.Apply(query, pr.Sort)
 
 	var destinations []models.Destination
 
 	// Get paginated results
-	response, err := utils.Paginate(query, pr, &destinations, &models.Destination{})
+	page, err := utils.Paginate(query, pr, &destinations, &models.Destination{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve destinations: " + err.Error()})
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondPage(c, p, page)
 }
@@ -0,0 +1,292 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+)
+
+func TestCreateDestination(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(req *controllers.CreateDestinationRequest)
+		code    int
+		errCode string
+	}{
+		{name: "valid destination", code: http.StatusCreated},
+		{name: "unknown recommendor", modify: func(req *controllers.CreateDestinationRequest) { req.RecommendorID += 1000 }, code: http.StatusNotFound, errCode: "RECOMMENDOR_NOT_FOUND"},
+		{name: "rating out of range", modify: func(req *controllers.CreateDestinationRequest) { req.Rating = 6 }, code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "missing name", modify: func(req *controllers.CreateDestinationRequest) { req.Name = "" }, code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+
+			req := controllers.CreateDestinationRequest{
+				RecommendorID: recommendor.ID,
+				Name:          "故宫",
+				Category:      "scenic_spot",
+				Rating:        4.8,
+			}
+			if tt.modify != nil {
+				tt.modify(&req)
+			}
+			w := h.Do("POST", "/api/v1/admin/destinations", req, h.AdminToken())
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code == http.StatusCreated {
+				var destination models.Destination
+				h.Decode(w, &destination)
+				if destination.ID == 0 || destination.Status != "active" || destination.RecommendorID != recommendor.ID {
+					t.Fatalf("unexpected destination: %+v", destination)
+				}
+			}
+		})
+	}
+}
+
+func TestGetDestinations(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		query string
+		other bool
+		admin bool
+		want  []string
+	}{
+		{name: "public active only", path: "/api/v1/destinations", want: []string{"故宫", "全聚德", "外滩"}},
+		{name: "public category", path: "/api/v1/destinations", query: "?category=food", want: []string{"全聚德"}},
+		{name: "public recommendor", path: "/api/v1/destinations", other: true, want: []string{"外滩"}},
+		{name: "public explicit status", path: "/api/v1/destinations", query: "?status=inactive", want: []string{"长城"}},
+		{name: "admin includes inactive", path: "/api/v1/admin/destinations", admin: true, want: []string{"故宫", "全聚德", "长城", "外滩"}},
+		{name: "legacy", path: "/api/destinations", query: "?page_size=1", want: []string{"故宫"}},
+		{name: "multi-key sort", path: "/api/v1/destinations", query: "?sort=-rating,name", want: []string{"全聚德", "外滩", "故宫"}},
+		{name: "ties broken by id", path: "/api/v1/destinations", query: "?sort=-rating", want: []string{"全聚德", "故宫", "外滩"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			other := h.CreateRecommendor()
+			h.CreateDestination(recommendor, func(d *models.Destination) { d.Name = "故宫" })
+			h.CreateDestination(recommendor, func(d *models.Destination) { d.Name, d.Category, d.Rating = "全聚德", "food", 4.8 })
+			h.CreateDestination(recommendor, func(d *models.Destination) { d.Name, d.Status = "长城", "inactive" })
+			h.CreateDestination(other, func(d *models.Destination) { d.Name = "外滩" })
+
+			token := ""
+			if tt.admin {
+				token = h.AdminToken()
+			}
+			query := tt.query
+			if tt.other {
+				query = fmt.Sprintf("?recommendor_id=%d", other.ID)
+			}
+			w := h.Do("GET", tt.path+query, nil, token)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			var destinations []models.Destination
+			if tt.path == "/api/destinations" {
+				// Legacy routes return the bare pagination response
+				var page struct {
+					Data []models.Destination `json:"data"`
+				}
+				decodeJSON(t, w.Body.Bytes(), &page)
+				destinations = page.Data
+			} else {
+				h.Decode(w, &destinations)
+			}
+			if got := destinationNames(destinations); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDestinationByID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      func(d *models.Destination) string
+		code    int
+		errCode string
+	}{
+		{name: "existing", id: func(d *models.Destination) string { return fmt.Sprint(d.ID) }, code: http.StatusOK},
+		{name: "unknown", id: func(d *models.Destination) string { return fmt.Sprint(d.ID + 1000) }, code: http.StatusNotFound, errCode: "DESTINATION_NOT_FOUND"},
+		{name: "invalid id", id: func(*models.Destination) string { return "abc" }, code: http.StatusBadRequest, errCode: "INVALID_ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			destination := h.CreateDestination(recommendor)
+
+			w := h.Do("GET", "/api/v1/destinations/"+tt.id(destination)+"?include=recommendor", nil, "")
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code == http.StatusOK {
+				var got models.Destination
+				h.Decode(w, &got)
+				if got.ID != destination.ID || got.Recommendor == nil || got.Recommendor.ID != recommendor.ID {
+					t.Fatalf("unexpected destination: %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetDestinationsByRecommendor(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		admin   bool
+		unknown bool
+		code    int
+		count   int
+	}{
+		{name: "public", path: "/api/v1/recommendors/%d/destinations", code: http.StatusOK, count: 1},
+		{name: "legacy admin", path: "/api/admin/recommendors/%d/destinations", admin: true, code: http.StatusOK, count: 1},
+		{name: "unknown recommendor", path: "/api/v1/recommendors/%d/destinations", unknown: true, code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			h.CreateDestination(recommendor)
+			h.CreateDestination(recommendor, func(d *models.Destination) { d.Status = "inactive" })
+			h.CreateDestination(h.CreateRecommendor())
+
+			id := recommendor.ID
+			if tt.unknown {
+				id += 1000
+			}
+			token := ""
+			if tt.admin {
+				token = h.AdminToken()
+			}
+			w := h.Do("GET", fmt.Sprintf(tt.path, id), nil, token)
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var page struct {
+				Data []models.Destination `json:"data"`
+			}
+			decodeJSON(t, w.Body.Bytes(), &page)
+			if len(page.Data) != tt.count {
+				t.Fatalf("got %d destinations, want %d", len(page.Data), tt.count)
+			}
+		})
+	}
+}
+
+func TestUpdateDestination(t *testing.T) {
+	rating := 4.9
+	badRating := 5.5
+	status := "inactive"
+
+	tests := []struct {
+		name    string
+		body    controllers.UpdateDestinationRequest
+		code    int
+		errCode string
+		check   func(t *testing.T, d models.Destination)
+	}{
+		{name: "rating", body: controllers.UpdateDestinationRequest{Rating: &rating}, code: http.StatusOK, check: func(t *testing.T, d models.Destination) {
+			if d.Rating != rating || d.Status != "active" {
+				t.Fatalf("rating = %v, status = %q", d.Rating, d.Status)
+			}
+		}},
+		{name: "deactivate", body: controllers.UpdateDestinationRequest{Status: &status}, code: http.StatusOK, check: func(t *testing.T, d models.Destination) {
+			if d.Status != status {
+				t.Fatalf("status = %q", d.Status)
+			}
+		}},
+		{name: "rating out of range", body: controllers.UpdateDestinationRequest{Rating: &badRating}, code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			destination := h.CreateDestination(h.CreateRecommendor())
+
+			w := h.Do("PUT", fmt.Sprintf("/api/v1/admin/destinations/%d", destination.ID), tt.body, h.AdminToken())
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.check != nil {
+				var stored models.Destination
+				if err := h.DB.First(&stored, destination.ID).Error; err != nil {
+					t.Fatal(err)
+				}
+				tt.check(t, stored)
+			}
+		})
+	}
+}
+
+func TestDeleteDestination(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		code    int
+		deleted bool
+	}{
+		{name: "v1", path: "/api/v1/admin/destinations/%d", code: http.StatusOK, deleted: true},
+		{name: "legacy", path: "/api/admin/destinations/%d", code: http.StatusOK, deleted: true},
+		{name: "unknown", path: "/api/v1/admin/destinations/1%d", code: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			destination := h.CreateDestination(h.CreateRecommendor())
+
+			w := h.Do("DELETE", fmt.Sprintf(tt.path, destination.ID), nil, h.AdminToken())
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+
+			var count int64
+			h.DB.Model(&models.Destination{}).Where("id = ?", destination.ID).Count(&count)
+			if deleted := count == 0; deleted != tt.deleted {
+				t.Fatalf("deleted = %v, want %v", deleted, tt.deleted)
+			}
+		})
+	}
+}
+
+func destinationNames(destinations []models.Destination) []string {
+	names := make([]string, 0, len(destinations))
+	for _, d := range destinations {
+		names = append(names, d.Name)
+	}
+	return names
+}
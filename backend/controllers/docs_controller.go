@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+
+	"tourism_recommendor/docs"
+
+	"github.com/gin-gonic/gin"
+	"github.com/swaggest/swgui/v5emb"
+)
+
+// DocsController serves the OpenAPI document and its interactive docs UI
+type DocsController struct {
+	ui http.Handler
+}
+
+// NewDocsController creates a DocsController whose UI at basePath loads the document from specPath
+func NewDocsController(specPath, basePath string) *DocsController {
+	return &DocsController{ui: v5emb.New("Tourism Recommender API", specPath, basePath)}
+}
+
+// GetOpenAPISpec returns the OpenAPI 3 document generated from the handler annotations
+// @Summary Get OpenAPI document
+// @Description Retrieve the OpenAPI 3 document of the v1 and legacy API
+// @Tags meta
+// @Produce json
+// @Success 200 {object} object
+// @Router /api/v1/openapi.json [get]
+// @x-envelope false
+func (dc *DocsController) GetOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", docs.OpenAPI)
+}
+
+// UI serves the bundled Swagger UI (assets are embedded, no CDN is required)
+func (dc *DocsController) UI(c *gin.Context) {
+	dc.ui.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,40 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tourism_recommendor/docs"
+	"tourism_recommendor/testutil"
+)
+
+func TestDocs(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		check       func(body []byte) bool
+	}{
+		{name: "openapi document", path: "/api/v1/openapi.json", contentType: "application/json", check: func(body []byte) bool { return bytes.Equal(body, docs.OpenAPI) }},
+		{name: "swagger ui", path: "/api/v1/docs/", contentType: "text/html", check: func(body []byte) bool { return bytes.Contains(body, []byte("/api/v1/openapi.json")) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+
+			w := h.Do("GET", tt.path, nil, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.contentType) {
+				t.Fatalf("content type = %q, want %q", contentType, tt.contentType)
+			}
+			if !tt.check(w.Body.Bytes()) {
+				t.Fatalf("unexpected body: %.200s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,551 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/logging"
+	"tourism_recommendor/middleware"
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Export file formats
+const (
+	ExportPNG   = "png"
+	ExportSVG   = "svg"
+	ExportBadge = "badge"
+)
+
+// ExportRetention is how long a finished export archive can be downloaded
+const ExportRetention = 24 * time.Hour
+
+// Export job tuning
+const (
+	exportLease         = 2 * time.Minute // an unfinished job whose lease has passed was left by a stopped process
+	exportSweepInterval = time.Minute     // how often expired archives and abandoned jobs are cleaned up
+)
+
+var (
+	// exportSlots limits how many exports run at once; the others wait as pending
+	exportSlots = make(chan struct{}, 1)
+	// exportsRunning tracks the background exports of this process
+	exportsRunning sync.WaitGroup
+)
+
+// ExportController handles bulk exports of recommender QR codes and badges
+type ExportController struct {
+	Recommendors *RecommendorController // renders the QR codes and badges
+}
+
+// NewExportController creates a new export controller
+func NewExportController(recommendors *RecommendorController) *ExportController {
+	return &ExportController{Recommendors: recommendors}
+}
+
+// CreateExportRequest selects the recommenders and files of an export. Without IDs or
+// filters every recommender is exported.
+type CreateExportRequest struct {
+	IDs          []uint   `json:"ids"`
+	ProvinceCode string   `json:"province_code"`
+	CityCode     string   `json:"city_code"`
+	DistrictCode string   `json:"district_code"`
+	Status       string   `json:"status" binding:"omitempty,oneof=active inactive"`
+	Types        []string `json:"types" binding:"omitempty,dive,oneof=web wxapp"`       // QR code types, both by default
+	Formats      []string `json:"formats" binding:"omitempty,dive,oneof=png svg badge"` // files per recommender, png and badge by default
+	Size         int      `json:"size" binding:"omitempty,min=64,max=1024"`             // QR code size in pixels, 512 by default
+}
+
+// ExportJobResponse is an export job with its progress and download link
+type ExportJobResponse struct {
+	models.ExportJob
+	Progress    float64 `json:"progress"`               // share of recommenders processed, from 0 to 1
+	DownloadURL string  `json:"download_url,omitempty"` // set once the export has completed
+}
+
+// CreateExport starts a background export of QR codes and badges
+// @Summary Export QR codes
+// @Description Start a background job that packs the QR codes and badges of the selected recommenders into a ZIP archive
+// @Description with a CSV manifest. Poll the returned job until it has completed, then download the archive.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body controllers.CreateExportRequest true "Recommenders and files to export"
+// @Success 202 {object} controllers.ExportJobResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/exports [post]
+func (ec *ExportController) CreateExport(c *gin.Context) {
+	var req CreateExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err)
+		return
+	}
+	if len(req.Types) == 0 {
+		req.Types = utils.QRCodeTypes
+	}
+	if len(req.Formats) == 0 {
+		req.Formats = []string{ExportPNG, ExportBadge}
+	}
+	if req.Size == 0 {
+		req.Size = 512
+	}
+
+	var total int64
+	if err := exportQuery(dbWithContext(c), req).Count(&total).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+	if total == 0 {
+		response.Error(c, response.ErrExportEmpty)
+		return
+	}
+
+	params, err := json.Marshal(req)
+	if err != nil {
+		response.Error(c, response.ErrInternal.WithCause(err))
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	lease := time.Now().Add(exportLease)
+	job := models.ExportJob{
+		Status:      models.ExportPending,
+		Params:      string(params),
+		Locale:      response.Locale(c),
+		Total:       int(total),
+		CreatedBy:   userID,
+		LockedUntil: &lease,
+	}
+	if err := dbWithContext(c).Create(&job).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	// The job outlives the request but keeps its logger and trace
+	ctx := context.WithoutCancel(c.Request.Context())
+	exportsRunning.Add(1)
+	go func() {
+		defer exportsRunning.Done()
+		ec.runExport(ctx, config.DB.WithContext(ctx), job, req)
+	}()
+
+	c.Header("Location", fmt.Sprintf("/api/v1/admin/recommendors/exports/%d", job.ID))
+	response.Accepted(c, exportJobResponse(job))
+}
+
+// GetExport reports the progress of an export
+// @Summary Get export
+// @Description Get the status and progress of an export job
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Export ID"
+// @Success 200 {object} controllers.ExportJobResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/exports/{id} [get]
+func (ec *ExportController) GetExport(c *gin.Context) {
+	job, ok := findExport(c)
+	if !ok {
+		return
+	}
+	response.OK(c, exportJobResponse(*job))
+}
+
+// DownloadExport streams the ZIP archive of a completed export
+// @Summary Download export
+// @Description Download the ZIP archive of a completed export: per-recommender QR codes and badges under {id}/, and manifest.csv
+// @Tags admin
+// @Produce zip
+// @Security BearerAuth
+// @Param id path int true "Export ID"
+// @Success 200 {file} file "ZIP archive"
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 409 {object} response.Body
+// @Router /api/v1/admin/recommendors/exports/{id}/download [get]
+// @x-envelope false
+func (ec *ExportController) DownloadExport(c *gin.Context) {
+	job, ok := findExport(c)
+	if !ok {
+		return
+	}
+	if job.Status != models.ExportCompleted {
+		response.Error(c, response.ErrExportNotReady.WithDetail("status", job.Status))
+		return
+	}
+
+	c.FileAttachment(job.FilePath, fmt.Sprintf("qrcodes-%d.zip", job.ID))
+}
+
+// WaitForExports blocks until the background exports of this process have finished or ctx is done
+func WaitForExports(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		exportsRunning.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// FailAbandonedExports marks unfinished exports whose lease has passed as failed. The process
+// running an export renews its lease, so only jobs left by a stopped process are affected;
+// jobs of other running instances are not.
+func FailAbandonedExports(db *gorm.DB) error {
+	now := time.Now()
+	return db.Model(&models.ExportJob{}).
+		Where("status IN ?", []string{models.ExportPending, models.ExportRunning}).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Updates(map[string]interface{}{
+			"status":       models.ExportFailed,
+			"error":        "interrupted by a server restart",
+			"completed_at": now,
+			"locked_until": nil,
+		}).Error
+}
+
+// SweepExports deletes expired archives and fails abandoned exports every exportSweepInterval
+// until ctx is done
+func SweepExports(ctx context.Context, db *gorm.DB) {
+	logger := logging.FromContext(ctx).With("component", "export_sweeper")
+	db = db.WithContext(context.WithoutCancel(ctx))
+
+	ticker := time.NewTicker(exportSweepInterval)
+	defer ticker.Stop()
+	for {
+		expireExports(db)
+		if err := FailAbandonedExports(db); err != nil {
+			logger.Error("failed to mark abandoned exports as failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// findExport loads the export named by the id path parameter, responding with an error when it cannot
+func findExport(c *gin.Context) (*models.ExportJob, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, response.ErrInvalidID)
+		return nil, false
+	}
+
+	var job models.ExportJob
+	if err := dbWithContext(c).First(&job, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrExportNotFound))
+		return nil, false
+	}
+	// The sweeper may not have run since the retention period ended
+	if exportExpired(&job, time.Now()) {
+		expireExport(dbWithContext(c), &job)
+	}
+	return &job, true
+}
+
+// exportJobResponse adds the progress and download link to a job
+func exportJobResponse(job models.ExportJob) ExportJobResponse {
+	res := ExportJobResponse{ExportJob: job}
+	if job.Total > 0 {
+		res.Progress = float64(job.Done) / float64(job.Total)
+	}
+	if job.Status == models.ExportCompleted {
+		res.DownloadURL = fmt.Sprintf("/api/v1/admin/recommendors/exports/%d/download", job.ID)
+	}
+	return res
+}
+
+// exportQuery selects the recommenders of an export
+func exportQuery(db *gorm.DB, req CreateExportRequest) *gorm.DB {
+	query := db.Model(&models.Recommendor{})
+	if len(req.IDs) > 0 {
+		query = query.Where("id IN ?", req.IDs)
+	}
+	if req.ProvinceCode != "" {
+		query = query.Where("province_code = ?", req.ProvinceCode)
+	}
+	if req.CityCode != "" {
+		query = query.Where("city_code = ?", req.CityCode)
+	}
+	if req.DistrictCode != "" {
+		query = query.Where("district_code = ?", req.DistrictCode)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	return query
+}
+
+// expireExports deletes the archives of exports finished more than ExportRetention ago
+func expireExports(db *gorm.DB) {
+	var jobs []models.ExportJob
+	if err := db.Where("status = ? AND completed_at < ?", models.ExportCompleted, time.Now().Add(-ExportRetention)).
+		Find(&jobs).Error; err != nil {
+		logging.FromContext(db.Statement.Context).Warn("failed to list expired exports", "error", err)
+		return
+	}
+	for i := range jobs {
+		expireExport(db, &jobs[i])
+	}
+}
+
+// exportExpired reports whether the archive of a completed job is past its retention period
+func exportExpired(job *models.ExportJob, now time.Time) bool {
+	return job.Status == models.ExportCompleted && job.CompletedAt != nil && now.After(job.CompletedAt.Add(ExportRetention))
+}
+
+// expireExport deletes the archive of a job and marks it expired. The job is left completed
+// when the archive cannot be deleted, so a later sweep tries again.
+func expireExport(db *gorm.DB, job *models.ExportJob) {
+	if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+		logging.FromContext(db.Statement.Context).Warn("failed to delete expired export", "export_id", job.ID, "error", err)
+		return
+	}
+	if err := db.Model(job).Updates(map[string]interface{}{"status": models.ExportExpired, "file_path": ""}).Error; err != nil {
+		logging.FromContext(db.Statement.Context).Warn("failed to mark export expired", "export_id", job.ID, "error", err)
+	}
+}
+
+// keepExportLease renews the lease of a job until the returned function is called
+func keepExportLease(ctx context.Context, db *gorm.DB, jobID uint) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(exportLease / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if err := db.Model(&models.ExportJob{}).
+				Where("id = ? AND status IN ?", jobID, []string{models.ExportPending, models.ExportRunning}).
+				UpdateColumn("locked_until", time.Now().Add(exportLease)).Error; err != nil {
+				logging.FromContext(ctx).Warn("failed to renew export lease", "export_id", jobID, "error", err)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// runExport builds the archive of a job and records the outcome
+func (ec *ExportController) runExport(ctx context.Context, db *gorm.DB, job models.ExportJob, req CreateExportRequest) {
+	// The lease covers the wait for a slot too, so the job is not taken for abandoned meanwhile
+	stopLease := keepExportLease(ctx, db, job.ID)
+	defer stopLease()
+
+	exportSlots <- struct{}{}
+	defer func() { <-exportSlots }()
+
+	logger := logging.FromContext(ctx).With("export_id", job.ID)
+	logger.Info("export started", "total", job.Total)
+
+	updates := map[string]interface{}{}
+	path, err := ec.writeExport(ctx, db, &job, req)
+	if err != nil {
+		logger.Error("export failed", "error", err)
+		updates["status"] = models.ExportFailed
+		updates["error"] = err.Error()
+	} else {
+		info, statErr := os.Stat(path)
+		if statErr == nil {
+			updates["file_size"] = info.Size()
+		}
+		updates["status"] = models.ExportCompleted
+		updates["file_path"] = path
+		logger.Info("export completed", "done", job.Done, "failed", job.Failed)
+	}
+	updates["completed_at"] = time.Now()
+	updates["locked_until"] = nil
+	if err := db.Model(&job).Updates(updates).Error; err != nil {
+		logger.Error("failed to record export result", "error", err)
+	}
+}
+
+// writeExport writes the archive of a job to ExportDir, updating the job's progress after
+// every recommender. Recommenders whose files cannot be generated are listed with the error
+// in the manifest; only failures to write the archive fail the job.
+func (ec *ExportController) writeExport(ctx context.Context, db *gorm.DB, job *models.ExportJob, req CreateExportRequest) (string, error) {
+	if err := db.Model(job).Update("status", models.ExportRunning).Error; err != nil {
+		return "", err
+	}
+
+	qrConfig, err := ec.Recommendors.qrCodeConfig()
+	if err != nil {
+		return "", err
+	}
+	opts := qrConfig.Options
+	opts.Size = req.Size
+
+	if err := utils.EnsureDirectory(utils.ExportDir); err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(utils.ExportDir, fmt.Sprintf("qrcodes-%d-*.zip.tmp", job.ID))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	archive := zip.NewWriter(file)
+	var manifest bytes.Buffer
+	rows := csv.NewWriter(&manifest)
+	rows.Write([]string{"id", "name", "region_address", "status", "valid_from", "valid_until", "web_url", "files", "error"})
+
+	var recommendors []models.Recommendor
+	err = exportQuery(db, req).Order("id").FindInBatches(&recommendors, 50, func(tx *gorm.DB, batch int) error {
+		for i := range recommendors {
+			r := &recommendors[i]
+			files, genErr, err := ec.exportRecommendor(ctx, archive, qrConfig, opts, r, req, job.Locale)
+			if err != nil {
+				return err
+			}
+			// The URL printed codes encode
+			codeURL, urlErr := utils.RecommendorCodeURL(ctx, qrConfig, r.ID)
+			if genErr == nil {
+				genErr = urlErr
+			}
+
+			errText := ""
+			if genErr != nil {
+				errText = genErr.Error()
+				job.Failed++
+			}
+			rows.Write([]string{
+				strconv.FormatUint(uint64(r.ID), 10), r.Name, r.RegionAddress, r.Status,
+				r.ValidFrom.Format("2006-01-02"), r.ValidUntil.Format("2006-01-02"),
+				codeURL,
+				strings.Join(files, " "), errText,
+			})
+
+			job.Done++
+			if err := db.Model(job).UpdateColumns(map[string]interface{}{"done": job.Done, "failed": job.Failed}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return "", err
+	}
+
+	rows.Flush()
+	if err := rows.Error(); err != nil {
+		return "", err
+	}
+	w, err := archive.Create("manifest.csv")
+	if err != nil {
+		return "", err
+	}
+	// A byte order mark lets spreadsheet applications detect UTF-8
+	if _, err := w.Write(append([]byte("\xef\xbb\xbf"), manifest.Bytes()...)); err != nil {
+		return "", err
+	}
+	if err := archive.Close(); err != nil {
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(utils.ExportDir, fmt.Sprintf("qrcodes-%d.zip", job.ID))
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// exportRecommendor adds the files of a recommender to the archive and returns their names.
+// A file that cannot be generated ends the recommender's files with genErr; err reports a
+// failure to write the archive.
+func (ec *ExportController) exportRecommendor(ctx context.Context, archive *zip.Writer, qrConfig utils.QRCodeConfig, opts utils.QRCodeOptions, r *models.Recommendor, req CreateExportRequest, locale string) (files []string, genErr, err error) {
+	add := func(name string, data []byte, method uint16) error {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		files = append(files, name)
+		return nil
+	}
+
+	codes := newRecommendorQRCodes(ctx, qrConfig, r.ID)
+	for _, format := range []string{ExportPNG, ExportSVG} {
+		if !slices.Contains(req.Formats, format) {
+			continue
+		}
+		opts.Format = format
+		for _, kind := range req.Types {
+			image, genErr := codes.draw(kind, opts)
+			if genErr != nil {
+				return files, genErr, nil
+			}
+			// PNG is compressed already
+			method := zip.Deflate
+			if format == ExportPNG {
+				method = zip.Store
+			}
+			if err := add(fmt.Sprintf("%d/%s.%s", r.ID, kind, format), image, method); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if slices.Contains(req.Formats, ExportBadge) {
+		webQR, wxappQR, genErr := badgeQRCodes(codes)
+		if genErr != nil {
+			return files, genErr, nil
+		}
+		pdf, genErr := ec.Recommendors.renderBadge(ctx, qrConfig, r, webQR, wxappQR, locale)
+		if genErr != nil {
+			return files, genErr, nil
+		}
+		if err := add(fmt.Sprintf("%d/badge.pdf", r.ID), pdf, zip.Deflate); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return files, nil, nil
+}
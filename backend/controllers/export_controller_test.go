@@ -0,0 +1,297 @@
+package controllers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+	"tourism_recommendor/utils"
+)
+
+func TestCreateExport(t *testing.T) {
+	h := testutil.New(t)
+	first := h.CreateRecommendor()
+	second := h.CreateRecommendor()
+
+	tests := []struct {
+		name    string
+		body    interface{}
+		token   string
+		code    int
+		errCode string
+		total   int
+		files   []string
+	}{
+		{
+			name:  "defaults",
+			body:  controllers.CreateExportRequest{IDs: []uint{first.ID, second.ID}},
+			token: h.AdminToken(),
+			code:  http.StatusAccepted,
+			total: 2,
+			files: []string{
+				fmt.Sprintf("%d/web.png", first.ID), fmt.Sprintf("%d/wxapp.png", first.ID), fmt.Sprintf("%d/badge.pdf", first.ID),
+				fmt.Sprintf("%d/web.png", second.ID), fmt.Sprintf("%d/wxapp.png", second.ID), fmt.Sprintf("%d/badge.pdf", second.ID),
+				"manifest.csv",
+			},
+		},
+		{
+			name:  "svg only",
+			body:  controllers.CreateExportRequest{IDs: []uint{first.ID}, Types: []string{"web"}, Formats: []string{"svg"}, Size: 128},
+			token: h.AdminToken(),
+			code:  http.StatusAccepted,
+			total: 1,
+			files: []string{fmt.Sprintf("%d/web.svg", first.ID), "manifest.csv"},
+		},
+		{
+			name:    "no matches",
+			body:    controllers.CreateExportRequest{ProvinceCode: "000000"},
+			token:   h.AdminToken(),
+			code:    http.StatusBadRequest,
+			errCode: "EXPORT_EMPTY",
+		},
+		{
+			name:    "unknown format",
+			body:    map[string]interface{}{"formats": []string{"gif"}},
+			token:   h.AdminToken(),
+			code:    http.StatusBadRequest,
+			errCode: "VALIDATION_FAILED",
+		},
+		{
+			name:    "size too large",
+			body:    controllers.CreateExportRequest{Size: 4096},
+			token:   h.AdminToken(),
+			code:    http.StatusBadRequest,
+			errCode: "VALIDATION_FAILED",
+		},
+		{
+			name:    "unauthenticated",
+			body:    controllers.CreateExportRequest{},
+			code:    http.StatusUnauthorized,
+			errCode: "AUTH_HEADER_MISSING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("POST", "/api/v1/admin/recommendors/exports", tt.body, tt.token)
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if tt.code != http.StatusAccepted {
+				if code := h.ErrorCode(w); code != tt.errCode {
+					t.Fatalf("error code = %q, want %q", code, tt.errCode)
+				}
+				return
+			}
+
+			var job controllers.ExportJobResponse
+			h.Decode(w, &job)
+			if job.Total != tt.total {
+				t.Fatalf("total = %d, want %d", job.Total, tt.total)
+			}
+			location := fmt.Sprintf("/api/v1/admin/recommendors/exports/%d", job.ID)
+			if got := w.Header().Get("Location"); got != location {
+				t.Fatalf("location = %q, want %q", got, location)
+			}
+
+			if err := controllers.WaitForExports(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			w = h.Do("GET", location, nil, h.AdminToken())
+			h.Decode(w, &job)
+			if job.Status != models.ExportCompleted || job.Done != tt.total || job.Failed != 0 || job.Progress != 1 {
+				t.Fatalf("job = %+v, want completed", job)
+			}
+			if job.DownloadURL != location+"/download" {
+				t.Fatalf("download url = %q", job.DownloadURL)
+			}
+
+			w = h.Do("GET", job.DownloadURL, nil, h.AdminToken())
+			if w.Code != http.StatusOK {
+				t.Fatalf("download status = %d: %s", w.Code, w.Body.String())
+			}
+			archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+			if err != nil {
+				t.Fatalf("download is not a ZIP archive: %v", err)
+			}
+			var names []string
+			for _, f := range archive.File {
+				names = append(names, f.Name)
+			}
+			if !slices.Equal(names, tt.files) {
+				t.Fatalf("files = %v, want %v", names, tt.files)
+			}
+
+			manifest, err := archive.Open("manifest.csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != tt.total+1 || rows[0][0] != "id" || rows[1][0] != fmt.Sprint(first.ID) {
+				t.Fatalf("manifest = %v", rows)
+			}
+			// The listed URL is the short link the printed web code encodes
+			var link models.ShortLink
+			if err := h.DB.Where("recommendor_id = ? AND type = ?", first.ID, utils.QRCodeWeb).First(&link).Error; err != nil {
+				t.Fatal(err)
+			}
+			if want := utils.ShortLinkURL(h.Config.QRCode.BaseURL, link.Code); rows[0][6] != "web_url" || rows[1][6] != want {
+				t.Fatalf("manifest url = %q, want %q", rows[1][6], want)
+			}
+		})
+	}
+}
+
+func TestExportDrawsMiniProgramCodesOnce(t *testing.T) {
+	h := testutil.New(t)
+	first := h.CreateRecommendor()
+	second := h.CreateRecommendor()
+
+	body := controllers.CreateExportRequest{IDs: []uint{first.ID, second.ID}, Formats: []string{"png", "svg", "badge"}, Size: 512}
+	w := h.Do("POST", "/api/v1/admin/recommendors/exports", body, h.AdminToken())
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if err := controllers.WaitForExports(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var job controllers.ExportJobResponse
+	h.Decode(h.Do("GET", w.Header().Get("Location"), nil, h.AdminToken()), &job)
+	if job.Status != models.ExportCompleted || job.Failed != 0 {
+		t.Fatalf("job = %+v, want completed", job)
+	}
+	// The PNG, SVG and badge codes are all scaled from the one code WeChat drew per recommender
+	if calls := h.WeChat.Calls(testutil.WeChatWxaCodePath); calls != 2 {
+		t.Fatalf("code calls = %d, want 2", calls)
+	}
+}
+
+func TestDownloadExport(t *testing.T) {
+	h := testutil.New(t)
+
+	pending := models.ExportJob{Status: models.ExportPending, Total: 1}
+	if err := h.DB.Create(&pending).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Finished before the retention period, but not swept yet
+	archive := filepath.Join(t.TempDir(), "qrcodes.zip")
+	if err := os.WriteFile(archive, []byte("PK"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	completedAt := time.Now().Add(-controllers.ExportRetention - time.Minute)
+	stale := models.ExportJob{Status: models.ExportCompleted, Total: 1, Done: 1, FilePath: archive, CompletedAt: &completedAt}
+	if err := h.DB.Create(&stale).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		code    int
+		errCode string
+	}{
+		{name: "get pending", path: fmt.Sprintf("/api/v1/admin/recommendors/exports/%d", pending.ID), code: http.StatusOK},
+		{name: "download pending", path: fmt.Sprintf("/api/v1/admin/recommendors/exports/%d/download", pending.ID), code: http.StatusConflict, errCode: "EXPORT_NOT_READY"},
+		{name: "download past retention", path: fmt.Sprintf("/api/v1/admin/recommendors/exports/%d/download", stale.ID), code: http.StatusConflict, errCode: "EXPORT_NOT_READY"},
+		{name: "get unknown", path: "/api/v1/admin/recommendors/exports/999999", code: http.StatusNotFound, errCode: "EXPORT_NOT_FOUND"},
+		{name: "download unknown", path: "/api/v1/admin/recommendors/exports/999999/download", code: http.StatusNotFound, errCode: "EXPORT_NOT_FOUND"},
+		{name: "invalid id", path: "/api/v1/admin/recommendors/exports/abc", code: http.StatusBadRequest, errCode: "INVALID_ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("GET", tt.path, nil, h.AdminToken())
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+		})
+	}
+}
+
+func TestDownloadExportExpiresArchive(t *testing.T) {
+	h := testutil.New(t)
+
+	archive := filepath.Join(t.TempDir(), "qrcodes.zip")
+	if err := os.WriteFile(archive, []byte("PK"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	completedAt := time.Now().Add(-controllers.ExportRetention - time.Minute)
+	job := models.ExportJob{Status: models.ExportCompleted, Total: 1, Done: 1, FilePath: archive, CompletedAt: &completedAt}
+	if err := h.DB.Create(&job).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w := h.Do("GET", fmt.Sprintf("/api/v1/admin/recommendors/exports/%d", job.ID), nil, h.AdminToken())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var got controllers.ExportJobResponse
+	h.Decode(w, &got)
+	if got.Status != models.ExportExpired || got.DownloadURL != "" {
+		t.Fatalf("status = %q, download URL = %q; want expired without a link", got.Status, got.DownloadURL)
+	}
+	if _, err := os.Stat(archive); !os.IsNotExist(err) {
+		t.Fatalf("archive not deleted: %v", err)
+	}
+}
+
+func TestFailAbandonedExports(t *testing.T) {
+	h := testutil.New(t)
+
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	jobs := map[string]*models.ExportJob{
+		"lease passed":  {Status: models.ExportRunning, Total: 1, LockedUntil: &past},
+		"no lease":      {Status: models.ExportPending, Total: 1},
+		"leased":        {Status: models.ExportRunning, Total: 1, LockedUntil: &future},
+		"leased, queue": {Status: models.ExportPending, Total: 1, LockedUntil: &future},
+	}
+	for _, job := range jobs {
+		if err := h.DB.Create(job).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := controllers.FailAbandonedExports(h.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"lease passed":  models.ExportFailed,
+		"no lease":      models.ExportFailed,
+		"leased":        models.ExportRunning,
+		"leased, queue": models.ExportPending,
+	}
+	for name, job := range jobs {
+		var got models.ExportJob
+		if err := h.DB.First(&got, job.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != want[name] {
+			t.Errorf("%s: status = %q, want %q", name, got.Status, want[name])
+		}
+	}
+}
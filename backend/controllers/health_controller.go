@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Health check statuses
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusFail     = "fail"
+	HealthStatusDegraded = "degraded"
+	HealthStatusSkipped  = "skipped"
+)
+
+// defaultCheckTimeout bounds each dependency probe
+const defaultCheckTimeout = 2 * time.Second
+
+// shuttingDown is set when the server starts graceful shutdown so readiness fails
+var shuttingDown atomic.Bool
+
+// SetShuttingDown marks the server as (not) shutting down for readiness probes
+func SetShuttingDown(v bool) {
+	shuttingDown.Store(v)
+}
+
+// HealthController handles liveness and readiness probes
+type HealthController struct {
+	DB           *gorm.DB
+	Config       *config.Config
+	WeChat       utils.WeChatClient // nil when WeChat is not configured
+	CheckTimeout time.Duration
+}
+
+// NewHealthController creates a new HealthController instance
+func NewHealthController(db *gorm.DB, cfg *config.Config, wechat utils.WeChatClient) *HealthController {
+	return &HealthController{DB: db, Config: cfg, WeChat: wechat, CheckTimeout: defaultCheckTimeout}
+}
+
+// HealthCheckResult represents the outcome of a single dependency probe
+type HealthCheckResult struct {
+	Status     string `json:"status"`
+	Critical   bool   `json:"critical"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// HealthResponse represents the aggregated probe response
+type HealthResponse struct {
+	Status    string                       `json:"status"`
+	Timestamp time.Time                    `json:"timestamp"`
+	Checks    map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// healthCheck is a named dependency probe
+type healthCheck struct {
+	name     string
+	critical bool
+	run      func(ctx context.Context) (status string, err error)
+}
+
+// Liveness reports whether the process is alive; it never checks dependencies
+// @Summary Liveness probe
+// @Description Report whether the API process is running
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func (hc *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{
+		Status:    HealthStatusOK,
+		Timestamp: time.Now(),
+	})
+}
+
+// Ping reports that the API is running, in the v1 response envelope
+// @Summary API health check
+// @Description Report that the API is running
+// @Tags health
+// @Produce json
+// @Success 200 {object} response.MessageBody{data=map[string]string}
+// @Router /api/v1/health [get]
+func (hc *HealthController) Ping(c *gin.Context) {
+	response.Message(c, "message.health.running", gin.H{"status": HealthStatusOK})
+}
+
+// Readiness reports whether the API can serve traffic by probing its dependencies
+// @Summary Readiness probe
+// @Description Check database connectivity, migration state, upload storage and WeChat token availability
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
+// @Router /readyz [get]
+func (hc *HealthController) Readiness(c *gin.Context) {
+	if shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, HealthResponse{
+			Status:    HealthStatusFail,
+			Timestamp: time.Now(),
+			Checks: map[string]HealthCheckResult{
+				"shutdown": {Status: HealthStatusFail, Critical: true, Error: "server is shutting down"},
+			},
+		})
+		return
+	}
+
+	checks := []healthCheck{
+		{name: "database", critical: true, run: hc.checkDatabase},
+		{name: "migrations", critical: true, run: hc.checkMigrations},
+		{name: "storage", critical: true, run: hc.checkStorage},
+		{name: "wechat", critical: false, run: hc.checkWeChat},
+	}
+
+	results := make(map[string]HealthCheckResult, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check healthCheck) {
+			defer wg.Done()
+			result := hc.runCheck(c.Request.Context(), check)
+			mu.Lock()
+			results[check.name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	status := HealthStatusOK
+	for _, result := range results {
+		if result.Status != HealthStatusFail {
+			continue
+		}
+		if result.Critical {
+			status = HealthStatusFail
+			break
+		}
+		status = HealthStatusDegraded
+	}
+
+	httpStatus := http.StatusOK
+	if status == HealthStatusFail {
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	c.JSON(httpStatus, HealthResponse{
+		Status:    status,
+		Timestamp: time.Now(),
+		Checks:    results,
+	})
+}
+
+// runCheck executes a probe with its own timeout
+func (hc *HealthController) runCheck(parent context.Context, check healthCheck) HealthCheckResult {
+	timeout := hc.CheckTimeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	type outcome struct {
+		status string
+		err    error
+	}
+
+	start := time.Now()
+	done := make(chan outcome, 1)
+	go func() {
+		status, err := check.run(ctx)
+		done <- outcome{status: status, err: err}
+	}()
+
+	result := HealthCheckResult{Critical: check.critical}
+	select {
+	case out := <-done:
+		result.Status = out.status
+		if out.err != nil {
+			result.Status = HealthStatusFail
+			result.Error = out.err.Error()
+		}
+	case <-ctx.Done():
+		result.Status = HealthStatusFail
+		result.Error = fmt.Sprintf("timed out after %v", timeout)
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	return result
+}
+
+// checkDatabase pings the database
+func (hc *HealthController) checkDatabase(ctx context.Context) (string, error) {
+	if hc.DB == nil {
+		return HealthStatusFail, fmt.Errorf("database is not initialized")
+	}
+
+	sqlDB, err := hc.DB.DB()
+	if err != nil {
+		return HealthStatusFail, err
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return HealthStatusFail, err
+	}
+
+	return HealthStatusOK, nil
+}
+
+// checkMigrations verifies that every model table exists
+func (hc *HealthController) checkMigrations(ctx context.Context) (string, error) {
+	if hc.DB == nil {
+		return HealthStatusFail, fmt.Errorf("database is not initialized")
+	}
+
+	migrator := hc.DB.WithContext(ctx).Migrator()
+	for _, model := range models.All() {
+		if !migrator.HasTable(model) {
+			return HealthStatusFail, fmt.Errorf("table for %T is missing", model)
+		}
+	}
+
+	return HealthStatusOK, nil
+}
+
+// checkStorage verifies that the upload directory is writable
+func (hc *HealthController) checkStorage(ctx context.Context) (string, error) {
+	file, err := os.CreateTemp(utils.UploadDir, ".healthcheck-*")
+	if err != nil {
+		return HealthStatusFail, fmt.Errorf("upload directory is not writable: %v", err)
+	}
+	name := file.Name()
+	file.Close()
+
+	if err := os.Remove(name); err != nil {
+		return HealthStatusFail, fmt.Errorf("failed to remove probe file: %v", err)
+	}
+
+	return HealthStatusOK, nil
+}
+
+// checkWeChat verifies that a WeChat access token can be obtained when WeChat is configured
+func (hc *HealthController) checkWeChat(ctx context.Context) (string, error) {
+	if hc.WeChat == nil {
+		return HealthStatusSkipped, nil
+	}
+
+	if _, err := hc.WeChat.AccessToken(ctx); err != nil {
+		return HealthStatusFail, err
+	}
+
+	return HealthStatusOK, nil
+}
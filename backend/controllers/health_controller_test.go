@@ -0,0 +1,67 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/testutil"
+)
+
+func TestHealthProbes(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		shuttingDown bool
+		wechatErr    int
+		code         int
+		status       string
+		wechat       string
+	}{
+		{name: "liveness", path: "/healthz", code: http.StatusOK, status: controllers.HealthStatusOK},
+		{name: "readiness", path: "/readyz", code: http.StatusOK, status: controllers.HealthStatusOK, wechat: controllers.HealthStatusOK},
+		{name: "readiness with WeChat rejecting the secret", path: "/readyz", wechatErr: 40125, code: http.StatusOK, status: controllers.HealthStatusDegraded, wechat: controllers.HealthStatusFail},
+		{name: "readiness while shutting down", path: "/readyz", shuttingDown: true, code: http.StatusServiceUnavailable, status: controllers.HealthStatusFail},
+		{name: "liveness while shutting down", path: "/healthz", shuttingDown: true, code: http.StatusOK, status: controllers.HealthStatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			controllers.SetShuttingDown(tt.shuttingDown)
+			t.Cleanup(func() { controllers.SetShuttingDown(false) })
+			if tt.wechatErr != 0 {
+				h.WeChat.FailWithCode(testutil.WeChatTokenPath, 1, tt.wechatErr)
+			}
+
+			w := h.Do("GET", tt.path, nil, "")
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			var resp controllers.HealthResponse
+			decodeJSON(t, w.Body.Bytes(), &resp)
+			if resp.Status != tt.status {
+				t.Fatalf("health status = %q, want %q: %s", resp.Status, tt.status, w.Body.String())
+			}
+			if tt.wechat != "" && resp.Checks["wechat"].Status != tt.wechat {
+				t.Fatalf("wechat check = %q, want %q: %s", resp.Checks["wechat"].Status, tt.wechat, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	h := testutil.New(t)
+
+	w := h.Do("GET", "/api/v1/health", nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var data map[string]string
+	envelope := h.Decode(w, &data)
+	if !envelope.Success || data["status"] != controllers.HealthStatusOK {
+		t.Fatalf("unexpected response: %s", w.Body.String())
+	}
+}
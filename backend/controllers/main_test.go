@@ -0,0 +1,21 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"tourism_recommendor/testutil"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testutil.Main(m))
+}
+
+// decodeJSON decodes a bare (legacy) response body
+func decodeJSON(t *testing.T, data []byte, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("failed to decode %s: %v", data, err)
+	}
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetaController serves reference data for clients
+type MetaController struct{}
+
+// GetEnums returns the values of enumerated fields with labels in the request locale
+// @Summary Get enum labels
+// @Description Retrieve gender, status, admin role and destination category values with localized labels
+// @Tags meta
+// @Produce json
+// @Success 200 {object} map[string][]i18n.EnumOption
+// @Router /api/v1/enums [get]
+func (mc *MetaController) GetEnums(c *gin.Context) {
+	response.OK(c, i18n.EnumOptions(response.Locale(c)))
+}
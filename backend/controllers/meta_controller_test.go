@@ -0,0 +1,50 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/testutil"
+)
+
+func TestGetEnums(t *testing.T) {
+	tests := []struct {
+		language string
+		locale   string
+	}{
+		{language: "en-US,en;q=0.9", locale: i18n.LocaleEN},
+		{language: "zh-CN", locale: i18n.LocaleZH},
+		{language: "", locale: i18n.LocaleZH},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+tt.language, func(t *testing.T) {
+			h := testutil.New(t)
+
+			req := httptest.NewRequest("GET", "/api/v1/enums", nil)
+			if tt.language != "" {
+				req.Header.Set("Accept-Language", tt.language)
+			}
+			w := h.Serve(req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			var enums map[string][]i18n.EnumOption
+			h.Decode(w, &enums)
+			want := i18n.EnumOptions(tt.locale)
+			for name, options := range want {
+				if len(enums[name]) != len(options) {
+					t.Fatalf("%s: got %d options, want %d", name, len(enums[name]), len(options))
+				}
+				for i, option := range options {
+					if enums[name][i] != option {
+						t.Fatalf("%s[%d] = %+v, want %+v", name, i, enums[name][i], option)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/logging"
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QR code job tuning
+const (
+	QRCodeJobAttempts     = 5                // attempts before a job fails for good
+	qrCodeJobRetryDelay   = 30 * time.Second // doubles with every failed attempt
+	qrCodeJobLease        = 2 * time.Minute  // a running job is picked up again once its lease has passed
+	qrCodeJobBatch        = 10
+	qrCodeJobPollInterval = 5 * time.Second
+)
+
+// qrCodeJobSortFields are the columns clients may sort QR code jobs by
+var qrCodeJobSortFields = []string{"id", "attempts", "run_at", "created_at", "updated_at"}
+
+// QRCodeJobFilters are the filters accepted by the QR code job list
+var QRCodeJobFilters = utils.FilterSpec{
+	{Name: "status", Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Values: []string{models.QRCodeJobPending, models.QRCodeJobRunning, models.QRCodeJobFailed}, Description: "Job status"},
+	{Name: "recommendor_id", Type: utils.FilterInt, Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Description: "Recommendor ID"},
+}
+
+// QRCodeQueue generates recommender QR codes in the background from the durable qrcode_jobs
+// table, so writes succeed while WeChat is unavailable. Any number of processes may work
+// the queue; a job is claimed with a lease and retried with backoff when generation fails.
+type QRCodeQueue struct {
+	Recommendors *RecommendorController // draws and stores the QR codes
+	wake         chan struct{}
+}
+
+// newQRCodeQueue creates the QR code queue of a recommendor controller
+func newQRCodeQueue(recommendors *RecommendorController) *QRCodeQueue {
+	return &QRCodeQueue{Recommendors: recommendors, wake: make(chan struct{}, 1)}
+}
+
+// GetQRCodeJobs lists queued and failed QR code generations
+// @Summary List QR code jobs
+// @Description List queued and failed QR code generations with their last error. Jobs are removed once the codes are
+// @Description stored, so status=failed lists the recommenders whose codes could not be generated.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "Comma-separated sort fields, prefix - for descending (id, attempts, run_at, created_at, updated_at)"
+// @Filters QRCodeJobFilters
+// @Success 200 {object} utils.PaginationResponse{data=[]models.QRCodeJob}
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/qrcode-jobs [get]
+func (q *QRCodeQueue) GetQRCodeJobs(c *gin.Context) {
+	pr, ok := paginationRequest(c, qrCodeJobSortFields)
+	if !ok {
+		return
+	}
+	filters, ok := listFilters(c, QRCodeJobFilters)
+	if !ok {
+		return
+	}
+	query := utils.ApplyFilters(dbWithContext(c).Model(&models.QRCodeJob{}), filters).Preload("Recommendor")
+
+	var jobs []models.QRCodeJob
+	page, err := utils.Paginate(query, pr, &jobs, &models.QRCodeJob{})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	response.Paginated(c, page)
+}
+
+// RetryQRCodeJob queues a QR code job again with a fresh set of attempts
+// @Summary Retry QR code job
+// @Description Queue a QR code generation again, typically one that has failed, with a fresh set of attempts
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "QR code job ID"
+// @Success 202 {object} models.QRCodeJob
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/qrcode-jobs/{id}/retry [post]
+func (q *QRCodeQueue) RetryQRCodeJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, response.ErrInvalidID)
+		return
+	}
+
+	var job models.QRCodeJob
+	if err := dbWithContext(c).First(&job, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrQRCodeJobNotFound))
+		return
+	}
+	if err := dbWithContext(c).Transaction(func(tx *gorm.DB) error {
+		return enqueueQRCodes(tx, job.RecommendorID)
+	}); err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+	q.Notify()
+
+	if err := dbWithContext(c).Preload("Recommendor").First(&job, id).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+	response.Accepted(c, job)
+}
+
+// Notify wakes the worker after jobs have been committed
+func (q *QRCodeQueue) Notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run works the queue until ctx is done. It first queues the recommenders whose codes were
+// drawn with other settings. A job in progress is finished before Run returns.
+func (q *QRCodeQueue) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx).With("component", "qrcode_queue")
+	// Database calls must not be cut off halfway through a job
+	jobCtx := context.WithoutCancel(ctx)
+
+	if queued, err := q.EnqueueStale(jobCtx); err != nil {
+		logger.Error("failed to queue outdated QR codes", "error", err)
+	} else if queued > 0 {
+		logger.Info("queued outdated QR codes", "count", queued)
+	}
+
+	ticker := time.NewTicker(qrCodeJobPollInterval)
+	defer ticker.Stop()
+	for {
+		for ctx.Err() == nil {
+			processed, err := q.RunPending(jobCtx)
+			if err != nil {
+				logger.Error("failed to claim QR code jobs", "error", err)
+			}
+			if processed < qrCodeJobBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// RunPending claims the jobs that are due, up to one batch, runs them and returns how many it ran
+func (q *QRCodeQueue) RunPending(ctx context.Context) (int, error) {
+	db := config.DB.WithContext(ctx)
+	now := time.Now()
+	due := db.Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)",
+		models.QRCodeJobPending, now, models.QRCodeJobRunning, now)
+
+	var candidates []models.QRCodeJob
+	if err := db.Where(due).Order("run_at").Limit(qrCodeJobBatch).Find(&candidates).Error; err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, job := range candidates {
+		// Another worker may have claimed the job since it was listed
+		lease := now.Add(qrCodeJobLease)
+		claim := db.Model(&models.QRCodeJob{}).Where("id = ?", job.ID).Where(due).
+			Updates(map[string]interface{}{"status": models.QRCodeJobRunning, "locked_until": lease})
+		if claim.Error != nil {
+			return processed, claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		q.runJob(ctx, db, job)
+		processed++
+	}
+	return processed, nil
+}
+
+// EnqueueStale queues the recommenders whose codes were drawn with other settings than the
+// current ones and that have no job yet, and returns how many it queued
+func (q *QRCodeQueue) EnqueueStale(ctx context.Context) (int, error) {
+	db := config.DB.WithContext(ctx)
+
+	var ids []uint
+	if err := db.Model(&models.Recommendor{}).
+		Where("qr_inputs IS NULL OR qr_inputs <> ?", q.Recommendors.qrCodeInputs()).
+		Where("id NOT IN (?)", db.Model(&models.QRCodeJob{}).Select("recommendor_id")).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	for start := 0; start < len(ids); start += 500 {
+		chunk := ids[start:min(start+500, len(ids))]
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return enqueueQRCodes(tx, chunk...)
+		}); err != nil {
+			return start, err
+		}
+	}
+	return len(ids), nil
+}
+
+// runJob generates the codes of a claimed job and records the outcome
+func (q *QRCodeQueue) runJob(ctx context.Context, db *gorm.DB, job models.QRCodeJob) {
+	logger := logging.FromContext(ctx).With("qrcode_job_id", job.ID, "recommendor_id", job.RecommendorID)
+
+	var recommendor models.Recommendor
+	err := db.First(&recommendor, job.RecommendorID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted since it was queued
+		db.Delete(&job)
+		return
+	}
+	if err == nil {
+		err = q.Recommendors.generateQRCodes(ctx, &recommendor)
+	}
+	if err == nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// A job queued again while this one ran stays queued and stores the codes once more
+			done := tx.Where("id = ? AND status = ?", job.ID, models.QRCodeJobRunning).Delete(&models.QRCodeJob{})
+			if done.Error != nil {
+				return done.Error
+			}
+			updates := map[string]interface{}{
+				"qr_code_web":   recommendor.QRCodeWeb,
+				"qr_code_wxapp": recommendor.QRCodeWxapp,
+				"qr_inputs":     recommendor.QRInputs,
+			}
+			if done.RowsAffected > 0 {
+				updates["qr_status"] = models.QRStatusReady
+			}
+			return tx.Model(&recommendor).UpdateColumns(updates).Error
+		})
+	}
+	if err == nil {
+		logger.Info("generated QR codes", "attempt", job.Attempts+1)
+		return
+	}
+
+	attempts := job.Attempts + 1
+	updates := map[string]interface{}{"attempts": attempts, "last_error": err.Error(), "locked_until": nil}
+	if attempts >= QRCodeJobAttempts {
+		logger.Error("QR code generation failed", "attempts", attempts, "error", err)
+		updates["status"] = models.QRCodeJobFailed
+	} else {
+		delay := qrCodeJobRetryDelay << (attempts - 1)
+		logger.Warn("QR code generation failed, retrying", "attempts", attempts, "retry_in", delay.String(), "error", err)
+		updates["status"] = models.QRCodeJobPending
+		updates["run_at"] = time.Now().Add(delay)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		failed := tx.Model(&models.QRCodeJob{}).Where("id = ? AND status = ?", job.ID, models.QRCodeJobRunning).Updates(updates)
+		if failed.Error != nil || failed.RowsAffected == 0 || updates["status"] != models.QRCodeJobFailed {
+			return failed.Error
+		}
+		return tx.Model(&models.Recommendor{}).Where("id = ?", job.RecommendorID).
+			UpdateColumn("qr_status", models.QRStatusFailed).Error
+	})
+	if err != nil {
+		logger.Error("failed to record QR code job result", "error", err)
+	}
+}
+
+// enqueueQRCodes queues QR code generation for recommenders within db, replacing the jobs
+// they already have, and marks their codes as pending
+func enqueueQRCodes(db *gorm.DB, recommendorIDs ...uint) error {
+	now := time.Now()
+	jobs := make([]models.QRCodeJob, len(recommendorIDs))
+	for i, id := range recommendorIDs {
+		jobs[i] = models.QRCodeJob{RecommendorID: id, Status: models.QRCodeJobPending, RunAt: now}
+	}
+
+	if err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "recommendor_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"status":       models.QRCodeJobPending,
+			"attempts":     0,
+			"last_error":   "",
+			"run_at":       now,
+			"locked_until": nil,
+			"updated_at":   now,
+		}),
+	}).Create(&jobs).Error; err != nil {
+		return err
+	}
+	return db.Model(&models.Recommendor{}).Where("id IN ?", recommendorIDs).
+		UpdateColumn("qr_status", models.QRStatusPending).Error
+}
@@ -0,0 +1,234 @@
+package controllers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+	"tourism_recommendor/utils"
+)
+
+// createQueuedRecommendor creates a recommendor through the API, which queues its QR codes
+func createQueuedRecommendor(t *testing.T, h *testutil.Harness) models.Recommendor {
+	t.Helper()
+
+	w := h.Do("POST", "/api/v1/admin/recommendors", newRecommendorRequest("110101199001011234"), h.AdminToken())
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var recommendor models.Recommendor
+	h.Decode(w, &recommendor)
+	return recommendor
+}
+
+func TestQRCodeQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(h *testutil.Harness, recommendor models.Recommendor)
+		qrStatus  string
+		jobStatus string // "" when the job is done with
+		attempts  int
+	}{
+		{name: "generated", setup: func(*testutil.Harness, models.Recommendor) {}, qrStatus: models.QRStatusReady},
+		{
+			name: "retried later",
+			setup: func(h *testutil.Harness, _ models.Recommendor) {
+				h.WeChat.FailWithCode(testutil.WeChatWxaCodePath, 1, 45009)
+			},
+			qrStatus:  models.QRStatusPending,
+			jobStatus: models.QRCodeJobPending,
+			attempts:  1,
+		},
+		{
+			name: "gives up",
+			setup: func(h *testutil.Harness, recommendor models.Recommendor) {
+				h.WeChat.FailWithCode(testutil.WeChatWxaCodePath, 1, 45009)
+				h.DB.Model(&models.QRCodeJob{}).Where("recommendor_id = ?", recommendor.ID).
+					Update("attempts", controllers.QRCodeJobAttempts-1)
+			},
+			qrStatus:  models.QRStatusFailed,
+			jobStatus: models.QRCodeJobFailed,
+			attempts:  controllers.QRCodeJobAttempts,
+		},
+		{
+			name: "deleted recommendor",
+			setup: func(h *testutil.Harness, recommendor models.Recommendor) {
+				h.DB.Delete(&models.Recommendor{}, recommendor.ID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := createQueuedRecommendor(t, h)
+			tt.setup(h, recommendor)
+
+			processed, err := h.QRCodes.RunPending(context.Background())
+			if err != nil || processed != 1 {
+				t.Fatalf("RunPending = %d, %v; want 1 job", processed, err)
+			}
+
+			var job models.QRCodeJob
+			found := h.DB.Where("recommendor_id = ?", recommendor.ID).Limit(1).Find(&job).RowsAffected == 1
+			if job.Status != tt.jobStatus || job.Attempts != tt.attempts {
+				t.Fatalf("job = %+v (found %v), want status %q after %d attempts", job, found, tt.jobStatus, tt.attempts)
+			}
+			if tt.jobStatus != "" && job.LastError == "" {
+				t.Fatal("the failure was not recorded")
+			}
+			if tt.jobStatus == models.QRCodeJobPending {
+				// Backed off: not due again yet
+				if !job.RunAt.After(time.Now()) {
+					t.Fatalf("run_at = %v, want a later attempt", job.RunAt)
+				}
+				if processed, _ := h.QRCodes.RunPending(context.Background()); processed != 0 {
+					t.Fatalf("ran %d jobs before the retry was due", processed)
+				}
+			}
+			if tt.qrStatus == "" {
+				return
+			}
+
+			var stored models.Recommendor
+			if err := h.DB.First(&stored, recommendor.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.QRStatus != tt.qrStatus {
+				t.Fatalf("qr_status = %q, want %q", stored.QRStatus, tt.qrStatus)
+			}
+			if tt.qrStatus != models.QRStatusReady {
+				return
+			}
+			if !strings.Contains(stored.QRCodeWxapp, "&v=") {
+				t.Fatalf("QR code URL is not versioned: %q", stored.QRCodeWxapp)
+			}
+			if _, err := utils.LoadQRCode(recommendor.ID, utils.QRCodeWxapp); err != nil {
+				t.Fatalf("wxapp QR code image was not stored: %v", err)
+			}
+		})
+	}
+}
+
+func TestQRCodeQueueRegeneratesOnSettingsChange(t *testing.T) {
+	h := testutil.New(t)
+	legacy := h.CreateRecommendor()
+	ctx := context.Background()
+
+	// Codes drawn before the queue existed are queued once
+	if queued, err := h.QRCodes.EnqueueStale(ctx); err != nil || queued != 1 {
+		t.Fatalf("EnqueueStale = %d, %v; want 1", queued, err)
+	}
+	if processed, err := h.QRCodes.RunPending(ctx); err != nil || processed != 1 {
+		t.Fatalf("RunPending = %d, %v; want 1", processed, err)
+	}
+	if queued, _ := h.QRCodes.EnqueueStale(ctx); queued != 0 {
+		t.Fatalf("queued %d up-to-date recommendors", queued)
+	}
+
+	jobs := func() int64 {
+		var count int64
+		h.DB.Model(&models.QRCodeJob{}).Where("recommendor_id = ?", legacy.ID).Count(&count)
+		return count
+	}
+	update := func() {
+		bio := fmt.Sprintf("bio %d", time.Now().UnixNano())
+		w := h.Do("PUT", fmt.Sprintf("/api/v1/admin/recommendors/%d", legacy.ID), controllers.UpdateRecommendorRequest{Bio: &bio}, h.AdminToken())
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	// Editing fields the codes do not depend on leaves them alone
+	update()
+	if n := jobs(); n != 0 {
+		t.Fatalf("%d jobs queued by a bio edit", n)
+	}
+
+	// Changing the settings the codes are drawn with queues them again
+	h.Config.QRCode.Foreground = "#112233"
+	update()
+	if n := jobs(); n != 1 {
+		t.Fatalf("%d jobs queued after the settings changed, want 1", n)
+	}
+}
+
+func TestQRCodeJobs(t *testing.T) {
+	h := testutil.New(t)
+	failed := h.CreateRecommendor(func(r *models.Recommendor) { r.QRStatus = models.QRStatusFailed })
+	pending := h.CreateRecommendor()
+	failedJob := models.QRCodeJob{RecommendorID: failed.ID, Status: models.QRCodeJobFailed, Attempts: controllers.QRCodeJobAttempts, LastError: "WeChat error 45009", RunAt: time.Now()}
+	pendingJob := models.QRCodeJob{RecommendorID: pending.ID, Status: models.QRCodeJobPending, RunAt: time.Now()}
+	h.DB.Create(&failedJob)
+	h.DB.Create(&pendingJob)
+
+	t.Run("list", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  []uint
+		}{
+			{query: "", want: []uint{failedJob.ID, pendingJob.ID}},
+			{query: "?status=failed", want: []uint{failedJob.ID}},
+			{query: fmt.Sprintf("?recommendor_id=%d", pending.ID), want: []uint{pendingJob.ID}},
+		}
+		for _, tt := range tests {
+			w := h.Do("GET", "/api/v1/admin/recommendors/qrcode-jobs"+tt.query, nil, h.AdminToken())
+			if w.Code != http.StatusOK {
+				t.Fatalf("%q: status = %d: %s", tt.query, w.Code, w.Body.String())
+			}
+			var jobs []models.QRCodeJob
+			h.Decode(w, &jobs)
+			var ids []uint
+			for _, job := range jobs {
+				if job.Recommendor == nil || job.Recommendor.ID != job.RecommendorID {
+					t.Fatalf("%q: recommendor not included: %+v", tt.query, job)
+				}
+				ids = append(ids, job.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Fatalf("%q: got %v, want %v", tt.query, ids, tt.want)
+			}
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			path    string
+			token   string
+			code    int
+			errCode string
+		}{
+			{name: "failed job", path: fmt.Sprintf("%d", failedJob.ID), token: h.AdminToken(), code: http.StatusAccepted},
+			{name: "unknown", path: "999999", token: h.AdminToken(), code: http.StatusNotFound, errCode: "QRCODE_JOB_NOT_FOUND"},
+			{name: "invalid id", path: "abc", token: h.AdminToken(), code: http.StatusBadRequest, errCode: "INVALID_ID"},
+			{name: "unauthenticated", path: fmt.Sprintf("%d", failedJob.ID), code: http.StatusUnauthorized, errCode: "AUTH_HEADER_MISSING"},
+		}
+		for _, tt := range tests {
+			w := h.Do("POST", "/api/v1/admin/recommendors/qrcode-jobs/"+tt.path+"/retry", nil, tt.token)
+			if w.Code != tt.code {
+				t.Fatalf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("%s: error code = %q, want %q", tt.name, code, tt.errCode)
+			}
+		}
+
+		var job models.QRCodeJob
+		h.DB.First(&job, failedJob.ID)
+		if job.Status != models.QRCodeJobPending || job.Attempts != 0 || job.LastError != "" {
+			t.Fatalf("job was not queued again: %+v", job)
+		}
+		var stored models.Recommendor
+		h.DB.First(&stored, failed.ID)
+		if stored.QRStatus != models.QRStatusPending {
+			t.Fatalf("qr_status = %q, want pending", stored.QRStatus)
+		}
+	})
+}
@@ -1,20 +1,77 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"tourism_recommendor/config"
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/logging"
 	"tourism_recommendor/models"
+	"tourism_recommendor/response"
 	"tourism_recommendor/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // RecommendorController handles recommender-related requests
-type RecommendorController struct{}
+type RecommendorController struct {
+	Config  *config.Config
+	WeChat  utils.WeChatClient // draws Mini Program codes; nil falls back to plain QR codes
+	QRCodes *QRCodeQueue       // generates the stored QR codes in the background
+}
+
+// recommendorSortFields are the columns clients may sort recommendors by
+var recommendorSortFields = []string{"id", "name", "age", "rating", "valid_from", "valid_until", "created_at", "updated_at"}
+
+// recommendorFieldset lists the fields clients may select and the relationships they may include
+var recommendorFieldset = &utils.Fieldset{
+	Fields: []string{
+		"id", "name", "gender", "age", "id_number", "avatar", "bio", "valid_from", "valid_until", "phone", "email",
+		"province_code", "city_code", "district_code", "region_address", "status", "rating",
+		"qr_code_web", "qr_code_wxapp", "qr_status", "created_at", "updated_at",
+	},
+	Includes: []utils.Include{{Name: "destinations", Association: "Destinations"}},
+}
+
+// RecommendorFilters are the filters accepted by the recommendor lists
+var RecommendorFilters = utils.FilterSpec{
+	{Name: "name", Ops: []utils.FilterOp{utils.OpLike, utils.OpEq}, Description: "Name"},
+	{Name: "gender", Ops: []utils.FilterOp{utils.OpEq, utils.OpNe, utils.OpIn}, Values: i18n.EnumValues("gender"), Description: "Gender"},
+	{Name: "status", Ops: []utils.FilterOp{utils.OpEq, utils.OpNe, utils.OpIn}, Values: i18n.EnumValues("status"), Description: "Status (public lists default to active)"},
+	{Name: "province_code", Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Description: "Province code"},
+	{Name: "city_code", Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Description: "City code"},
+	{Name: "district_code", Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Description: "District code"},
+	{Name: "province", Column: "region_address", Ops: []utils.FilterOp{utils.OpLike}, Description: "Province name, matched against the region address"},
+	{Name: "city", Column: "region_address", Ops: []utils.FilterOp{utils.OpLike}, Description: "City name, matched against the region address"},
+	{Name: "district", Column: "region_address", Ops: []utils.FilterOp{utils.OpLike}, Description: "District name, matched against the region address"},
+	{Name: "age", Type: utils.FilterInt, Ops: []utils.FilterOp{utils.OpEq, utils.OpIn, utils.OpRange}, Description: "Age"},
+	{Name: "min_age", Column: "age", Type: utils.FilterInt, Ops: []utils.FilterOp{utils.OpGte}, Description: "Minimum age (same as age[gte])"},
+	{Name: "max_age", Column: "age", Type: utils.FilterInt, Ops: []utils.FilterOp{utils.OpLte}, Description: "Maximum age (same as age[lte])"},
+	{Name: "rating", Type: utils.FilterNumber, Ops: []utils.FilterOp{utils.OpEq, utils.OpRange}, Description: "Rating"},
+	{Name: "valid_until", Type: utils.FilterTime, Ops: []utils.FilterOp{utils.OpRange}, Description: "End of validity"},
+	{Name: "qr_status", Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Values: i18n.EnumValues("qr_status"), Description: "QR code generation status"},
+}
+
+// NewRecommendorController creates a new RecommendorController instance
+func NewRecommendorController(cfg *config.Config, wechat utils.WeChatClient) *RecommendorController {
+	rc := &RecommendorController{Config: cfg, WeChat: wechat}
+	rc.QRCodes = newQRCodeQueue(rc)
+	return rc
+}
 
 // CreateRecommendorRequest holds the request data for creating a recommender
 type CreateRecommendorRequest struct {
@@ -57,26 +114,31 @@ type UpdateRecommendorRequest struct {
 
 // CreateRecommendor creates a new recommender
 // @Summary Create a new recommender
-// @Description Create a new recommender with the provided data and generate QR codes
+// @Description Create a new recommender with the provided data. Its QR codes are generated in the background: qr_status
+// @Description is pending until they are stored, and the QR code URLs draw them on demand meanwhile.
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param recommendor body CreateRecommendorRequest true "Recommendor data"
 // @Success 201 {object} models.Recommendor
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors [post]
 // @Router /api/admin/recommendors [post]
 func (rc *RecommendorController) CreateRecommendor(c *gin.Context) {
 	var req CreateRecommendorRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		response.BadRequest(c, err)
 		return
 	}
 
 	// Check if ID number already exists
 	var existingRecommendor models.Recommendor
-	if err := config.DB.Where("id_number = ?", req.IDNumber).First(&existingRecommendor).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ID number already exists"})
+	if err := dbWithContext(c).Where("id_number = ?", req.IDNumber).First(&existingRecommendor).Error; err == nil {
+		response.Error(c, response.ErrIDNumberExists)
 		return
 	}
 
@@ -112,126 +174,103 @@ func (rc *RecommendorController) CreateRecommendor(c *gin.Context) {
 		Status:        status,
 	}
 
-	// Create recommendor
-	if err := config.DB.Create(&recommendor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recommendor: " + err.Error()})
-		return
-	}
-
-	// Generate QR codes
-	if err := rc.generateQRCodes(&recommendor); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR codes: " + err.Error()})
-		return
-	}
-
-	// Update with QR codes
-	if err := config.DB.Save(&recommendor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save QR codes: " + err.Error()})
+	// Create the recommendor and queue its QR codes; until they are stored, the
+	// unversioned URLs draw them on demand
+	err := dbWithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&recommendor).Error; err != nil {
+			return err
+		}
+		recommendor.QRCodeWeb = utils.QRCodeURL(rc.Config.QRCode.BaseURL, recommendor.ID, utils.QRCodeWeb, nil)
+		recommendor.QRCodeWxapp = utils.QRCodeURL(rc.Config.QRCode.BaseURL, recommendor.ID, utils.QRCodeWxapp, nil)
+		recommendor.QRStatus = models.QRStatusPending
+		if err := tx.Model(&recommendor).UpdateColumns(map[string]interface{}{
+			"qr_code_web":   recommendor.QRCodeWeb,
+			"qr_code_wxapp": recommendor.QRCodeWxapp,
+		}).Error; err != nil {
+			return err
+		}
+		return enqueueQRCodes(tx, recommendor.ID)
+	})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
+	rc.QRCodes.Notify()
 
-	c.JSON(http.StatusCreated, recommendor)
+	response.Created(c, recommendor)
 }
 
 // GetRecommendors retrieves a paginated list of recommendors with filtering and sorting
 // @Summary Get all recommendors
-// @Description Retrieve a paginated list of recommendors with optional filtering and sorting
+// @Description Retrieve a paginated list of active recommendors with optional filtering and sorting
 // @Tags recommendors
 // @Produce json
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Param sort_by query string false "Sort by field" default(id)
-// @Param sort_order query string false "Sort order (asc/desc)" default(asc)
-// @Param name query string false "Filter by name"
-// @Param gender query string false "Filter by gender"
-// @Param province_code query string false "Filter by province code"
-// @Param city_code query string false "Filter by city code"
-// @Param district_code query string false "Filter by district code"
-// @Param status query string false "Filter by status"
-// @Param min_age query int false "Minimum age"
-// @Param max_age query int false "Maximum age"
-// @Success 200 {object} utils.PaginationResponse
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma-separated sort fields, prefix - for descending (id, name, age, rating, valid_from, valid_until, created_at, updated_at)"
+// @Param sort_by query string false "Sort by field (superseded by sort)" default(id)
+// @Param sort_order query string false "Sort order (asc/desc, superseded by sort)" default(asc)
+// @Param pagination query string false "Pagination mode; cursor pages return next_cursor/prev_cursor and ignore page" Enums(page,cursor) default(page)
+// @Param cursor query string false "next_cursor or prev_cursor of a previous cursor page"
+// @Param with_total query bool false "Also count the total in cursor mode" default(false)
+// @Param fields query string false "Comma-separated fields to return (id, name, gender, age, id_number, avatar, bio, valid_from, valid_until, phone, email, province_code, city_code, district_code, region_address, status, rating, qr_code_web, qr_code_wxapp, qr_status, created_at, updated_at)"
+// @Param include query string false "Relationships to embed (destinations)" Enums(destinations)
+// @Filters RecommendorFilters
+// @Success 200 {object} utils.PaginationResponse{data=[]models.Recommendor}
+// @Failure 400 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/recommendors [get]
 // @Router /api/recommendors [get]
 func (rc *RecommendorController) GetRecommendors(c *gin.Context) {
-	// Parse pagination parameters
-	pr := utils.ParsePaginationRequest(
-		c.DefaultQuery("page", "1"),
-		c.DefaultQuery("page_size", "10"),
-		c.DefaultQuery("sort_by", "id"),
-		c.DefaultQuery("sort_order", "asc"),
-	)
-
-	// Build query
-	query := config.DB.Model(&models.Recommendor{})
-
-	// Apply filters
-	if name := c.Query("name"); name != "" {
-		query = utils.ApplyFilter(query, "name", name)
-	}
-
-	if gender := c.Query("gender"); gender != "" {
-		query = utils.ApplyEqualFilter(query, "gender", gender)
-	}
-
-	// Region filters - use province/city/district codes
-	if provinceCode := c.Query("province_code"); provinceCode != "" {
-		query = utils.ApplyEqualFilter(query, "province_code", provinceCode)
-	}
-
-	if cityCode := c.Query("city_code"); cityCode != "" {
-		query = utils.ApplyEqualFilter(query, "city_code", cityCode)
-	}
-
-	if districtCode := c.Query("district_code"); districtCode != "" {
-		query = utils.ApplyEqualFilter(query, "district_code", districtCode)
-	}
-
-	// Region filters by name (for WeChat Mini Program picker)
-	// Use fuzzy matching on region_address field
-	if province := c.Query("province"); province != "" {
-		query = query.Where("region_address LIKE ?", "%"+province+"%")
-	}
-
-	if city := c.Query("city"); city != "" {
-		query = query.Where("region_address LIKE ?", "%"+city+"%")
+	// Parse pagination and sorting parameters
+	pr, ok := paginationRequest(c, recommendorSortFields)
+	if !ok {
+		return
 	}
-
-	if district := c.Query("district"); district != "" {
-		query = query.Where("region_address LIKE ?", "%"+district+"%")
+	if !cursorRequest(c, pr) {
+		return
 	}
 
-	if status := c.Query("status"); status != "" {
-		query = utils.ApplyEqualFilter(query, "status", status)
+	// Parse the fields and include parameters
+	p, ok := projection(c, recommendorFieldset, "")
+	if !ok {
+		return
 	}
 
-	if minAge := c.Query("min_age"); minAge != "" {
-		if age, err := strconv.Atoi(minAge); err == nil {
-			query = query.Where("age >= ?", age)
-		}
-	}
+	// Build query
+	query := dbWithContext(c).Model(&models.Recommendor{})
 
-	if maxAge := c.Query("max_age"); maxAge != "" {
-		if age, err := strconv.Atoi(maxAge); err == nil {
-			query = query.Where("age <= ?", age)
-		}
+	// Apply filters
+	filters, ok := listFilters(c, RecommendorFilters)
+	if !ok {
+		return
 	}
+	query = utils.ApplyFilters(query, filters)
 
 	// Filter only active recommendors by default, unless status is explicitly set
-	if c.Query("status") == "" {
+	if !utils.HasFilter(filters, "status") {
 		query = query.Where("status = ?", "active")
 	}
 
+	// Select the requested fields and preload the included relationships
+	query = p.Apply(query, pr.Sort)
+
 	var recommendors []models.Recommendor
 
 	// Get paginated results
-	response, err := utils.Paginate(query, pr, &recommendors, &models.Recommendor{})
+	page, err := utils.Paginate(query, pr, &recommendors, &models.Recommendor{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve recommendors: " + err.Error()})
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	// Return translated content in the request locale
+	if err := localizeRecommendors(c, recommendors); err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	respondPage(c, p, page)
 }
 
 // GetRecommendorByID retrieves a single recommender by ID with destinations
@@ -240,58 +279,79 @@ func (rc *RecommendorController) GetRecommendors(c *gin.Context) {
 // @Tags recommendors
 // @Produce json
 // @Param id path int true "Recommendor ID"
+// @Param fields query string false "Comma-separated fields to return (id, name, gender, age, id_number, avatar, bio, valid_from, valid_until, phone, email, province_code, city_code, district_code, region_address, status, rating, qr_code_web, qr_code_wxapp, qr_status, created_at, updated_at)"
+// @Param include query string false "Relationships to embed (destinations)" Enums(destinations)
 // @Success 200 {object} models.Recommendor
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/recommendors/{id} [get]
 // @Router /api/recommendors/{id} [get]
+// @Router /api/v1/admin/recommendors/{id} [get]
+// @Router /api/admin/recommendors/{id} [get]
 func (rc *RecommendorController) GetRecommendorByID(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recommendor ID"})
+		response.Error(c, response.ErrInvalidID)
+		return
+	}
+
+	// Parse the fields and include parameters
+	p, ok := projection(c, recommendorFieldset, "destinations")
+	if !ok {
 		return
 	}
 
 	var recommendor models.Recommendor
-	if err := config.DB.
-		Preload("Destinations").
-		First(&recommendor, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recommendor not found"})
+	if err := p.Apply(dbWithContext(c), nil).First(&recommendor, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
+		return
+	}
+
+	// Return translated content in the request locale
+	localized := []models.Recommendor{recommendor}
+	if err := localizeRecommendors(c, localized); err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, recommendor)
+	respondRecord(c, p, localized[0])
 }
 
 // UpdateRecommendor updates an existing recommender
 // @Summary Update a recommender
-// @Description Update an existing recommender with the provided data and regenerate QR codes if needed
+// @Description Update an existing recommender with the provided data. QR codes are queued for regeneration only when
+// @Description the QR code settings changed since they were drawn.
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Recommendor ID"
 // @Param recommendor body UpdateRecommendorRequest true "Recommendor data"
 // @Success 200 {object} models.Recommendor
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/{id} [put]
 // @Router /api/admin/recommendors/{id} [put]
 func (rc *RecommendorController) UpdateRecommendor(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recommendor ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	var req UpdateRecommendorRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		response.BadRequest(c, err)
 		return
 	}
 
 	var recommendor models.Recommendor
-	if err := config.DB.First(&recommendor, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recommendor not found"})
+	if err := dbWithContext(c).First(&recommendor, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
 		return
 	}
 
@@ -308,8 +368,8 @@ func (rc *RecommendorController) UpdateRecommendor(c *gin.Context) {
 	if req.IDNumber != nil {
 		// Check if ID number is already used by another recommendor
 		var existingRecommendor models.Recommendor
-		if err := config.DB.Where("id_number = ? AND id != ?", *req.IDNumber, id).First(&existingRecommendor).Error; err == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "ID number already exists"})
+		if err := dbWithContext(c).Where("id_number = ? AND id != ?", *req.IDNumber, id).First(&existingRecommendor).Error; err == nil {
+			response.Error(c, response.ErrIDNumberExists)
 			return
 		}
 		recommendor.IDNumber = *req.IDNumber
@@ -351,19 +411,28 @@ func (rc *RecommendorController) UpdateRecommendor(c *gin.Context) {
 		recommendor.Rating = *req.Rating
 	}
 
-	// Regenerate QR codes
-	if err := rc.generateQRCodes(&recommendor); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate QR codes: " + err.Error()})
+	// The QR codes only depend on the ID and the QR code settings, so they are queued again
+	// only when the settings have changed since they were drawn
+	stale := recommendor.QRInputs != rc.qrCodeInputs()
+	err = dbWithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&recommendor).Error; err != nil {
+			return err
+		}
+		if !stale {
+			return nil
+		}
+		recommendor.QRStatus = models.QRStatusPending
+		return enqueueQRCodes(tx, recommendor.ID)
+	})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
-
-	// Save updates
-	if err := config.DB.Save(&recommendor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recommendor: " + err.Error()})
-		return
+	if stale {
+		rc.QRCodes.Notify()
 	}
 
-	c.JSON(http.StatusOK, recommendor)
+	response.OK(c, recommendor)
 }
 
 // DeleteRecommendor deletes a recommender (soft delete)
@@ -371,32 +440,42 @@ func (rc *RecommendorController) UpdateRecommendor(c *gin.Context) {
 // @Description Soft delete a recommendor by its ID
 // @Tags admin
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Recommendor ID"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} response.MessageBody
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/{id} [delete]
 // @Router /api/admin/recommendors/{id} [delete]
 func (rc *RecommendorController) DeleteRecommendor(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recommendor ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	var recommendor models.Recommendor
-	if err := config.DB.First(&recommendor, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recommendor not found"})
+	if err := dbWithContext(c).First(&recommendor, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
 		return
 	}
 
-	// Soft delete (GORM will set deleted_at)
-	if err := config.DB.Delete(&recommendor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recommendor: " + err.Error()})
+	// Soft delete (GORM will set deleted_at) together with the translations of the record
+	err = dbWithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&recommendor).Error; err != nil {
+			return err
+		}
+		return deleteTranslations(tx, models.TranslationEntityRecommendor, recommendor.ID)
+	})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Recommendor deleted successfully"})
+	response.Message(c, "message.recommendor.deleted", nil)
 }
 
 // RegenerateQRCodes regenerates QR codes for a recommender
@@ -404,149 +483,533 @@ func (rc *RecommendorController) DeleteRecommendor(c *gin.Context) {
 // @Description Regenerate QR codes for a specific recommender
 // @Tags admin
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Recommendor ID"
 // @Success 200 {object} models.Recommendor
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/{id}/qrcodes [post]
 // @Router /api/admin/recommendors/{id}/qrcodes [post]
 func (rc *RecommendorController) RegenerateQRCodes(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recommendor ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	var recommendor models.Recommendor
-	if err := config.DB.First(&recommendor, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recommendor not found"})
+	if err := dbWithContext(c).First(&recommendor, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
 		return
 	}
 
 	// Regenerate QR codes
-	if err := rc.generateQRCodes(&recommendor); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate QR codes: " + err.Error()})
+	if err := rc.generateQRCodes(c.Request.Context(), &recommendor); err != nil {
+		response.Error(c, response.ErrQRCodeGenerate.WithCause(err))
 		return
 	}
 
-	// Save updates
-	if err := config.DB.Save(&recommendor).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save QR codes: " + err.Error()})
+	// Save updates; a queued or failed job for the recommendor is done with
+	err = dbWithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&recommendor).Error; err != nil {
+			return err
+		}
+		return tx.Where("recommendor_id = ?", recommendor.ID).Delete(&models.QRCodeJob{}).Error
+	})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, recommendor)
+	response.OK(c, recommendor)
 }
 
-// GetAdminRecommendors retrieves recommendors for admin (includes all statuses)
-// @Summary Get all recommendors (admin)
-// @Description Retrieve all recommendors including inactive ones for admin management
+// GetBadge renders the printable ID badge of a recommender
+// @Summary Get ID badge
+// @Description Render an A6 badge with the avatar, name, region, validity period and both QR codes of a recommender as a PDF.
+// @Description Labels follow the request locale; text uses the embedded CJK font unless BADGE_FONT_PATH names another TrueType font.
 // @Tags admin
-// @Produce json
-// @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Page size" default(10)
-// @Param sort_by query string false "Sort by field" default(id)
-// @Param sort_order query string false "Sort order (asc/desc)" default(asc)
-// @Param name query string false "Filter by name"
-// @Param gender query string false "Filter by gender"
-// @Param province_code query string false "Filter by province code"
-// @Param city_code query string false "Filter by city code"
-// @Param district_code query string false "Filter by district code"
-// @Param status query string false "Filter by status"
-// @Param min_age query int false "Minimum age"
-// @Param max_age query int false "Maximum age"
-// @Success 200 {object} utils.PaginationResponse
-// @Failure 500 {object} map[string]string
-// @Router /api/admin/recommendors [get]
-func (rc *RecommendorController) GetAdminRecommendors(c *gin.Context) {
-	// Parse pagination parameters
-	pr := utils.ParsePaginationRequest(
-		c.DefaultQuery("page", "1"),
-		c.DefaultQuery("page_size", "10"),
-		c.DefaultQuery("sort_by", "id"),
-		c.DefaultQuery("sort_order", "asc"),
-	)
+// @Produce pdf
+// @Security BearerAuth
+// @Param id path int true "Recommendor ID"
+// @Success 200 {file} file "PDF document"
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/{id}/badge.pdf [get]
+// @x-envelope false
+func (rc *RecommendorController) GetBadge(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, response.ErrInvalidID)
+		return
+	}
 
-	// Build query with preloads
-	query := config.DB.Model(&models.Recommendor{})
+	var recommendor models.Recommendor
+	if err := dbWithContext(c).First(&recommendor, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
+		return
+	}
 
-	// Apply filters (same as public but without default status filter)
-	if name := c.Query("name"); name != "" {
-		query = utils.ApplyFilter(query, "name", name)
+	qrConfig, err := rc.qrCodeConfig()
+	if err != nil {
+		response.Error(c, response.ErrQRCodeGenerate.WithCause(err))
+		return
+	}
+	ctx := c.Request.Context()
+	webQR, wxappQR, err := badgeQRCodes(newRecommendorQRCodes(ctx, qrConfig, recommendor.ID))
+	if err != nil {
+		response.Error(c, response.ErrQRCodeGenerate.WithCause(err))
+		return
+	}
+	pdf, err := rc.renderBadge(ctx, qrConfig, &recommendor, webQR, wxappQR, response.Locale(c))
+	if err != nil {
+		response.Error(c, response.ErrQRCodeGenerate.WithCause(err))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="badge-%d.pdf"`, recommendor.ID))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// badgeQRCodeSize is the size of the QR codes on badges. Print resolution: 34 mm at 512 px is about 380 dpi.
+const badgeQRCodeSize = 512
+
+// badgeQRCodes draws the web and Mini Program codes printed on the badge of a recommender
+func badgeQRCodes(codes *recommendorQRCodes) (webQR, wxappQR []byte, err error) {
+	opts := codes.qrConfig.Options
+	opts.Size = badgeQRCodeSize
+	if webQR, err = codes.draw(utils.QRCodeWeb, opts); err != nil {
+		return nil, nil, err
+	}
+	if wxappQR, err = codes.draw(utils.QRCodeWxapp, opts); err != nil {
+		return nil, nil, err
+	}
+	return webQR, wxappQR, nil
+}
+
+// renderBadge renders the ID badge of a recommender, with the QR codes drawn by badgeQRCodes,
+// with labels in locale
+func (rc *RecommendorController) renderBadge(ctx context.Context, qrConfig utils.QRCodeConfig, recommendor *models.Recommendor, webQR, wxappQR []byte, locale string) ([]byte, error) {
+	return utils.RenderBadgePDF(utils.Badge{
+		Title:      i18n.T(locale, "badge.title"),
+		Name:       recommendor.Name,
+		Region:     recommendor.RegionAddress,
+		Validity:   i18n.T(locale, "badge.validity", recommendor.ValidFrom.Format("2006-01-02"), recommendor.ValidUntil.Format("2006-01-02")),
+		Avatar:     badgeAvatar(ctx, recommendor.Avatar),
+		WebQR:      webQR,
+		WebLabel:   i18n.T(locale, "badge.web_qrcode"),
+		WxappQR:    wxappQR,
+		WxappLabel: i18n.T(locale, "badge.wxapp_qrcode"),
+		Accent:     qrConfig.Options.Foreground,
+		FontPath:   rc.Config.QRCode.BadgeFont,
+	})
+}
+
+// badgeAvatar loads an uploaded avatar for the badge. Avatars hosted elsewhere are not
+// fetched, and unreadable ones are left out, so the badge falls back to a placeholder.
+func badgeAvatar(ctx context.Context, avatar string) image.Image {
+	if avatar == "" {
+		return nil
+	}
+	if !strings.Contains(avatar, "/") {
+		avatar = "/uploads/avatars/" + avatar
+	}
+	if !strings.HasPrefix(avatar, "/uploads/") {
+		return nil
+	}
+
+	data, err := utils.ReadUpload(avatar)
+	if err == nil {
+		var img image.Image
+		if img, _, err = image.Decode(bytes.NewReader(data)); err == nil {
+			return img
+		}
 	}
+	logging.FromContext(ctx).Warn("failed to load avatar for badge", "avatar", avatar, "error", err)
+	return nil
+}
 
-	if gender := c.Query("gender"); gender != "" {
-		query = utils.ApplyEqualFilter(query, "gender", gender)
+// GetQRCode serves a QR code of a recommender as a PNG image
+// @Summary Get QR code image
+// @Description Serve the web or Mini Program QR code of a recommender. Images with the configured branding and the default size
+// @Description are generated once and stored; other sizes and styles are rendered on request and cached. Mini Program codes
+// @Description from WeChat only honour the size and are scaled from the stored code. Responses carry an ETag and are cached
+// @Description for a year when the URL has a matching v parameter.
+// @Tags recommendors
+// @Produce png
+// @Param id path int true "Recommendor ID"
+// @Param type query string false "QR code type" Enums(web,wxapp) default(web)
+// @Param size query int false "Image size in pixels" minimum(64) maximum(1024) default(256)
+// @Param level query string false "Error correction level, H when a logo is drawn" Enums(L,M,Q,H)
+// @Param fg query string false "Module color such as 1a1a1a"
+// @Param bg query string false "Background color such as ffffff"
+// @Param logo query bool false "Draw the configured center logo" default(true)
+// @Param v query string false "Image version, as found in qr_code_web and qr_code_wxapp"
+// @Success 200 {file} file "PNG image"
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/recommendors/{id}/qrcode.png [get]
+// @x-envelope false
+func (rc *RecommendorController) GetQRCode(c *gin.Context) {
+	rc.serveQRCode(c, utils.QRFormatPNG)
+}
+
+// GetQRCodeSVG serves a QR code of a recommender as an SVG image
+// @Summary Get QR code SVG
+// @Description Serve the web or Mini Program QR code of a recommender as a scalable SVG for print. Takes the same options as the
+// @Description PNG endpoint; Mini Program codes from WeChat are scaled from the stored code and embedded as PNG.
+// @Tags recommendors
+// @Produce svg
+// @Param id path int true "Recommendor ID"
+// @Param type query string false "QR code type" Enums(web,wxapp) default(web)
+// @Param size query int false "Image size in pixels" minimum(64) maximum(1024) default(256)
+// @Param level query string false "Error correction level, H when a logo is drawn" Enums(L,M,Q,H)
+// @Param fg query string false "Module color such as 1a1a1a"
+// @Param bg query string false "Background color such as ffffff"
+// @Param logo query bool false "Draw the configured center logo" default(true)
+// @Param v query string false "Image version"
+// @Success 200 {file} file "SVG image"
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/recommendors/{id}/qrcode.svg [get]
+// @x-envelope false
+func (rc *RecommendorController) GetQRCodeSVG(c *gin.Context) {
+	rc.serveQRCode(c, utils.QRFormatSVG)
+}
+
+// serveQRCode answers a QR code image request in format
+func (rc *RecommendorController) serveQRCode(c *gin.Context, format string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, response.ErrInvalidID)
+		return
 	}
 
-	// Region filters - use province/city/district codes
-	if provinceCode := c.Query("province_code"); provinceCode != "" {
-		query = utils.ApplyEqualFilter(query, "province_code", provinceCode)
+	kind := c.DefaultQuery("type", utils.QRCodeWeb)
+	if !slices.Contains(utils.QRCodeTypes, kind) {
+		response.Error(c, response.ErrInvalidQRCode.WithDetail("type", kind).WithDetail("allowed", utils.QRCodeTypes))
+		return
 	}
 
-	if cityCode := c.Query("city_code"); cityCode != "" {
-		query = utils.ApplyEqualFilter(query, "city_code", cityCode)
+	qrConfig, err := rc.qrCodeConfig()
+	if err != nil {
+		response.Error(c, response.ErrQRCodeGenerate.WithCause(err))
+		return
+	}
+	opts, ok := qrCodeOptions(c, qrConfig.Options)
+	if !ok {
+		return
 	}
+	opts.Format = format
 
-	if districtCode := c.Query("district_code"); districtCode != "" {
-		query = utils.ApplyEqualFilter(query, "district_code", districtCode)
+	var recommendor models.Recommendor
+	if err := dbWithContext(c).Select("id").First(&recommendor, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
+		return
 	}
 
-	if status := c.Query("status"); status != "" {
-		query = utils.ApplyEqualFilter(query, "status", status)
+	// Other sizes and styles are rendered from the stored code, or locally, and cached, so
+	// anonymous requests reach the WeChat API at most once per recommender
+	ctx := c.Request.Context()
+	image, err := storedQRCode(ctx, qrConfig, recommendor.ID, kind)
+	if err == nil && (format != utils.QRFormatPNG || slices.ContainsFunc(qrCodeStyleParams, func(param string) bool { return c.Query(param) != "" })) {
+		stored := image
+		key := utils.QRCodeVariantKey(recommendor.ID, kind, opts, utils.QRCodeETag(stored))
+		image, err = utils.CachedQRCodeVariant(key, func() ([]byte, error) {
+			if kind != utils.QRCodeWxapp || qrConfig.WeChat == nil {
+				return utils.GenerateRecommendorQR(ctx, qrConfig, recommendor.ID, kind, opts)
+			}
+			return scaleWxappQRCode(stored, opts)
+		})
+	}
+	if err != nil {
+		response.Error(c, response.ErrQRCodeGenerate.WithCause(err))
+		return
 	}
 
-	if minAge := c.Query("min_age"); minAge != "" {
-		if age, err := strconv.Atoi(minAge); err == nil {
-			query = query.Where("age >= ?", age)
-		}
+	// A versioned URL always names the same image; unversioned ones are revalidated with the ETag
+	etag := utils.QRCodeETag(image)
+	if c.Query("v") == etag {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "public, max-age=3600")
+	}
+	c.Header("ETag", `"`+etag+`"`)
+	if match := c.GetHeader("If-None-Match"); match == `"`+etag+`"` || match == "*" {
+		c.Status(http.StatusNotModified)
+		return
 	}
 
-	if maxAge := c.Query("max_age"); maxAge != "" {
-		if age, err := strconv.Atoi(maxAge); err == nil {
-			query = query.Where("age <= ?", age)
+	contentType := http.DetectContentType(image)
+	if format == utils.QRFormatSVG {
+		contentType = "image/svg+xml"
+	}
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// qrCodeStyleParams are the query parameters that make a QR code differ from the stored one
+var qrCodeStyleParams = []string{"size", "level", "fg", "bg", "logo"}
+
+// qrCodeOptions applies the size and style query parameters to the configured options
+func qrCodeOptions(c *gin.Context, opts utils.QRCodeOptions) (utils.QRCodeOptions, bool) {
+	if value := c.Query("size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < utils.MinQRCodeSize || size > utils.MaxQRCodeSize {
+			response.Error(c, response.ErrInvalidQRCode.WithDetail("size", value).
+				WithDetail("min", utils.MinQRCodeSize).WithDetail("max", utils.MaxQRCodeSize))
+			return opts, false
 		}
+		opts.Size = size
 	}
+	if value := c.Query("level"); value != "" {
+		level, err := utils.ParseQRCodeLevel(value)
+		if err != nil {
+			response.Error(c, response.ErrInvalidQRCode.WithDetail("level", value).WithDetail("allowed", utils.QRCodeLevels))
+			return opts, false
+		}
+		opts.Level = level
+	}
+	colors := []struct {
+		param  string
+		target *color.Color
+	}{{"fg", &opts.Foreground}, {"bg", &opts.Background}}
+	for _, col := range colors {
+		value := c.Query(col.param)
+		if value == "" {
+			continue
+		}
+		parsed, err := utils.ParseHexColor(value)
+		if err != nil {
+			response.Error(c, response.ErrInvalidQRCode.WithDetail(col.param, value).WithCause(err))
+			return opts, false
+		}
+		*col.target = parsed
+	}
+	if value := c.Query("logo"); value != "" {
+		draw, err := strconv.ParseBool(value)
+		if err != nil {
+			response.Error(c, response.ErrInvalidQRCode.WithDetail("logo", value))
+			return opts, false
+		}
+		if !draw {
+			opts.Logo = nil
+		}
+	}
+	return opts, true
+}
 
-	var recommendors []models.Recommendor
+// storedQRCode returns the stored QR code, generating and storing it when missing
+func storedQRCode(ctx context.Context, qrConfig utils.QRCodeConfig, recommendorID uint, kind string) ([]byte, error) {
+	image, err := utils.LoadQRCode(recommendorID, kind)
+	if err == nil {
+		return image, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
 
-	// Get paginated results
-	response, err := utils.Paginate(query, pr, &recommendors, &models.Recommendor{})
+	image, err = utils.GenerateRecommendorQR(ctx, qrConfig, recommendorID, kind, qrConfig.Options)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve recommendors: " + err.Error()})
-		return
+		return nil, err
+	}
+	stored, err := utils.StoreQRCode(recommendorID, kind, image)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to store QR code", "recommendor_id", recommendorID, "type", kind, "error", err)
+		return image, nil
+	}
+	return stored, nil
+}
+
+// scaleWxappQRCode scales a Mini Program code to the size and format of opts. Codes drawn by
+// WeChat only honour the size, so the stored one is scaled rather than drawn again.
+func scaleWxappQRCode(code []byte, opts utils.QRCodeOptions) ([]byte, error) {
+	resized, err := utils.ResizeQRCode(code, opts.Size)
+	if err != nil || opts.Format != utils.QRFormatSVG {
+		return resized, err
 	}
+	return utils.EmbedImageSVG(resized, opts.Size), nil
+}
+
+// recommendorQRCodes draws the QR codes of one recommender in the sizes and formats a request
+// needs, drawing each at most once and loading the stored Mini Program code at most once
+type recommendorQRCodes struct {
+	ctx           context.Context
+	qrConfig      utils.QRCodeConfig
+	recommendorID uint
+	storedWxapp   []byte
+	drawn         map[string][]byte
+}
 
-	c.JSON(http.StatusOK, response)
+// newRecommendorQRCodes returns a drawer for the QR codes of a recommender
+func newRecommendorQRCodes(ctx context.Context, qrConfig utils.QRCodeConfig, recommendorID uint) *recommendorQRCodes {
+	return &recommendorQRCodes{ctx: ctx, qrConfig: qrConfig, recommendorID: recommendorID, drawn: map[string][]byte{}}
 }
 
-// generateQRCodes generates both web and mini program QR codes for a recommender
-func (rc *RecommendorController) generateQRCodes(recommendor *models.Recommendor) error {
-	qrConfig := utils.QRCodeConfig{
-		BaseURL:      os.Getenv("BASE_URL"),
-		MinAppID:     os.Getenv("WX_APP_ID"),
-		MinAppPath:   os.Getenv("WX_APP_PATH"),
-		MinAppSecret: os.Getenv("WX_APP_SECRET"),
+// draw returns the kind QR code drawn with opts; the style of opts is the same for all calls
+func (q *recommendorQRCodes) draw(kind string, opts utils.QRCodeOptions) ([]byte, error) {
+	key := fmt.Sprintf("%s:%s:%d", kind, opts.Format, opts.Size)
+	if image, ok := q.drawn[key]; ok {
+		return image, nil
+	}
+
+	var image []byte
+	var err error
+	if kind != utils.QRCodeWxapp || q.qrConfig.WeChat == nil {
+		image, err = utils.GenerateRecommendorQR(q.ctx, q.qrConfig, q.recommendorID, kind, opts)
+	} else {
+		if q.storedWxapp == nil {
+			if q.storedWxapp, err = storedQRCode(q.ctx, q.qrConfig, q.recommendorID, kind); err != nil {
+				return nil, err
+			}
+		}
+		image, err = scaleWxappQRCode(q.storedWxapp, opts)
+	}
+	if err != nil {
+		return nil, err
 	}
+	q.drawn[key] = image
+	return image, nil
+}
 
-	// Set defaults if not provided
-	if qrConfig.BaseURL == "" {
-		qrConfig.BaseURL = "http://localhost:8080"
+// GetAdminRecommendors retrieves recommendors for admin (includes all statuses)
+// @Summary Get all recommendors (admin)
+// @Description Retrieve all recommendors including inactive ones for admin management
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "Comma-separated sort fields, prefix - for descending (id, name, age, rating, valid_from, valid_until, created_at, updated_at)"
+// @Param sort_by query string false "Sort by field (superseded by sort)" default(id)
+// @Param sort_order query string false "Sort order (asc/desc, superseded by sort)" default(asc)
+// @Param fields query string false "Comma-separated fields to return (id, name, gender, age, id_number, avatar, bio, valid_from, valid_until, phone, email, province_code, city_code, district_code, region_address, status, rating, qr_code_web, qr_code_wxapp, qr_status, created_at, updated_at)"
+// @Param include query string false "Relationships to embed (destinations)" Enums(destinations)
+// @Filters RecommendorFilters
+// @Success 200 {object} utils.PaginationResponse{data=[]models.Recommendor}
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors [get]
+// @Router /api/admin/recommendors [get]
+func (rc *RecommendorController) GetAdminRecommendors(c *gin.Context) {
+	// Parse pagination and sorting parameters
+	pr, ok := paginationRequest(c, recommendorSortFields)
+	if !ok {
+		return
 	}
-	if qrConfig.MinAppID == "" {
-		qrConfig.MinAppID = "your_miniprogram_appid"
+
+	// Parse the fields and include parameters
+	p, ok := projection(c, recommendorFieldset, "")
+	if !ok {
+		return
 	}
-	if qrConfig.MinAppPath == "" {
-		qrConfig.MinAppPath = "/"
+
+	// Build query with preloads
+	query := dbWithContext(c).Model(&models.Recommendor{})
+
+	// Apply filters (same as public but without default status filter)
+	filters, ok := listFilters(c, RecommendorFilters)
+	if !ok {
+		return
 	}
+	query = utils.ApplyFilters(query, filters)
+
+	// Select the requested fields and preload the included relationships
+	query = p.Apply(query, pr.Sort)
 
-	webQR, wxappQR, err := utils.GenerateRecommendorQRs(qrConfig, recommendor.ID)
+	var recommendors []models.Recommendor
+
+	// Get paginated results
+	page, err := utils.Paginate(query, pr, &recommendors, &models.Recommendor{})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	respondPage(c, p, page)
+}
+
+// generateQRCodes generates both web and mini program QR codes for a recommender,
+// stores the images and points the QR code fields at their URLs
+func (rc *RecommendorController) generateQRCodes(ctx context.Context, recommendor *models.Recommendor) error {
+	inputs := rc.qrCodeInputs()
+	qrConfig, err := rc.qrCodeConfig()
+	if err != nil {
+		return err
+	}
+	webQR, wxappQR, err := utils.GenerateRecommendorQRs(ctx, qrConfig, recommendor.ID)
 	if err != nil {
 		return err
 	}
 
-	recommendor.QRCodeWeb = webQR
-	recommendor.QRCodeWxapp = wxappQR
+	if webQR, err = utils.StoreQRCode(recommendor.ID, utils.QRCodeWeb, webQR); err != nil {
+		return err
+	}
+	if wxappQR, err = utils.StoreQRCode(recommendor.ID, utils.QRCodeWxapp, wxappQR); err != nil {
+		return err
+	}
+
+	recommendor.QRCodeWeb = utils.QRCodeURL(qrConfig.BaseURL, recommendor.ID, utils.QRCodeWeb, webQR)
+	recommendor.QRCodeWxapp = utils.QRCodeURL(qrConfig.BaseURL, recommendor.ID, utils.QRCodeWxapp, wxappQR)
+	recommendor.QRStatus = models.QRStatusReady
+	recommendor.QRInputs = inputs
 
 	return nil
 }
+
+// qrCodeContent names what the QR codes encode; changing it queues every code again
+const qrCodeContent = "short-links,wxapp-scenes"
+
+// qrCodeInputs fingerprints the settings the stored QR codes are drawn with. The codes of a
+// recommender whose fingerprint differs are out of date.
+func (rc *RecommendorController) qrCodeInputs() string {
+	brand := rc.Config.QRCode
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		qrCodeContent, brand.BaseURL, brand.WxAppID, brand.WxAppPath, strconv.FormatBool(rc.WeChat != nil),
+		brand.Level, brand.Foreground, brand.Background, brand.LogoPath,
+	}, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// qrCodeConfig returns the QR code generation settings, with the configured branding as default options
+func (rc *RecommendorController) qrCodeConfig() (utils.QRCodeConfig, error) {
+	brand := rc.Config.QRCode
+	opts := utils.DefaultQRCodeOptions()
+
+	var err error
+	if opts.Level, err = utils.ParseQRCodeLevel(brand.Level); err != nil {
+		return utils.QRCodeConfig{}, err
+	}
+	if opts.Foreground, err = utils.ParseHexColor(brand.Foreground); err != nil {
+		return utils.QRCodeConfig{}, err
+	}
+	if opts.Background, err = utils.ParseHexColor(brand.Background); err != nil {
+		return utils.QRCodeConfig{}, err
+	}
+	if brand.LogoPath != "" {
+		if opts.Logo, err = utils.LoadQRCodeLogo(brand.LogoPath); err != nil {
+			return utils.QRCodeConfig{}, err
+		}
+	}
+
+	return utils.QRCodeConfig{
+		BaseURL:     brand.BaseURL,
+		MinAppID:    brand.WxAppID,
+		MinAppPath:  brand.WxAppPath,
+		WeChat:      rc.WeChat,
+		Options:     opts,
+		ShortLinks:  shortLinkCode,
+		WxappScenes: recommendorSceneToken,
+	}, nil
+}
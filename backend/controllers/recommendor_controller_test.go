@@ -0,0 +1,594 @@
+package controllers_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+	"tourism_recommendor/utils"
+)
+
+func newRecommendorRequest(idNumber string) controllers.CreateRecommendorRequest {
+	return controllers.CreateRecommendorRequest{
+		Name:         "张三",
+		Gender:       "male",
+		Age:          30,
+		IDNumber:     idNumber,
+		Bio:          "资深导游",
+		ValidFrom:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ValidUntil:   time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		ProvinceCode: "110000",
+		CityCode:     "110100",
+		DistrictCode: "110101",
+	}
+}
+
+func TestCreateRecommendor(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(req *controllers.CreateRecommendorRequest)
+		code    int
+		errCode string
+	}{
+		{name: "valid recommendor", code: http.StatusCreated},
+		{name: "duplicate id number", modify: func(req *controllers.CreateRecommendorRequest) { req.IDNumber = "existing" }, code: http.StatusBadRequest, errCode: "ID_NUMBER_EXISTS"},
+		{name: "invalid gender", modify: func(req *controllers.CreateRecommendorRequest) { req.Gender = "unknown" }, code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "under age", modify: func(req *controllers.CreateRecommendorRequest) { req.Age = 16 }, code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "missing region codes", modify: func(req *controllers.CreateRecommendorRequest) { req.DistrictCode = "" }, code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			h.CreateRecommendor(func(r *models.Recommendor) { r.IDNumber = "existing" })
+
+			req := newRecommendorRequest("110101199001011234")
+			if tt.modify != nil {
+				tt.modify(&req)
+			}
+			w := h.Do("POST", "/api/v1/admin/recommendors", req, h.AdminToken())
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code != http.StatusCreated {
+				return
+			}
+
+			var recommendor models.Recommendor
+			h.Decode(w, &recommendor)
+			if recommendor.Status != "active" || recommendor.RegionAddress != "110000/110100/110101" {
+				t.Fatalf("defaults not applied: %+v", recommendor)
+			}
+			// Absolute, so the admin frontend and the Mini Program can load them from their own origins
+			imageURL := h.Config.QRCode.BaseURL + "/api/v1/recommendors/"
+			if !strings.HasPrefix(recommendor.QRCodeWeb, imageURL) || !strings.HasPrefix(recommendor.QRCodeWxapp, imageURL) {
+				t.Fatalf("QR codes were not stored as image URLs: %q, %q", recommendor.QRCodeWeb, recommendor.QRCodeWxapp)
+			}
+
+			// The codes are generated by the queue, not by the request
+			if recommendor.QRStatus != models.QRStatusPending {
+				t.Fatalf("qr_status = %q, want pending", recommendor.QRStatus)
+			}
+			if calls := h.WeChat.Calls(testutil.WeChatWxaCodePath); calls != 0 {
+				t.Fatalf("WeChat was called %d times while creating", calls)
+			}
+			var jobs int64
+			h.DB.Model(&models.QRCodeJob{}).Where("recommendor_id = ?", recommendor.ID).Count(&jobs)
+			if jobs != 1 {
+				t.Fatalf("%d QR code jobs queued, want 1", jobs)
+			}
+		})
+	}
+}
+
+func TestGetRecommendors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "active only by default", want: []string{"alice", "bob", "carol"}},
+		{name: "gender", query: "?gender=male", want: []string{"bob"}},
+		{name: "explicit status", query: "?status=inactive", want: []string{"dave"}},
+		{name: "age range", query: "?min_age=30&max_age=40", want: []string{"bob", "carol"}},
+		{name: "district code", query: "?district_code=310101", want: []string{"carol"}},
+		{name: "name ignores case", query: "?name=ALI", want: []string{"alice"}},
+		{name: "sorted by age", query: "?sort_by=age&sort_order=desc", want: []string{"carol", "bob", "alice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			h.CreateRecommendor(func(r *models.Recommendor) { r.Name, r.Age = "alice", 25 })
+			h.CreateRecommendor(func(r *models.Recommendor) { r.Name, r.Age, r.Gender = "bob", 32, models.GenderMale })
+			h.CreateRecommendor(func(r *models.Recommendor) { r.Name, r.Age, r.DistrictCode = "carol", 40, "310101" })
+			h.CreateRecommendor(func(r *models.Recommendor) { r.Name, r.Status = "dave", "inactive" })
+
+			w := h.Do("GET", "/api/v1/recommendors"+tt.query, nil, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			var recommendors []models.Recommendor
+			h.Decode(w, &recommendors)
+			if got := recommendorNames(recommendors); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAdminRecommendors(t *testing.T) {
+	h := testutil.New(t)
+	h.CreateRecommendor(func(r *models.Recommendor) { r.Name = "alice" })
+	h.CreateRecommendor(func(r *models.Recommendor) { r.Name, r.Status = "dave", "inactive" })
+
+	w := h.Do("GET", "/api/v1/admin/recommendors", nil, h.AdminToken())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	// Unlike the public list, admins see inactive recommendors too
+	var recommendors []models.Recommendor
+	h.Decode(w, &recommendors)
+	if got := recommendorNames(recommendors); fmt.Sprint(got) != "[alice dave]" {
+		t.Fatalf("got %v, want [alice dave]", got)
+	}
+}
+
+func TestGetRecommendorByID(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		id           func(r *models.Recommendor) string
+		query        string
+		code         int
+		errCode      string
+		destinations int
+	}{
+		{name: "public", path: "/api/v1/recommendors/", id: func(r *models.Recommendor) string { return fmt.Sprint(r.ID) }, code: http.StatusOK},
+		{name: "admin", path: "/api/v1/admin/recommendors/", id: func(r *models.Recommendor) string { return fmt.Sprint(r.ID) }, code: http.StatusOK},
+		{name: "include destinations", path: "/api/v1/recommendors/", id: func(r *models.Recommendor) string { return fmt.Sprint(r.ID) }, query: "?include=destinations", code: http.StatusOK, destinations: 1},
+		{name: "unknown", path: "/api/v1/recommendors/", id: func(r *models.Recommendor) string { return fmt.Sprint(r.ID + 1000) }, code: http.StatusNotFound, errCode: "RECOMMENDOR_NOT_FOUND"},
+		{name: "invalid id", path: "/api/v1/recommendors/", id: func(*models.Recommendor) string { return "abc" }, code: http.StatusBadRequest, errCode: "INVALID_ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			h.CreateDestination(recommendor)
+
+			w := h.Do("GET", tt.path+tt.id(recommendor)+tt.query, nil, h.AdminToken())
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code == http.StatusOK {
+				var got models.Recommendor
+				h.Decode(w, &got)
+				if got.ID != recommendor.ID || len(got.Destinations) != tt.destinations {
+					t.Fatalf("unexpected recommendor: id %d with %d destinations", got.ID, len(got.Destinations))
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateRecommendor(t *testing.T) {
+	bio := "金牌导游"
+	age := 17
+	existing := "existing"
+
+	tests := []struct {
+		name    string
+		body    controllers.UpdateRecommendorRequest
+		code    int
+		errCode string
+		check   func(t *testing.T, r models.Recommendor)
+	}{
+		{name: "partial update", body: controllers.UpdateRecommendorRequest{Bio: &bio}, code: http.StatusOK, check: func(t *testing.T, r models.Recommendor) {
+			if r.Bio != bio || r.Age != 30 {
+				t.Fatalf("bio = %q, age = %d", r.Bio, r.Age)
+			}
+		}},
+		{name: "invalid age", body: controllers.UpdateRecommendorRequest{Age: &age}, code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "id number taken", body: controllers.UpdateRecommendorRequest{IDNumber: &existing}, code: http.StatusBadRequest, errCode: "ID_NUMBER_EXISTS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			h.CreateRecommendor(func(r *models.Recommendor) { r.IDNumber = existing })
+			recommendor := h.CreateRecommendor()
+
+			w := h.Do("PUT", fmt.Sprintf("/api/v1/admin/recommendors/%d", recommendor.ID), tt.body, h.AdminToken())
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.check != nil {
+				var stored models.Recommendor
+				if err := h.DB.First(&stored, recommendor.ID).Error; err != nil {
+					t.Fatal(err)
+				}
+				tt.check(t, stored)
+			}
+		})
+	}
+}
+
+func TestDeleteRecommendor(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		code    int
+		errCode string
+	}{
+		{name: "v1", path: "/api/v1/admin/recommendors/%d", code: http.StatusOK},
+		{name: "legacy", path: "/api/admin/recommendors/%d", code: http.StatusOK},
+		{name: "unknown", path: "/api/v1/admin/recommendors/1%d", code: http.StatusNotFound, errCode: "RECOMMENDOR_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+
+			w := h.Do("DELETE", fmt.Sprintf(tt.path, recommendor.ID), nil, h.AdminToken())
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if tt.errCode != "" {
+				if code := h.ErrorCode(w); code != tt.errCode {
+					t.Fatalf("error code = %q, want %q", code, tt.errCode)
+				}
+				return
+			}
+
+			// Recommendors are soft deleted
+			var count int64
+			h.DB.Model(&models.Recommendor{}).Where("id = ?", recommendor.ID).Count(&count)
+			if count != 0 {
+				t.Fatal("recommendor is still visible after delete")
+			}
+			h.DB.Unscoped().Model(&models.Recommendor{}).Where("id = ?", recommendor.ID).Count(&count)
+			if count != 1 {
+				t.Fatal("recommendor row was removed instead of soft deleted")
+			}
+		})
+	}
+}
+
+func TestRegenerateQRCodes(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+
+	w := h.Do("POST", fmt.Sprintf("/api/v1/admin/recommendors/%d/qrcodes", recommendor.ID), nil, h.AdminToken())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var stored models.Recommendor
+	if err := h.DB.First(&stored, recommendor.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.QRCodeWeb == "" || stored.QRCodeWxapp == "" {
+		t.Fatal("QR codes were not stored")
+	}
+	if _, err := utils.LoadQRCode(recommendor.ID, utils.QRCodeWeb); err != nil {
+		t.Fatalf("web QR code image was not stored: %v", err)
+	}
+	if stored.QRStatus != models.QRStatusReady {
+		t.Fatalf("qr_status = %q, want ready", stored.QRStatus)
+	}
+}
+
+func TestGetQRCode(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+	path := fmt.Sprintf("/api/v1/recommendors/%d/qrcode.png", recommendor.ID)
+
+	svgPath := fmt.Sprintf("/api/v1/recommendors/%d/qrcode.svg", recommendor.ID)
+
+	tests := []struct {
+		name        string
+		path        string
+		code        int
+		errCode     string
+		size        int
+		contentType string
+		contains    string
+	}{
+		{name: "web by default", path: path, code: http.StatusOK, size: utils.DefaultQRCodeSize},
+		{name: "wxapp", path: path + "?type=wxapp", code: http.StatusOK, size: utils.DefaultQRCodeSize},
+		{name: "wxapp custom size", path: path + "?type=wxapp&size=300", code: http.StatusOK, size: 300},
+		{name: "wxapp svg", path: svgPath + "?type=wxapp", code: http.StatusOK, contentType: "image/svg+xml", contains: "data:image/png;base64,"},
+		{name: "custom size", path: path + "?size=512", code: http.StatusOK, size: 512},
+		{name: "branded", path: path + "?level=H&fg=%231a4d8f&bg=f0f0f0", code: http.StatusOK, size: utils.DefaultQRCodeSize},
+		{name: "svg", path: svgPath + "?size=300&fg=1a4d8f", code: http.StatusOK, contentType: "image/svg+xml", contains: `fill="#1a4d8f"`},
+		{name: "size too small", path: path + "?size=10", code: http.StatusBadRequest, errCode: "INVALID_QRCODE_OPTIONS"},
+		{name: "unknown type", path: path + "?type=foo", code: http.StatusBadRequest, errCode: "INVALID_QRCODE_OPTIONS"},
+		{name: "unknown level", path: path + "?level=X", code: http.StatusBadRequest, errCode: "INVALID_QRCODE_OPTIONS"},
+		{name: "invalid color", path: svgPath + "?fg=blue", code: http.StatusBadRequest, errCode: "INVALID_QRCODE_OPTIONS"},
+		{name: "unknown recommendor", path: "/api/v1/recommendors/999999/qrcode.png", code: http.StatusNotFound, errCode: "RECOMMENDOR_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("GET", tt.path, nil, "")
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if tt.code != http.StatusOK {
+				if code := h.ErrorCode(w); code != tt.errCode {
+					t.Fatalf("error code = %q, want %q", code, tt.errCode)
+				}
+				return
+			}
+
+			if tt.contentType == "" {
+				tt.contentType = "image/png"
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Fatalf("content type = %q, want %q", ct, tt.contentType)
+			}
+			if w.Header().Get("ETag") == "" {
+				t.Fatal("ETag header missing")
+			}
+			if tt.contentType != "image/png" {
+				if !strings.Contains(w.Body.String(), tt.contains) {
+					t.Fatalf("body does not contain %s: %.200s", tt.contains, w.Body.String())
+				}
+				return
+			}
+			config, err := png.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != tt.size {
+				t.Fatalf("width = %d, want %d", config.Width, tt.size)
+			}
+		})
+	}
+
+	w := h.Do("GET", path, nil, "")
+	etag := w.Header().Get("ETag")
+	if cc := w.Header().Get("Cache-Control"); strings.Contains(cc, "immutable") {
+		t.Fatalf("unversioned URL cached as immutable: %q", cc)
+	}
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("If-None-Match", etag)
+	if w := h.Serve(req); w.Code != http.StatusNotModified {
+		t.Fatalf("conditional request status = %d, want 304", w.Code)
+	}
+
+	w = h.Do("GET", path+"?v="+strings.Trim(etag, `"`), nil, "")
+	if cc := w.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Fatalf("versioned URL Cache-Control = %q", cc)
+	}
+}
+
+func TestGetQRCodeWeChat(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(*testutil.WeChat)
+		code    int
+		errCode string
+		tokens  int
+		codes   int
+	}{
+		{name: "token cached", setup: func(*testutil.WeChat) {}, code: http.StatusOK, tokens: 1, codes: 2},
+		{
+			name:   "transient failure retried",
+			setup:  func(w *testutil.WeChat) { w.FailWithStatus(testutil.WeChatWxaCodePath, 1, http.StatusBadGateway) },
+			code:   http.StatusOK,
+			tokens: 1,
+			codes:  3,
+		},
+		{
+			name:   "revoked token refreshed",
+			setup:  func(w *testutil.WeChat) { w.RevokeToken() },
+			code:   http.StatusOK,
+			tokens: 2,
+			codes:  3,
+		},
+		{
+			name:    "rejected request",
+			setup:   func(w *testutil.WeChat) { w.FailWithCode(testutil.WeChatWxaCodePath, 1, 45009) },
+			code:    http.StatusInternalServerError,
+			errCode: "QRCODE_GENERATION_FAILED",
+			tokens:  1,
+			codes:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			path := fmt.Sprintf("/api/v1/admin/recommendors/%d/qrcodes", recommendor.ID)
+			token := h.AdminToken()
+
+			if w := h.Do("POST", path, nil, token); w.Code != http.StatusOK {
+				t.Fatalf("first request status = %d: %s", w.Code, w.Body.String())
+			}
+			tt.setup(h.WeChat)
+
+			w := h.Do("POST", path, nil, token)
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if tt.code != http.StatusOK {
+				if code := h.ErrorCode(w); code != tt.errCode {
+					t.Fatalf("error code = %q, want %q", code, tt.errCode)
+				}
+			}
+			if calls := h.WeChat.Calls(testutil.WeChatTokenPath); calls != tt.tokens {
+				t.Fatalf("token calls = %d, want %d", calls, tt.tokens)
+			}
+			if calls := h.WeChat.Calls(testutil.WeChatWxaCodePath); calls != tt.codes {
+				t.Fatalf("code calls = %d, want %d", calls, tt.codes)
+			}
+		})
+	}
+}
+
+func TestGetQRCodeVariants(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+	path := fmt.Sprintf("/api/v1/recommendors/%d/qrcode", recommendor.ID)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		size        int
+	}{
+		{name: "stored", path: path + ".png?type=wxapp", contentType: "image/png", size: utils.DefaultQRCodeSize},
+		{name: "resized", path: path + ".png?type=wxapp&size=200", contentType: "image/png", size: 200},
+		{name: "resized again", path: path + ".png?type=wxapp&size=200", contentType: "image/png", size: 200},
+		{name: "other size", path: path + ".png?type=wxapp&size=300&fg=1a4d8f", contentType: "image/png", size: 300},
+		{name: "svg", path: path + ".svg?type=wxapp&size=128", contentType: "image/svg+xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("GET", tt.path, nil, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Fatalf("content type = %q, want %q", ct, tt.contentType)
+			}
+			if tt.size == 0 {
+				return
+			}
+			config, _, err := image.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != tt.size {
+				t.Fatalf("width = %d, want %d", config.Width, tt.size)
+			}
+		})
+	}
+
+	// Only the stored code was drawn by WeChat, and it is stored as PNG
+	if calls := h.WeChat.Calls(testutil.WeChatWxaCodePath); calls != 1 {
+		t.Fatalf("code calls = %d, want 1", calls)
+	}
+	if _, err := os.Stat(filepath.Join(utils.QRCodeDir, fmt.Sprintf("%d_wxapp.png", recommendor.ID))); err != nil {
+		t.Fatalf("Mini Program code not stored as PNG: %v", err)
+	}
+
+	// Codes stored as JPEG before are served and stored again as PNG
+	legacy := h.CreateRecommendor()
+	var code bytes.Buffer
+	if err := jpeg.Encode(&code, image.NewRGBA(image.Rect(0, 0, utils.DefaultQRCodeSize, utils.DefaultQRCodeSize)), nil); err != nil {
+		t.Fatal(err)
+	}
+	legacyPath := filepath.Join(utils.QRCodeDir, fmt.Sprintf("%d_wxapp.jpg", legacy.ID))
+	if err := os.WriteFile(legacyPath, code.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := h.Do("GET", fmt.Sprintf("/api/v1/recommendors/%d/qrcode.png?type=wxapp", legacy.ID), nil, "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("status = %d, content type %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	if _, err := os.Stat(legacyPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("JPEG code not replaced: %v", err)
+	}
+	if calls := h.WeChat.Calls(testutil.WeChatWxaCodePath); calls != 1 {
+		t.Fatalf("code calls = %d, want the stored code to be converted", calls)
+	}
+}
+
+func TestGetBadge(t *testing.T) {
+	h := testutil.New(t)
+
+	var avatar bytes.Buffer
+	if err := png.Encode(&avatar, image.NewRGBA(image.Rect(0, 0, 40, 60))); err != nil {
+		t.Fatal(err)
+	}
+	upload, err := utils.WriteFile(utils.AvatarDir, "badge.png", avatar.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withAvatar := h.CreateRecommendor(func(r *models.Recommendor) {
+		r.Avatar = "/uploads/avatars/" + upload.FileName
+	})
+	withoutAvatar := h.CreateRecommendor()
+	missingAvatar := h.CreateRecommendor(func(r *models.Recommendor) {
+		r.Avatar = "/uploads/../../etc/passwd"
+	})
+
+	tests := []struct {
+		name    string
+		id      uint
+		token   string
+		code    int
+		errCode string
+	}{
+		{name: "with avatar", id: withAvatar.ID, token: h.AdminToken(), code: http.StatusOK},
+		{name: "without avatar", id: withoutAvatar.ID, token: h.AdminToken(), code: http.StatusOK},
+		{name: "unreadable avatar", id: missingAvatar.ID, token: h.AdminToken(), code: http.StatusOK},
+		{name: "unauthenticated", id: withAvatar.ID, code: http.StatusUnauthorized, errCode: "AUTH_HEADER_MISSING"},
+		{name: "unknown recommendor", id: 999999, token: h.AdminToken(), code: http.StatusNotFound, errCode: "RECOMMENDOR_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("GET", fmt.Sprintf("/api/v1/admin/recommendors/%d/badge.pdf", tt.id), nil, tt.token)
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if tt.code != http.StatusOK {
+				if code := h.ErrorCode(w); code != tt.errCode {
+					t.Fatalf("error code = %q, want %q", code, tt.errCode)
+				}
+				return
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+				t.Fatalf("content type = %q", ct)
+			}
+			if !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")) {
+				t.Fatalf("body is not a PDF: %.20q", w.Body.String())
+			}
+		})
+	}
+}
+
+func recommendorNames(recommendors []models.Recommendor) []string {
+	names := make([]string, 0, len(recommendors))
+	for _, r := range recommendors {
+		names = append(names, r.Name)
+	}
+	return names
+}
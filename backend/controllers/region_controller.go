@@ -1,11 +1,10 @@
 package controllers
 
 import (
-	"net/http"
 	"strconv"
 
-	"tourism_recommendor/config"
 	"tourism_recommendor/models"
+	"tourism_recommendor/response"
 	"tourism_recommendor/utils"
 
 	"github.com/gin-gonic/gin"
@@ -14,15 +13,25 @@ import (
 // RegionController handles region-related requests
 type RegionController struct{}
 
+// regionSortFields are the columns clients may sort regions by
+var regionSortFields = []string{"id", "name", "created_at", "updated_at"}
+
+// RegionFilters are the filters accepted by the region list
+var RegionFilters = utils.FilterSpec{
+	{Name: "name", Ops: []utils.FilterOp{utils.OpLike, utils.OpEq}, Description: "Name"},
+}
+
 // CreateRegionRequest holds the request data for creating a region
 type CreateRegionRequest struct {
 	Name        string `json:"name" binding:"required"`
+	Code        string `json:"code"`
 	Description string `json:"description"`
 }
 
 // UpdateRegionRequest holds the request data for updating a region
 type UpdateRegionRequest struct {
 	Name        string `json:"name"`
+	Code        string `json:"code"`
 	Description string `json:"description"`
 }
 
@@ -32,29 +41,34 @@ type UpdateRegionRequest struct {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param region body CreateRegionRequest true "Region data"
 // @Success 201 {object} models.Region
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/regions [post]
 // @Router /api/admin/regions [post]
 func (rc *RegionController) CreateRegion(c *gin.Context) {
 	var req CreateRegionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		response.BadRequest(c, err)
 		return
 	}
 
 	region := models.Region{
 		Name:        req.Name,
+		Code:        req.Code,
 		Description: req.Description,
 	}
 
-	if err := config.DB.Create(&region).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create region: " + err.Error()})
+	if err := dbWithContext(c).Create(&region).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusCreated, region)
+	response.Created(c, region)
 }
 
 // GetRegions retrieves a paginated list of regions
@@ -62,41 +76,47 @@ func (rc *RegionController) CreateRegion(c *gin.Context) {
 // @Description Retrieve a paginated list of regions with optional filtering
 // @Tags admin
 // @Produce json
+// @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
-// @Param sort_by query string false "Sort by field" default(id)
-// @Param sort_order query string false "Sort order (asc/desc)" default(asc)
-// @Param name query string false "Filter by name"
-// @Success 200 {object} utils.PaginationResponse
-// @Failure 500 {object} map[string]string
+// @Param sort query string false "Comma-separated sort fields, prefix - for descending (id, name, created_at, updated_at)"
+// @Param sort_by query string false "Sort by field (superseded by sort)" default(id)
+// @Param sort_order query string false "Sort order (asc/desc, superseded by sort)" default(asc)
+// @Filters RegionFilters
+// @Success 200 {object} utils.PaginationResponse{data=[]models.Region}
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/regions [get]
 // @Router /api/admin/regions [get]
 func (rc *RegionController) GetRegions(c *gin.Context) {
-	// Parse pagination parameters
-	pr := utils.ParsePaginationRequest(
-		c.DefaultQuery("page", "1"),
-		c.DefaultQuery("page_size", "10"),
-		c.DefaultQuery("sort_by", "id"),
-		c.DefaultQuery("sort_order", "asc"),
-	)
+	// Parse pagination and sorting parameters
+	pr, ok := paginationRequest(c, regionSortFields)
+	if !ok {
+		return
+	}
 
 	// Build query
-	query := config.DB.Model(&models.Region{})
+	query := dbWithContext(c).Model(&models.Region{})
 
 	// Apply filters
-	if name := c.Query("name"); name != "" {
-		query = utils.ApplyFilter(query, "name", name)
+	filters, ok := listFilters(c, RegionFilters)
+	if !ok {
+		return
 	}
+	query = utils.ApplyFilters(query, filters)
 
 	var regions []models.Region
 
 	// Get paginated results
-	response, err := utils.Paginate(query, pr, &regions, &models.Region{})
+	page, err := utils.Paginate(query, pr, &regions, &models.Region{})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve regions: " + err.Error()})
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	response.Paginated(c, page)
 }
 
 // GetRegionByID retrieves a single region by ID
@@ -104,25 +124,30 @@ func (rc *RegionController) GetRegions(c *gin.Context) {
 // @Description Retrieve a single region by its ID
 // @Tags admin
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Region ID"
 // @Success 200 {object} models.Region
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/regions/{id} [get]
 // @Router /api/admin/regions/{id} [get]
 func (rc *RegionController) GetRegionByID(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid region ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	var region models.Region
-	if err := config.DB.First(&region, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Region not found"})
+	if err := dbWithContext(c).First(&region, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRegionNotFound))
 		return
 	}
 
-	c.JSON(http.StatusOK, region)
+	response.OK(c, region)
 }
 
 // UpdateRegion updates an existing region
@@ -131,29 +156,33 @@ func (rc *RegionController) GetRegionByID(c *gin.Context) {
 // @Tags admin
 // @Accept json
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Region ID"
 // @Param region body UpdateRegionRequest true "Region data"
 // @Success 200 {object} models.Region
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/regions/{id} [put]
 // @Router /api/admin/regions/{id} [put]
 func (rc *RegionController) UpdateRegion(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid region ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	var req UpdateRegionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data: " + err.Error()})
+		response.BadRequest(c, err)
 		return
 	}
 
 	var region models.Region
-	if err := config.DB.First(&region, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Region not found"})
+	if err := dbWithContext(c).First(&region, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRegionNotFound))
 		return
 	}
 
@@ -161,16 +190,19 @@ func (rc *RegionController) UpdateRegion(c *gin.Context) {
 	if req.Name != "" {
 		region.Name = req.Name
 	}
+	if req.Code != "" {
+		region.Code = req.Code
+	}
 	if req.Description != "" {
 		region.Description = req.Description
 	}
 
-	if err := config.DB.Save(&region).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update region: " + err.Error()})
+	if err := dbWithContext(c).Save(&region).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, region)
+	response.OK(c, region)
 }
 
 // DeleteRegion deletes a region
@@ -178,43 +210,51 @@ func (rc *RegionController) UpdateRegion(c *gin.Context) {
 // @Description Delete a region by its ID
 // @Tags admin
 // @Produce json
+// @Security BearerAuth
 // @Param id path int true "Region ID"
-// @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Success 200 {object} response.MessageBody
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/regions/{id} [delete]
 // @Router /api/admin/regions/{id} [delete]
 func (rc *RegionController) DeleteRegion(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid region ID"})
+		response.Error(c, response.ErrInvalidID)
 		return
 	}
 
 	var region models.Region
-	if err := config.DB.First(&region, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Region not found"})
+	if err := dbWithContext(c).First(&region, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRegionNotFound))
 		return
 	}
 
-	// Check if region is in use by recommendors
+	// Check if region is in use by recommendors; they refer to regions by name as one
+	// segment of their "/"-separated address, or by code when none was given
+	inUse := utils.ApplySegmentFilter(dbWithContext(c), "region_address", "/", region.Name)
+	if region.Code != "" {
+		inUse = inUse.Or("province_code = ? OR city_code = ? OR district_code = ?", region.Code, region.Code, region.Code)
+	}
 	var count int64
-	if err := config.DB.Model(&models.Recommendor{}).Where("region_id = ?", id).Count(&count).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check region usage"})
+	query := dbWithContext(c).Model(&models.Recommendor{}).Where(inUse)
+	if err := query.Count(&count).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
 	if count > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Cannot delete region because it is being used by recommendors",
-		})
+		response.Error(c, response.ErrRegionInUse)
 		return
 	}
 
-	if err := config.DB.Delete(&region).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete region: " + err.Error()})
+	if err := dbWithContext(c).Delete(&region).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Region deleted successfully"})
+	response.Message(c, "message.region.deleted", nil)
 }
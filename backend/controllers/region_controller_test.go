@@ -0,0 +1,211 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+)
+
+func TestCreateRegion(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    interface{}
+		role    models.AdminRole
+		code    int
+		errCode string
+	}{
+		{name: "valid region", body: controllers.CreateRegionRequest{Name: "华北", Description: "北方地区"}, code: http.StatusCreated},
+		{name: "missing name", body: controllers.CreateRegionRequest{Description: "北方地区"}, code: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "malformed body", body: "not an object", code: http.StatusBadRequest, errCode: "INVALID_BODY"},
+		{name: "non-admin role", body: controllers.CreateRegionRequest{Name: "华北"}, role: "viewer", code: http.StatusForbidden, errCode: "ADMIN_REQUIRED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			admin := h.CreateAdmin(func(a *models.Admin) {
+				if tt.role != "" {
+					a.Role = tt.role
+				}
+			})
+
+			w := h.Do("POST", "/api/v1/admin/regions", tt.body, h.Token(admin))
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code == http.StatusCreated {
+				var region models.Region
+				h.Decode(w, &region)
+				if region.ID == 0 || region.Name != "华北" {
+					t.Fatalf("unexpected region: %+v", region)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRegions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		total int64
+		count int
+		first string
+	}{
+		{name: "first page", query: "?page=1&page_size=2", total: 3, count: 2, first: "华北"},
+		{name: "last page", query: "?page=2&page_size=2", total: 3, count: 1, first: "华东"},
+		{name: "sorted descending", query: "?sort_by=id&sort_order=desc", total: 3, count: 3, first: "华东"},
+		{name: "sort expression", query: "?sort=-name", total: 3, count: 3, first: "华南"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			for _, name := range []string{"华北", "华南", "华东"} {
+				h.CreateRegion(name)
+			}
+
+			w := h.Do("GET", "/api/v1/admin/regions"+tt.query, nil, h.AdminToken())
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			var regions []models.Region
+			envelope := h.Decode(w, &regions)
+			if envelope.Meta == nil || envelope.Meta.Total == nil || *envelope.Meta.Total != tt.total {
+				t.Fatalf("meta = %+v, want total %d", envelope.Meta, tt.total)
+			}
+			if len(regions) != tt.count || regions[0].Name != tt.first {
+				t.Fatalf("got %d regions starting with %q, want %d starting with %q", len(regions), regions[0].Name, tt.count, tt.first)
+			}
+		})
+	}
+}
+
+func TestGetRegionByID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      func(region *models.Region) string
+		code    int
+		errCode string
+	}{
+		{name: "existing region", id: func(r *models.Region) string { return fmt.Sprint(r.ID) }, code: http.StatusOK},
+		{name: "unknown region", id: func(r *models.Region) string { return fmt.Sprint(r.ID + 1000) }, code: http.StatusNotFound, errCode: "REGION_NOT_FOUND"},
+		{name: "invalid id", id: func(*models.Region) string { return "abc" }, code: http.StatusBadRequest, errCode: "INVALID_ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			region := h.CreateRegion("华北")
+
+			w := h.Do("GET", "/api/v1/admin/regions/"+tt.id(region), nil, h.AdminToken())
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+		})
+	}
+}
+
+func TestUpdateRegion(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        controllers.UpdateRegionRequest
+		wantName    string
+		wantDetails string
+	}{
+		{name: "rename", body: controllers.UpdateRegionRequest{Name: "华北地区"}, wantName: "华北地区", wantDetails: "北方"},
+		{name: "describe", body: controllers.UpdateRegionRequest{Description: "京津冀"}, wantName: "华北", wantDetails: "京津冀"},
+		{name: "empty update keeps values", body: controllers.UpdateRegionRequest{}, wantName: "华北", wantDetails: "北方"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			region := h.CreateRegion("华北")
+			if err := h.DB.Model(region).Update("description", "北方").Error; err != nil {
+				t.Fatal(err)
+			}
+
+			w := h.Do("PUT", fmt.Sprintf("/api/v1/admin/regions/%d", region.ID), tt.body, h.AdminToken())
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			var stored models.Region
+			if err := h.DB.First(&stored, region.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Name != tt.wantName || stored.Description != tt.wantDetails {
+				t.Fatalf("stored region = %+v, want %q/%q", stored, tt.wantName, tt.wantDetails)
+			}
+		})
+	}
+}
+
+func TestDeleteRegion(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		region  models.Region
+		address string // region address of an existing recommendor, if any
+		code    int
+		errCode string
+		deleted bool
+	}{
+		{name: "v1", path: "/api/v1/admin/regions/%d", code: http.StatusOK, deleted: true},
+		{name: "legacy", path: "/api/admin/regions/%d", code: http.StatusOK, deleted: true},
+		{name: "in use", path: "/api/v1/admin/regions/%d", address: "华北/北京市/东城区", code: http.StatusBadRequest, errCode: "REGION_IN_USE"},
+		{name: "in use with spaced separators", path: "/api/v1/admin/regions/%d", region: models.Region{Name: "北京市"}, address: "北京市 / 北京市 / 东城区", code: http.StatusBadRequest, errCode: "REGION_IN_USE"},
+		{name: "partial name", path: "/api/v1/admin/regions/%d", region: models.Region{Name: "北京"}, address: "北京市 / 北京市 / 东城区", code: http.StatusOK, deleted: true},
+		{name: "wildcard name", path: "/api/v1/admin/regions/%d", region: models.Region{Name: "%"}, address: "北京市/北京市/东城区", code: http.StatusOK, deleted: true},
+		{name: "in use by code", path: "/api/v1/admin/regions/%d", region: models.Region{Name: "东城区", Code: "110101"}, address: "110000/110100/110101", code: http.StatusBadRequest, errCode: "REGION_IN_USE"},
+		{name: "unauthenticated", path: "/api/v1/admin/regions/%d", code: http.StatusUnauthorized, errCode: "AUTH_HEADER_MISSING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			region := tt.region
+			if region.Name == "" {
+				region.Name = "华北"
+			}
+			if err := h.DB.Create(&region).Error; err != nil {
+				t.Fatalf("failed to create region: %v", err)
+			}
+			if tt.address != "" {
+				h.CreateRecommendor(func(r *models.Recommendor) { r.RegionAddress = tt.address })
+			}
+			token := ""
+			if tt.code != http.StatusUnauthorized {
+				token = h.AdminToken()
+			}
+
+			w := h.Do("DELETE", fmt.Sprintf(tt.path, region.ID), nil, token)
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+
+			var count int64
+			h.DB.Model(&models.Region{}).Where("id = ?", region.ID).Count(&count)
+			if deleted := count == 0; deleted != tt.deleted {
+				t.Fatalf("deleted = %v, want %v", deleted, tt.deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/logging"
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Scan report limits
+const (
+	scanReportDefaultDays = 30
+	scanReportMaxDays     = 366
+	scanDayLayout         = "2006-01-02"
+	scanFieldLimit        = 500 // longest user agent or referrer stored with a scan
+)
+
+// ShortLinkController handles the /r/{code} redirects of QR codes and the scan reports
+type ShortLinkController struct {
+	Config *config.Config
+}
+
+// NewShortLinkController creates a short link controller
+func NewShortLinkController(cfg *config.Config) *ShortLinkController {
+	return &ShortLinkController{Config: cfg}
+}
+
+// ShortLinkResponse is a short link with its public URL, effective target and scan count
+type ShortLinkResponse struct {
+	models.ShortLink
+	URL    string `json:"url"`    // address encoded in the QR code
+	Target string `json:"target"` // where the link currently redirects to
+	Scans  int64  `json:"scans"`
+}
+
+// UpdateShortLinkRequest represents the request to change the target of a short link
+type UpdateShortLinkRequest struct {
+	TargetURL string `json:"target_url" binding:"omitempty,url,max=500"` // empty restores the default target
+}
+
+// RecommendorScans is the number of scans of the QR codes of a recommender
+type RecommendorScans struct {
+	RecommendorID uint   `json:"recommendor_id"`
+	Name          string `json:"name"`
+	Scans         int64  `json:"scans"`
+}
+
+// DailyScans is the number of scans on a day
+type DailyScans struct {
+	Day   string `json:"day"` // 2006-01-02
+	Scans int64  `json:"scans"`
+}
+
+// Redirect counts a scan of a QR code and redirects to the target of its short link
+// @Summary Follow short link
+// @Description Redirect to the target of a short link, the address plain QR codes encode, and record the scan with its
+// @Description user agent, referrer and coarse source. Redirects are not cached, so every scan is counted.
+// @Tags recommendors
+// @Param code path string true "Short link code"
+// @Success 302 {string} string "Redirect to the target"
+// @Failure 404 {object} response.Body
+// @Router /r/{code} [get]
+func (sc *ShortLinkController) Redirect(c *gin.Context) {
+	var link models.ShortLink
+	if err := dbWithContext(c).Where("code = ?", c.Param("code")).First(&link).Error; err != nil {
+		response.EnableEnvelope(c)
+		response.Error(c, lookupError(err, response.ErrShortLinkNotFound))
+		return
+	}
+
+	now := time.Now()
+	userAgent := c.Request.UserAgent()
+	scan := models.ScanEvent{
+		ShortLinkID:   link.ID,
+		RecommendorID: link.RecommendorID,
+		Source:        utils.ScanSource(userAgent),
+		UserAgent:     truncate(userAgent, scanFieldLimit),
+		Referrer:      truncate(c.Request.Referer(), scanFieldLimit),
+		Day:           now.Format(scanDayLayout),
+		ScannedAt:     now,
+	}
+	if err := dbWithContext(c).Create(&scan).Error; err != nil {
+		// A lost scan must not keep the visitor from the page
+		logging.FromContext(c.Request.Context()).Warn("failed to record scan", "short_link_id", link.ID, "error", err)
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, sc.target(&link))
+}
+
+// GetRecommendorShortLinks lists the short links of a recommender
+// @Summary List short links of a recommendor
+// @Description List the short links the plain QR codes of a recommender point to, with their targets and scan counts.
+// @Description Links are created when the QR codes are generated.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Recommendor ID"
+// @Success 200 {array} controllers.ShortLinkResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/{id}/short-links [get]
+func (sc *ShortLinkController) GetRecommendorShortLinks(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, response.ErrInvalidID)
+		return
+	}
+
+	var recommendor models.Recommendor
+	if err := dbWithContext(c).Select("id").First(&recommendor, id).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
+		return
+	}
+
+	var links []models.ShortLink
+	if err := dbWithContext(c).Where("recommendor_id = ?", recommendor.ID).Order("type").Find(&links).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	result := make([]ShortLinkResponse, 0, len(links))
+	for _, link := range links {
+		res, err := sc.linkResponse(dbWithContext(c), link)
+		if err != nil {
+			response.Error(c, response.ErrDatabase.WithCause(err))
+			return
+		}
+		result = append(result, res)
+	}
+	response.OK(c, result)
+}
+
+// UpdateShortLink changes the target of a short link
+// @Summary Update short link
+// @Description Point a short link at another URL without reprinting its QR code. An empty target_url restores the
+// @Description recommender page the link points to by default.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Short link code"
+// @Param request body UpdateShortLinkRequest true "New target"
+// @Success 200 {object} ShortLinkResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/short-links/{code} [put]
+func (sc *ShortLinkController) UpdateShortLink(c *gin.Context) {
+	var req UpdateShortLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err)
+		return
+	}
+
+	var link models.ShortLink
+	if err := dbWithContext(c).Where("code = ?", c.Param("code")).First(&link).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrShortLinkNotFound))
+		return
+	}
+
+	link.TargetURL = req.TargetURL
+	if err := dbWithContext(c).Model(&link).Update("target_url", link.TargetURL).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	res, err := sc.linkResponse(dbWithContext(c), link)
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+	response.OK(c, res)
+}
+
+// GetRecommendorScans reports the scans per recommender
+// @Summary Scans per recommendor
+// @Description Count the QR code scans of each recommender between two days, most scanned first. Recommenders without
+// @Description scans in the range are left out.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "First day (2006-01-02), defaults to 29 days before to"
+// @Param to query string false "Last day (2006-01-02), defaults to today"
+// @Param source query string false "Only count scans from this source" Enums(wechat,alipay,mobile,desktop,bot,unknown)
+// @Success 200 {array} controllers.RecommendorScans
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/scans/recommendors [get]
+func (sc *ShortLinkController) GetRecommendorScans(c *gin.Context) {
+	query, _, ok := scanQuery(c)
+	if !ok {
+		return
+	}
+
+	result := []RecommendorScans{}
+	err := query.
+		Select("scan_events.recommendor_id, recommendors.name, COUNT(*) AS scans").
+		Joins("LEFT JOIN recommendors ON recommendors.id = scan_events.recommendor_id").
+		Group("scan_events.recommendor_id, recommendors.name").
+		Order("scans DESC, scan_events.recommendor_id").
+		Scan(&result).Error
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+	response.OK(c, result)
+}
+
+// GetDailyScans reports the scans per day
+// @Summary Scans per day
+// @Description Count the QR code scans of every day between two days, including days without scans, optionally for
+// @Description one recommender.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "First day (2006-01-02), defaults to 29 days before to"
+// @Param to query string false "Last day (2006-01-02), defaults to today"
+// @Param source query string false "Only count scans from this source" Enums(wechat,alipay,mobile,desktop,bot,unknown)
+// @Param recommendor_id query int false "Only count scans of this recommendor"
+// @Success 200 {array} controllers.DailyScans
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/scans/daily [get]
+func (sc *ShortLinkController) GetDailyScans(c *gin.Context) {
+	query, days, ok := scanQuery(c)
+	if !ok {
+		return
+	}
+	if value := c.Query("recommendor_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			response.Error(c, response.ErrInvalidID.WithDetail("recommendor_id", value))
+			return
+		}
+		query = query.Where("scan_events.recommendor_id = ?", id)
+	}
+
+	var counted []DailyScans
+	if err := query.Select("day, COUNT(*) AS scans").Group("day").Scan(&counted).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	// Every day of the range is reported, so charts have no gaps
+	scans := make(map[string]int64, len(counted))
+	for _, row := range counted {
+		scans[row.Day] = row.Scans
+	}
+	result := make([]DailyScans, len(days))
+	for i, day := range days {
+		result[i] = DailyScans{Day: day, Scans: scans[day]}
+	}
+	response.OK(c, result)
+}
+
+// scanQuery parses the range and source of a scan report and returns the query of the
+// matching scans with the days of the range
+func scanQuery(c *gin.Context) (*gorm.DB, []string, bool) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		day, err := time.ParseInLocation(scanDayLayout, value, time.Local)
+		if err != nil {
+			response.Error(c, response.ErrInvalidDateRange.WithDetail("to", value))
+			return nil, nil, false
+		}
+		to = day
+	}
+	from := to.AddDate(0, 0, 1-scanReportDefaultDays)
+	if value := c.Query("from"); value != "" {
+		day, err := time.ParseInLocation(scanDayLayout, value, time.Local)
+		if err != nil {
+			response.Error(c, response.ErrInvalidDateRange.WithDetail("from", value))
+			return nil, nil, false
+		}
+		from = day
+	}
+
+	var days []string
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if len(days) == scanReportMaxDays {
+			response.Error(c, response.ErrInvalidDateRange.WithDetail("max_days", scanReportMaxDays))
+			return nil, nil, false
+		}
+		days = append(days, day.Format(scanDayLayout))
+	}
+	if len(days) == 0 {
+		response.Error(c, response.ErrInvalidDateRange.WithDetail("from", from.Format(scanDayLayout)).WithDetail("to", to.Format(scanDayLayout)))
+		return nil, nil, false
+	}
+
+	query := dbWithContext(c).Model(&models.ScanEvent{}).Where("day BETWEEN ? AND ?", days[0], days[len(days)-1])
+	if source := c.Query("source"); source != "" {
+		if !slices.Contains(utils.ScanSources, source) {
+			response.Error(c, response.ErrValidation.WithDetail("source", source).WithDetail("allowed", utils.ScanSources))
+			return nil, nil, false
+		}
+		query = query.Where("source = ?", source)
+	}
+	return query, days, true
+}
+
+// linkResponse adds the URL, effective target and scan count to a short link
+func (sc *ShortLinkController) linkResponse(db *gorm.DB, link models.ShortLink) (ShortLinkResponse, error) {
+	res := ShortLinkResponse{
+		ShortLink: link,
+		URL:       utils.ShortLinkURL(sc.Config.QRCode.BaseURL, link.Code),
+		Target:    sc.target(&link),
+	}
+	err := db.Model(&models.ScanEvent{}).Where("short_link_id = ?", link.ID).Count(&res.Scans).Error
+	return res, err
+}
+
+// target returns where a short link redirects to: its own target, or else the page of its recommender
+func (sc *ShortLinkController) target(link *models.ShortLink) string {
+	if link.TargetURL != "" {
+		return link.TargetURL
+	}
+	brand := sc.Config.QRCode
+	if link.Type == utils.QRCodeWxapp {
+		return utils.WxappFallbackURL(brand.WxAppID, utils.RecommendorWxappPath(brand.WxAppPath, link.RecommendorID))
+	}
+	return utils.RecommendorWebURL(brand.BaseURL, link.RecommendorID)
+}
+
+// shortLinkCode returns the code of the short link of a recommender's QR code, creating
+// the link on first use
+func shortLinkCode(ctx context.Context, recommendorID uint, kind string) (string, error) {
+	db := config.DB.WithContext(ctx)
+	for attempt := 0; attempt < 3; attempt++ {
+		var link models.ShortLink
+		err := db.Where("recommendor_id = ? AND type = ?", recommendorID, kind).First(&link).Error
+		if err == nil {
+			return link.Code, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+
+		code, err := utils.NewShortCode()
+		if err != nil {
+			return "", err
+		}
+		// Nothing is created when a concurrent generation has created the link first, or in
+		// the rare case the code is taken; both are settled by looking again
+		created := db.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.ShortLink{Code: code, RecommendorID: recommendorID, Type: kind})
+		if created.Error != nil {
+			return "", created.Error
+		}
+		if created.RowsAffected == 1 {
+			return code, nil
+		}
+	}
+	return "", errors.New("failed to allocate a short link code")
+}
+
+// truncate shortens s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,246 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+	"tourism_recommendor/utils"
+)
+
+// recommendorShortLink generates the QR codes of a recommendor and returns the short link of its web code
+func recommendorShortLink(t *testing.T, h *testutil.Harness, recommendor *models.Recommendor) controllers.ShortLinkResponse {
+	t.Helper()
+
+	w := h.Do("POST", fmt.Sprintf("/api/v1/admin/recommendors/%d/qrcodes", recommendor.ID), nil, h.AdminToken())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	w = h.Do("GET", fmt.Sprintf("/api/v1/admin/recommendors/%d/short-links", recommendor.ID), nil, h.AdminToken())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	// Mini Program codes drawn by WeChat open the Mini Program directly and have no short link
+	var links []controllers.ShortLinkResponse
+	h.Decode(w, &links)
+	if len(links) != 1 || links[0].Type != utils.QRCodeWeb {
+		t.Fatalf("links = %+v, want the web link only", links)
+	}
+	return links[0]
+}
+
+func TestShortLinkRedirect(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string // defaults to the code of the recommendor's link
+		target    string
+		userAgent string
+		status    int
+		location  string // defaults to the recommendor page
+		source    string
+	}{
+		{
+			name:      "wechat",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) MicroMessenger/8.0.40",
+			status:    http.StatusFound,
+			source:    utils.ScanSourceWeChat,
+		},
+		{
+			name:      "changed target",
+			target:    "https://example.com/campaign",
+			userAgent: "Mozilla/5.0 (Linux; Android 14) Mobile Safari/537.36",
+			status:    http.StatusFound,
+			location:  "https://example.com/campaign",
+			source:    utils.ScanSourceMobile,
+		},
+		{name: "unknown", code: "missing", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			link := recommendorShortLink(t, h, recommendor)
+			if tt.target != "" {
+				h.DB.Model(&models.ShortLink{}).Where("id = ?", link.ID).Update("target_url", tt.target)
+			}
+
+			code := tt.code
+			if code == "" {
+				code = link.Code
+			}
+			req := httptest.NewRequest("GET", "/r/"+code, nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			req.Header.Set("Referer", "https://example.com/poster")
+			w := h.Serve(req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status != http.StatusFound {
+				if code := h.ErrorCode(w); code != "SHORT_LINK_NOT_FOUND" {
+					t.Fatalf("error code = %q", code)
+				}
+				return
+			}
+
+			location := tt.location
+			if location == "" {
+				location = utils.RecommendorWebURL(h.Config.QRCode.BaseURL, recommendor.ID)
+			}
+			if got := w.Header().Get("Location"); got != location {
+				t.Fatalf("Location = %q, want %q", got, location)
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Fatalf("redirect may be cached: %q", w.Header().Get("Cache-Control"))
+			}
+
+			var scans []models.ScanEvent
+			h.DB.Where("short_link_id = ?", link.ID).Find(&scans)
+			if len(scans) != 1 {
+				t.Fatalf("%d scans recorded, want 1", len(scans))
+			}
+			scan := scans[0]
+			if scan.RecommendorID != recommendor.ID || scan.Source != tt.source || scan.UserAgent != tt.userAgent ||
+				scan.Referrer != "https://example.com/poster" || scan.Day != time.Now().Format("2006-01-02") {
+				t.Fatalf("scan = %+v", scan)
+			}
+		})
+	}
+}
+
+func TestUpdateShortLink(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+	link := recommendorShortLink(t, h, recommendor)
+	page := utils.RecommendorWebURL(h.Config.QRCode.BaseURL, recommendor.ID)
+
+	tests := []struct {
+		name    string
+		code    string
+		body    interface{}
+		status  int
+		errCode string
+		target  string
+	}{
+		{name: "new target", code: link.Code, body: controllers.UpdateShortLinkRequest{TargetURL: "https://example.com/a"}, status: http.StatusOK, target: "https://example.com/a"},
+		{name: "default target", code: link.Code, body: controllers.UpdateShortLinkRequest{}, status: http.StatusOK, target: page},
+		{name: "not a url", code: link.Code, body: controllers.UpdateShortLinkRequest{TargetURL: "example"}, status: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "unknown", code: "missing", body: controllers.UpdateShortLinkRequest{}, status: http.StatusNotFound, errCode: "SHORT_LINK_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("PUT", "/api/v1/admin/short-links/"+tt.code, tt.body, h.AdminToken())
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.target == "" {
+				return
+			}
+
+			var updated controllers.ShortLinkResponse
+			h.Decode(w, &updated)
+			if updated.Target != tt.target || updated.URL != utils.ShortLinkURL(h.Config.QRCode.BaseURL, link.Code) {
+				t.Fatalf("link = %+v, want target %q", updated, tt.target)
+			}
+		})
+	}
+}
+
+func TestScanReports(t *testing.T) {
+	h := testutil.New(t)
+	alice := h.CreateRecommendor(func(r *models.Recommendor) { r.Name = "alice" })
+	bob := h.CreateRecommendor(func(r *models.Recommendor) { r.Name = "bob" })
+	scan := func(recommendor *models.Recommendor, day, source string) {
+		at, _ := time.ParseInLocation("2006-01-02", day, time.Local)
+		h.DB.Create(&models.ScanEvent{ShortLinkID: 1, RecommendorID: recommendor.ID, Source: source, Day: day, ScannedAt: at})
+	}
+	scan(alice, "2024-03-01", utils.ScanSourceWeChat)
+	scan(bob, "2024-03-01", utils.ScanSourceWeChat)
+	scan(bob, "2024-03-03", utils.ScanSourceMobile)
+	scan(bob, "2024-03-09", utils.ScanSourceWeChat) // outside the range below
+
+	t.Run("per recommendor", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  string
+		}{
+			{query: "?from=2024-03-01&to=2024-03-07", want: fmt.Sprintf("[{%d bob 2} {%d alice 1}]", bob.ID, alice.ID)},
+			{query: "?from=2024-03-01&to=2024-03-07&source=mobile", want: fmt.Sprintf("[{%d bob 1}]", bob.ID)},
+			{query: "?from=2024-04-01&to=2024-04-07", want: "[]"},
+		}
+		for _, tt := range tests {
+			w := h.Do("GET", "/api/v1/admin/scans/recommendors"+tt.query, nil, h.AdminToken())
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d: %s", tt.query, w.Code, w.Body.String())
+			}
+			var rows []controllers.RecommendorScans
+			h.Decode(w, &rows)
+			if got := fmt.Sprint(rows); got != tt.want {
+				t.Fatalf("%s: got %s, want %s", tt.query, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("per day", func(t *testing.T) {
+		tests := []struct {
+			query string
+			want  string
+		}{
+			{query: "?from=2024-03-01&to=2024-03-04", want: "[{2024-03-01 2} {2024-03-02 0} {2024-03-03 1} {2024-03-04 0}]"},
+			{query: fmt.Sprintf("?from=2024-03-01&to=2024-03-03&recommendor_id=%d", alice.ID), want: "[{2024-03-01 1} {2024-03-02 0} {2024-03-03 0}]"},
+			{query: "?from=2024-03-01&to=2024-03-03&source=wechat", want: "[{2024-03-01 2} {2024-03-02 0} {2024-03-03 0}]"},
+		}
+		for _, tt := range tests {
+			w := h.Do("GET", "/api/v1/admin/scans/daily"+tt.query, nil, h.AdminToken())
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: status = %d: %s", tt.query, w.Code, w.Body.String())
+			}
+			var rows []controllers.DailyScans
+			h.Decode(w, &rows)
+			if got := fmt.Sprint(rows); got != tt.want {
+				t.Fatalf("%s: got %s, want %s", tt.query, got, tt.want)
+			}
+		}
+
+		// Without a range the report covers the last 30 days
+		w := h.Do("GET", "/api/v1/admin/scans/daily", nil, h.AdminToken())
+		var rows []controllers.DailyScans
+		h.Decode(w, &rows)
+		if len(rows) != 30 || rows[29].Day != time.Now().Format("2006-01-02") {
+			t.Fatalf("default range = %v", rows)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			query   string
+			errCode string
+		}{
+			{query: "?from=2024-03-05&to=2024-03-01", errCode: "INVALID_DATE_RANGE"},
+			{query: "?from=03/01/2024", errCode: "INVALID_DATE_RANGE"},
+			{query: "?from=2023-01-01&to=2024-03-01", errCode: "INVALID_DATE_RANGE"},
+			{query: "?source=fax", errCode: "VALIDATION_FAILED"},
+			{query: "?recommendor_id=abc", errCode: "INVALID_ID"},
+		}
+		for _, tt := range tests {
+			w := h.Do("GET", "/api/v1/admin/scans/daily"+tt.query, nil, h.AdminToken())
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("%s: status = %d: %s", tt.query, w.Code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("%s: error code = %q, want %q", tt.query, code, tt.errCode)
+			}
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"tourism_recommendor/config"
+	"tourism_recommendor/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SystemController handles operational and monitoring requests
+type SystemController struct{}
+
+// DatabaseStatsResponse represents the database connection pool statistics
+type DatabaseStatsResponse struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// GetDatabaseStats returns the database connection pool statistics
+// @Summary Get database pool statistics
+// @Description Retrieve connection pool statistics of the database for monitoring
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} DatabaseStatsResponse
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/system/database [get]
+func (sc *SystemController) GetDatabaseStats(c *gin.Context) {
+	stats, err := config.DBStats()
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	response.OK(c, DatabaseStatsResponse{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	})
+}
@@ -0,0 +1,50 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+)
+
+func TestGetDatabaseStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    models.AdminRole
+		token   bool
+		code    int
+		errCode string
+	}{
+		{name: "admin", role: models.AdminRoleAdmin, token: true, code: http.StatusOK},
+		{name: "super admin", role: models.AdminRoleSuperAdmin, token: true, code: http.StatusOK},
+		{name: "unauthenticated", code: http.StatusUnauthorized, errCode: "AUTH_HEADER_MISSING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			token := ""
+			if tt.token {
+				token = h.Token(h.CreateAdmin(func(a *models.Admin) { a.Role = tt.role }))
+			}
+
+			w := h.Do("GET", "/api/v1/admin/system/database", nil, token)
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code == http.StatusOK {
+				var stats controllers.DatabaseStatsResponse
+				h.Decode(w, &stats)
+				if stats.OpenConnections < 1 {
+					t.Fatalf("open connections = %d, want at least the test transaction", stats.OpenConnections)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"tourism_recommendor/logging"
+	"tourism_recommendor/middleware"
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TouristController signs in the tourists of the Mini Program
+type TouristController struct {
+	WeChat utils.WeChatClient // exchanges wx.login codes; nil when no AppSecret is configured
+}
+
+// NewTouristController creates a tourist controller
+func NewTouristController(wechat utils.WeChatClient) *TouristController {
+	return &TouristController{WeChat: wechat}
+}
+
+// WxappLoginRequest carries the code of a wx.login call
+type WxappLoginRequest struct {
+	Code string `json:"code" binding:"required,max=128"`
+}
+
+// WxappLoginResponse is the token of a signed in tourist
+type WxappLoginResponse struct {
+	Token     string         `json:"token"` // tourist token, refused by the admin endpoints
+	ExpiresAt int64          `json:"expires_at"`
+	Tourist   models.Tourist `json:"tourist"`
+	Created   bool           `json:"created"` // first sign in of the tourist
+}
+
+// WxappLogin signs in a Mini Program user as a tourist
+// @Summary Mini Program login
+// @Description Exchange the code of a wx.login call for a tourist token through WeChat's jscode2session API. The first sign in
+// @Description of a WeChat user creates the tourist. Tourist tokens are only accepted by the tourist endpoints.
+// @Tags wxapp
+// @Accept json
+// @Produce json
+// @Param request body WxappLoginRequest true "wx.login code"
+// @Success 200 {object} response.MessageBody{data=WxappLoginResponse}
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Failure 503 {object} response.Body
+// @Router /api/v1/wxapp/login [post]
+func (tc *TouristController) WxappLogin(c *gin.Context) {
+	var req WxappLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err)
+		return
+	}
+	if tc.WeChat == nil {
+		response.Error(c, response.ErrWxappUnavailable)
+		return
+	}
+
+	logger := logging.FromContext(c.Request.Context())
+	session, err := tc.WeChat.Code2Session(c.Request.Context(), req.Code)
+	var apiErr *utils.WeChatError
+	if errors.As(err, &apiErr) && apiErr.CodeRejected() {
+		logger.Warn("wxapp login failed: code rejected", "errcode", apiErr.Code)
+		response.Error(c, response.ErrWxappCodeInvalid)
+		return
+	}
+	if err != nil {
+		logger.Error("wxapp login failed: WeChat session error", "error", err)
+		response.Error(c, response.ErrWxappUnavailable.WithCause(err))
+		return
+	}
+
+	tourist, created, err := signInTourist(dbWithContext(c), session)
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	token, err := utils.GenerateTouristToken(tourist.ID)
+	if err != nil {
+		response.Error(c, response.ErrInternal.WithCause(err))
+		return
+	}
+
+	logger.Info("wxapp login successful", "tourist_id", tourist.ID, "created", created)
+	response.Message(c, "message.wxapp.login_success", WxappLoginResponse{
+		Token:     token,
+		ExpiresAt: time.Now().Add(utils.TouristTokenExpiration).Unix(),
+		Tourist:   tourist,
+		Created:   created,
+	})
+}
+
+// GetCurrentTourist returns the signed in tourist
+// @Summary Get current tourist
+// @Description Retrieve the tourist a Mini Program token was issued to
+// @Tags wxapp
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.Tourist
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/wxapp/me [get]
+func (tc *TouristController) GetCurrentTourist(c *gin.Context) {
+	touristID, err := middleware.GetTouristID(c)
+	if err != nil {
+		response.Error(c, response.ErrUnauthorized)
+		return
+	}
+
+	var tourist models.Tourist
+	if err := dbWithContext(c).First(&tourist, touristID).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrUserNotFound))
+		return
+	}
+	response.OK(c, tourist)
+}
+
+// signInTourist finds or creates the tourist of a WeChat session and records the sign in.
+// It reports whether the tourist was created.
+func signInTourist(db *gorm.DB, session *utils.WeChatSession) (models.Tourist, bool, error) {
+	now := time.Now()
+	// Concurrent first sign ins of the same user create one tourist
+	created := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Tourist{OpenID: session.OpenID, UnionID: session.UnionID, LastLoginAt: &now})
+	if created.Error != nil {
+		return models.Tourist{}, false, created.Error
+	}
+
+	var tourist models.Tourist
+	if err := db.Where("open_id = ?", session.OpenID).First(&tourist).Error; err != nil {
+		return models.Tourist{}, false, err
+	}
+	if created.RowsAffected == 1 {
+		return tourist, true, nil
+	}
+
+	tourist.LastLoginAt = &now
+	if session.UnionID != "" {
+		tourist.UnionID = session.UnionID
+	}
+	if err := db.Model(&tourist).Select("last_login_at", "union_id").Updates(&tourist).Error; err != nil {
+		return models.Tourist{}, false, err
+	}
+	return tourist, false, nil
+}
@@ -0,0 +1,129 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+)
+
+// wxappLogin signs in the Mini Program user openID and returns the login response
+func wxappLogin(t *testing.T, h *testutil.Harness, openID string) controllers.WxappLoginResponse {
+	t.Helper()
+
+	w := h.Do("POST", "/api/v1/wxapp/login", controllers.WxappLoginRequest{Code: h.WeChat.LoginCode(openID)}, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var login controllers.WxappLoginResponse
+	h.Decode(w, &login)
+	return login
+}
+
+func TestWxappLogin(t *testing.T) {
+	h := testutil.New(t)
+
+	t.Run("first and later sign ins", func(t *testing.T) {
+		first := wxappLogin(t, h, "openid-alice")
+		if !first.Created || first.Token == "" || first.Tourist.ID == 0 || first.Tourist.LastLoginAt == nil {
+			t.Fatalf("first login = %+v", first)
+		}
+		again := wxappLogin(t, h, "openid-alice")
+		if again.Created || again.Tourist.ID != first.Tourist.ID {
+			t.Fatalf("second login = %+v, want tourist %d again", again, first.Tourist.ID)
+		}
+		other := wxappLogin(t, h, "openid-bob")
+		if !other.Created || other.Tourist.ID == first.Tourist.ID {
+			t.Fatalf("other login = %+v, want a new tourist", other)
+		}
+
+		var count int64
+		h.DB.Model(&models.Tourist{}).Count(&count)
+		if count != 2 {
+			t.Fatalf("%d tourists, want 2", count)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		setup   func() string // returns the code to sign in with
+		status  int
+		errCode string
+	}{
+		{name: "missing code", setup: func() string { return "" }, status: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "unknown code", setup: func() string { return "forged" }, status: http.StatusUnauthorized, errCode: "WXAPP_CODE_INVALID"},
+		{
+			name: "code used twice",
+			setup: func() string {
+				code := h.WeChat.LoginCode("openid-carol")
+				h.Do("POST", "/api/v1/wxapp/login", controllers.WxappLoginRequest{Code: code}, "")
+				return code
+			},
+			status:  http.StatusUnauthorized,
+			errCode: "WXAPP_CODE_INVALID",
+		},
+		{
+			name: "rate limited",
+			setup: func() string {
+				h.WeChat.FailWithCode(testutil.WeChatSessionPath, 1, 45011)
+				return h.WeChat.LoginCode("openid-dave")
+			},
+			status:  http.StatusServiceUnavailable,
+			errCode: "WXAPP_LOGIN_UNAVAILABLE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("POST", "/api/v1/wxapp/login", controllers.WxappLoginRequest{Code: tt.setup()}, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+		})
+	}
+}
+
+func TestTouristAndAdminTokens(t *testing.T) {
+	h := testutil.New(t)
+	tourist := wxappLogin(t, h, "openid-alice").Token
+	admin := h.AdminToken()
+
+	tests := []struct {
+		name    string
+		path    string
+		token   string
+		status  int
+		errCode string
+	}{
+		{name: "tourist profile", path: "/api/v1/wxapp/me", token: tourist, status: http.StatusOK},
+		{name: "tourist profile with admin token", path: "/api/v1/wxapp/me", token: admin, status: http.StatusForbidden, errCode: "TOURIST_REQUIRED"},
+		{name: "tourist profile without token", path: "/api/v1/wxapp/me", status: http.StatusUnauthorized, errCode: "AUTH_HEADER_MISSING"},
+		{name: "tourist profile with forged token", path: "/api/v1/wxapp/me", token: "forged", status: http.StatusUnauthorized, errCode: "TOKEN_INVALID"},
+		{name: "admin profile with tourist token", path: "/api/v1/auth/me", token: tourist, status: http.StatusForbidden, errCode: "ADMIN_REQUIRED"},
+		{name: "admin list with tourist token", path: "/api/v1/admin/recommendors", token: tourist, status: http.StatusForbidden, errCode: "ADMIN_REQUIRED"},
+		{name: "admin list", path: "/api/v1/admin/recommendors", token: admin, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("GET", tt.path, nil, tt.token)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+		})
+	}
+
+	// A tourist token cannot be refreshed into an admin token
+	w := h.Do("POST", "/api/v1/auth/refresh-token", nil, tourist)
+	if w.Code != http.StatusUnauthorized || h.ErrorCode(w) != "TOKEN_INVALID" {
+		t.Fatalf("refresh with tourist token: status = %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,431 @@
+package controllers
+
+import (
+	"strconv"
+
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/middleware"
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TranslationController handles per-locale translations of recommender and destination content
+type TranslationController struct{}
+
+// RecommendorTranslationRequest holds the translated fields of a recommender.
+// Omitted fields are left unchanged; an empty string removes the translation.
+type RecommendorTranslationRequest struct {
+	Bio *string `json:"bio"`
+}
+
+// DestinationTranslationRequest holds the translated fields of a destination.
+// Omitted fields are left unchanged; an empty string removes the translation.
+type DestinationTranslationRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// TranslationsResponse lists the translations of an entity grouped by locale and field
+type TranslationsResponse struct {
+	EntityType   string                       `json:"entity_type"`
+	EntityID     uint                         `json:"entity_id"`
+	SourceLocale string                       `json:"source_locale"`
+	Translations map[string]map[string]string `json:"translations"`
+}
+
+// GetRecommendorTranslations lists the translations of a recommender
+// @Summary Get recommender translations
+// @Description List the translated bio of a recommender for every locale
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Recommendor ID"
+// @Success 200 {object} TranslationsResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/{id}/translations [get]
+func (tc *TranslationController) GetRecommendorTranslations(c *gin.Context) {
+	tc.getTranslations(c, models.TranslationEntityRecommendor, &models.Recommendor{}, response.ErrRecommendorNotFound)
+}
+
+// UpdateRecommendorTranslation creates or updates the translation of a recommender for a locale
+// @Summary Update recommender translation
+// @Description Set the translated bio of a recommender for a locale
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Recommendor ID"
+// @Param locale path string true "Locale (e.g. en)"
+// @Param translation body RecommendorTranslationRequest true "Translated fields"
+// @Success 200 {object} TranslationsResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/{id}/translations/{locale} [put]
+func (tc *TranslationController) UpdateRecommendorTranslation(c *gin.Context) {
+	var req RecommendorTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err)
+		return
+	}
+
+	tc.updateTranslation(c, models.TranslationEntityRecommendor, &models.Recommendor{}, response.ErrRecommendorNotFound,
+		map[string]*string{"bio": req.Bio})
+}
+
+// DeleteRecommendorTranslation removes every translated field of a recommender for a locale
+// @Summary Delete recommender translation
+// @Description Remove the translation of a recommender for a locale
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Recommendor ID"
+// @Param locale path string true "Locale (e.g. en)"
+// @Success 200 {object} TranslationsResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/recommendors/{id}/translations/{locale} [delete]
+func (tc *TranslationController) DeleteRecommendorTranslation(c *gin.Context) {
+	tc.deleteTranslation(c, models.TranslationEntityRecommendor, &models.Recommendor{}, response.ErrRecommendorNotFound)
+}
+
+// GetDestinationTranslations lists the translations of a destination
+// @Summary Get destination translations
+// @Description List the translated name and description of a destination for every locale
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Destination ID"
+// @Success 200 {object} TranslationsResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/destinations/{id}/translations [get]
+func (tc *TranslationController) GetDestinationTranslations(c *gin.Context) {
+	tc.getTranslations(c, models.TranslationEntityDestination, &models.Destination{}, response.ErrDestinationNotFound)
+}
+
+// UpdateDestinationTranslation creates or updates the translation of a destination for a locale
+// @Summary Update destination translation
+// @Description Set the translated name and description of a destination for a locale
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Destination ID"
+// @Param locale path string true "Locale (e.g. en)"
+// @Param translation body DestinationTranslationRequest true "Translated fields"
+// @Success 200 {object} TranslationsResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/destinations/{id}/translations/{locale} [put]
+func (tc *TranslationController) UpdateDestinationTranslation(c *gin.Context) {
+	var req DestinationTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err)
+		return
+	}
+
+	tc.updateTranslation(c, models.TranslationEntityDestination, &models.Destination{}, response.ErrDestinationNotFound,
+		map[string]*string{"name": req.Name, "description": req.Description})
+}
+
+// DeleteDestinationTranslation removes every translated field of a destination for a locale
+// @Summary Delete destination translation
+// @Description Remove the translation of a destination for a locale
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Destination ID"
+// @Param locale path string true "Locale (e.g. en)"
+// @Success 200 {object} TranslationsResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/destinations/{id}/translations/{locale} [delete]
+func (tc *TranslationController) DeleteDestinationTranslation(c *gin.Context) {
+	tc.deleteTranslation(c, models.TranslationEntityDestination, &models.Destination{}, response.ErrDestinationNotFound)
+}
+
+// getTranslations responds with every translation of the entity
+func (tc *TranslationController) getTranslations(c *gin.Context, entityType string, model interface{}, notFound *response.APIError) {
+	id, ok := findTranslatableEntity(c, model, notFound)
+	if !ok {
+		return
+	}
+
+	respondTranslations(c, entityType, id)
+}
+
+// updateTranslation upserts the non-nil fields for the locale in the path
+func (tc *TranslationController) updateTranslation(c *gin.Context, entityType string, model interface{}, notFound *response.APIError, values map[string]*string) {
+	locale, ok := translationLocale(c)
+	if !ok {
+		return
+	}
+
+	id, ok := findTranslatableEntity(c, model, notFound)
+	if !ok {
+		return
+	}
+
+	err := dbWithContext(c).Transaction(func(tx *gorm.DB) error {
+		for field, value := range values {
+			if value == nil {
+				continue
+			}
+
+			entry := tx.Where("entity_type = ? AND entity_id = ? AND locale = ? AND field = ?", entityType, id, locale, field)
+			if *value == "" {
+				if err := entry.Delete(&models.Translation{}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			translation := models.Translation{
+				EntityType: entityType,
+				EntityID:   id,
+				Locale:     locale,
+				Field:      field,
+				Value:      *value,
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "entity_type"}, {Name: "entity_id"}, {Name: "locale"}, {Name: "field"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+			}).Create(&translation).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	respondTranslations(c, entityType, id)
+}
+
+// deleteTranslation removes every field of the entity for the locale in the path
+func (tc *TranslationController) deleteTranslation(c *gin.Context, entityType string, model interface{}, notFound *response.APIError) {
+	locale, ok := translationLocale(c)
+	if !ok {
+		return
+	}
+
+	id, ok := findTranslatableEntity(c, model, notFound)
+	if !ok {
+		return
+	}
+
+	result := dbWithContext(c).
+		Where("entity_type = ? AND entity_id = ? AND locale = ?", entityType, id, locale).
+		Delete(&models.Translation{})
+	if result.Error != nil {
+		response.Error(c, response.ErrDatabase.WithCause(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.Error(c, response.ErrTranslationNotFound)
+		return
+	}
+
+	respondTranslations(c, entityType, id)
+}
+
+// deleteTranslations removes every translation of the entity; callers run it in the
+// transaction that deletes the entity itself
+func deleteTranslations(tx *gorm.DB, entityType string, id uint) error {
+	return tx.Where("entity_type = ? AND entity_id = ?", entityType, id).Delete(&models.Translation{}).Error
+}
+
+// findTranslatableEntity parses the :id parameter and checks that the entity exists
+func findTranslatableEntity(c *gin.Context, model interface{}, notFound *response.APIError) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.Error(c, response.ErrInvalidID)
+		return 0, false
+	}
+
+	if err := dbWithContext(c).Select("id").First(model, id).Error; err != nil {
+		response.Error(c, lookupError(err, notFound))
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+// translationLocale validates the :locale parameter; the source locale lives on the record itself
+func translationLocale(c *gin.Context) (string, bool) {
+	locale := c.Param("locale")
+	if !i18n.IsSupported(locale) {
+		response.Error(c, response.ErrUnsupportedLocale.WithDetail("supported", i18n.Locales()))
+		return "", false
+	}
+	if locale == i18n.SourceLocale {
+		response.Error(c, response.ErrTranslationSourceLocale)
+		return "", false
+	}
+	return locale, true
+}
+
+// respondTranslations responds with the translations of the entity grouped by locale
+func respondTranslations(c *gin.Context, entityType string, id uint) {
+	var translations []models.Translation
+	if err := dbWithContext(c).
+		Where("entity_type = ? AND entity_id = ?", entityType, id).
+		Order("locale, field").
+		Find(&translations).Error; err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	grouped := make(map[string]map[string]string)
+	for _, t := range translations {
+		if grouped[t.Locale] == nil {
+			grouped[t.Locale] = make(map[string]string)
+		}
+		grouped[t.Locale][t.Field] = t.Value
+	}
+
+	response.OK(c, TranslationsResponse{
+		EntityType:   entityType,
+		EntityID:     id,
+		SourceLocale: i18n.SourceLocale,
+		Translations: grouped,
+	})
+}
+
+// contentLocale returns the locale to translate content into, or "" when the route
+// serves content as stored (admin routes) or the request asks for the source locale
+func contentLocale(c *gin.Context) string {
+	if !c.GetBool(middleware.LocalizedContentKey) {
+		return ""
+	}
+
+	locale := response.Locale(c)
+	if locale == i18n.SourceLocale {
+		return ""
+	}
+	return locale
+}
+
+// loadTranslations returns the translated fields of the entities in the locale, keyed by entity ID
+func loadTranslations(c *gin.Context, entityType string, ids []uint, locale string) (map[uint]map[string]string, error) {
+	result := make(map[uint]map[string]string)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var translations []models.Translation
+	if err := dbWithContext(c).
+		Where("entity_type = ? AND entity_id IN ? AND locale = ?", entityType, ids, locale).
+		Find(&translations).Error; err != nil {
+		return nil, err
+	}
+
+	for _, t := range translations {
+		if result[t.EntityID] == nil {
+			result[t.EntityID] = make(map[string]string)
+		}
+		result[t.EntityID][t.Field] = t.Value
+	}
+
+	return result, nil
+}
+
+// localizeRecommendors replaces translatable fields (and those of preloaded destinations)
+// with their translation in the request locale, keeping the stored value as fallback
+func localizeRecommendors(c *gin.Context, recommendors []models.Recommendor) error {
+	locale := contentLocale(c)
+	if locale == "" || len(recommendors) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(recommendors))
+	for _, r := range recommendors {
+		ids = append(ids, r.ID)
+	}
+
+	translations, err := loadTranslations(c, models.TranslationEntityRecommendor, ids, locale)
+	if err != nil {
+		return err
+	}
+
+	for i := range recommendors {
+		if bio, ok := translations[recommendors[i].ID]["bio"]; ok {
+			recommendors[i].Bio = bio
+		}
+		if err := localizeDestinations(c, recommendors[i].Destinations); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// localizeDestinations replaces translatable fields (and the preloaded recommender's bio)
+// with their translation in the request locale, keeping the stored value as fallback
+func localizeDestinations(c *gin.Context, destinations []models.Destination) error {
+	locale := contentLocale(c)
+	if locale == "" || len(destinations) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(destinations))
+	recommendorIDs := make([]uint, 0, len(destinations))
+	for _, d := range destinations {
+		ids = append(ids, d.ID)
+		if d.Recommendor != nil {
+			recommendorIDs = append(recommendorIDs, d.Recommendor.ID)
+		}
+	}
+
+	translations, err := loadTranslations(c, models.TranslationEntityDestination, ids, locale)
+	if err != nil {
+		return err
+	}
+
+	recommendorTranslations, err := loadTranslations(c, models.TranslationEntityRecommendor, recommendorIDs, locale)
+	if err != nil {
+		return err
+	}
+
+	for i := range destinations {
+		fields := translations[destinations[i].ID]
+		if name, ok := fields["name"]; ok {
+			destinations[i].Name = name
+		}
+		if description, ok := fields["description"]; ok {
+			destinations[i].Description = description
+		}
+		if r := destinations[i].Recommendor; r != nil {
+			if bio, ok := recommendorTranslations[r.ID]["bio"]; ok {
+				r.Bio = bio
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+)
+
+func TestUpdateTranslation(t *testing.T) {
+	bio := "Senior guide"
+	name := "Forbidden City"
+
+	tests := []struct {
+		name    string
+		path    string
+		body    interface{}
+		code    int
+		errCode string
+		want    map[string]string
+	}{
+		{name: "recommendor bio", path: "/api/v1/admin/recommendors/%d/translations/en", body: controllers.RecommendorTranslationRequest{Bio: &bio}, code: http.StatusOK, want: map[string]string{"bio": bio}},
+		{name: "destination name", path: "/api/v1/admin/destinations/%d/translations/en", body: controllers.DestinationTranslationRequest{Name: &name}, code: http.StatusOK, want: map[string]string{"name": name}},
+		{name: "source locale", path: "/api/v1/admin/recommendors/%d/translations/zh-CN", body: controllers.RecommendorTranslationRequest{Bio: &bio}, code: http.StatusBadRequest, errCode: "TRANSLATION_SOURCE_LOCALE"},
+		{name: "unsupported locale", path: "/api/v1/admin/recommendors/%d/translations/fr", body: controllers.RecommendorTranslationRequest{Bio: &bio}, code: http.StatusBadRequest, errCode: "UNSUPPORTED_LOCALE"},
+		{name: "unknown entity", path: "/api/v1/admin/destinations/1%d/translations/en", body: controllers.DestinationTranslationRequest{Name: &name}, code: http.StatusNotFound, errCode: "DESTINATION_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			destination := h.CreateDestination(recommendor)
+
+			id := recommendor.ID
+			if _, ok := tt.body.(controllers.DestinationTranslationRequest); ok {
+				id = destination.ID
+			}
+			w := h.Do("PUT", fmt.Sprintf(tt.path, id), tt.body, h.AdminToken())
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code == http.StatusOK {
+				var resp controllers.TranslationsResponse
+				h.Decode(w, &resp)
+				if fmt.Sprint(resp.Translations["en"]) != fmt.Sprint(tt.want) {
+					t.Fatalf("translations = %v, want en = %v", resp.Translations, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteTranslation(t *testing.T) {
+	tests := []struct {
+		name      string
+		translate bool
+		code      int
+		errCode   string
+	}{
+		{name: "existing translation", translate: true, code: http.StatusOK},
+		{name: "missing translation", code: http.StatusNotFound, errCode: "TRANSLATION_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			if tt.translate {
+				translateBio(t, h, recommendor, "Senior guide")
+			}
+
+			w := h.Do("DELETE", fmt.Sprintf("/api/v1/admin/recommendors/%d/translations/en", recommendor.ID), nil, h.AdminToken())
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+
+			var count int64
+			h.DB.Model(&models.Translation{}).Where("entity_id = ?", recommendor.ID).Count(&count)
+			if count != 0 {
+				t.Fatalf("%d translations left", count)
+			}
+		})
+	}
+}
+
+func TestLocalizedContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		language string
+		want     string
+	}{
+		{name: "english", path: "/api/v1/recommendors/%d", language: "en", want: "Senior guide"},
+		{name: "source locale", path: "/api/v1/recommendors/%d", language: "zh-CN", want: "资深导游"},
+		{name: "admin routes serve stored content", path: "/api/v1/admin/recommendors/%d", language: "en", want: "资深导游"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			translateBio(t, h, recommendor, "Senior guide")
+
+			req := httptest.NewRequest("GET", fmt.Sprintf(tt.path, recommendor.ID), nil)
+			req.Header.Set("Accept-Language", tt.language)
+			req.Header.Set("Authorization", "Bearer "+h.AdminToken())
+			w := h.Serve(req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			var got models.Recommendor
+			h.Decode(w, &got)
+			if got.Bio != tt.want {
+				t.Fatalf("bio = %q, want %q", got.Bio, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizedContentIgnoresDefaultLocale(t *testing.T) {
+	i18n.SetDefaultLocale(i18n.LocaleEN)
+	t.Cleanup(func() { i18n.SetDefaultLocale(i18n.LocaleZH) })
+
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+	translateBio(t, h, recommendor, "Senior guide")
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/v1/recommendors/%d", recommendor.ID), nil)
+	req.Header.Set("Accept-Language", "en")
+	w := h.Serve(req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var got models.Recommendor
+	h.Decode(w, &got)
+	if got.Bio != "Senior guide" {
+		t.Fatalf("bio = %q, want the English translation", got.Bio)
+	}
+}
+
+func TestDeleteEntityRemovesTranslations(t *testing.T) {
+	tests := []struct {
+		name       string
+		entityType string
+		path       string
+	}{
+		{name: "recommendor", entityType: models.TranslationEntityRecommendor, path: "/api/v1/admin/recommendors/%d"},
+		{name: "destination", entityType: models.TranslationEntityDestination, path: "/api/v1/admin/destinations/%d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+			recommendor := h.CreateRecommendor()
+			destination := h.CreateDestination(recommendor)
+			ids := map[string]uint{
+				models.TranslationEntityRecommendor: recommendor.ID,
+				models.TranslationEntityDestination: destination.ID,
+			}
+			for entityType, id := range ids {
+				translation := models.Translation{EntityType: entityType, EntityID: id, Locale: "en", Field: "name", Value: "Translated"}
+				if err := h.DB.Create(&translation).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := h.Do("DELETE", fmt.Sprintf(tt.path, ids[tt.entityType]), nil, h.AdminToken())
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+
+			for entityType, id := range ids {
+				var count int64
+				h.DB.Model(&models.Translation{}).Where("entity_type = ? AND entity_id = ?", entityType, id).Count(&count)
+				want := int64(1)
+				if entityType == tt.entityType {
+					want = 0
+				}
+				if count != want {
+					t.Fatalf("%s translations = %d, want %d", entityType, count, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetTranslations(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+	translateBio(t, h, recommendor, "Senior guide")
+
+	w := h.Do("GET", fmt.Sprintf("/api/v1/admin/recommendors/%d/translations", recommendor.ID), nil, h.AdminToken())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp controllers.TranslationsResponse
+	h.Decode(w, &resp)
+	if resp.EntityID != recommendor.ID || resp.SourceLocale != "zh-CN" || resp.Translations["en"]["bio"] != "Senior guide" {
+		t.Fatalf("unexpected translations: %+v", resp)
+	}
+}
+
+// translateBio stores an English bio for the recommendor
+func translateBio(t *testing.T, h *testutil.Harness, recommendor *models.Recommendor, bio string) {
+	t.Helper()
+	translation := models.Translation{
+		EntityType: models.TranslationEntityRecommendor,
+		EntityID:   recommendor.ID,
+		Locale:     "en",
+		Field:      "bio",
+		Value:      bio,
+	}
+	if err := h.DB.Create(&translation).Error; err != nil {
+		t.Fatal(err)
+	}
+}
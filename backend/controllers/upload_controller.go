@@ -2,9 +2,10 @@ package controllers
 
 import (
 	"fmt"
-	"net/http"
 	"strings"
 
+	"tourism_recommendor/metrics"
+	"tourism_recommendor/response"
 	"tourism_recommendor/utils"
 
 	"github.com/gin-gonic/gin"
@@ -20,27 +21,22 @@ type UploadController struct{}
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Avatar image file"
-// @Success 200 {object} utils.UploadResult
-// @Failure 400 {object} map[string]string
-// @Failure 413 {object} map[string]string
-// @Failure 500 {object} map[string]string
-// @Router /api/upload/avatar [post]
+// @Success 200 {object} response.MessageBody{data=utils.UploadResult}
+// @Failure 400 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/upload/avatar [post]
 func (uc *UploadController) UploadAvatar(c *gin.Context) {
 	// Get file from form
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No file uploaded or invalid file",
-		})
+		response.Error(c, response.ErrFileMissing.WithCause(err))
 		return
 	}
 
 	// Open file
 	file, err := fileHeader.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to open file",
-		})
+		response.Error(c, response.ErrInternal.WithCause(err))
 		return
 	}
 	defer file.Close()
@@ -48,24 +44,21 @@ func (uc *UploadController) UploadAvatar(c *gin.Context) {
 	// Validate and save avatar
 	result, err := utils.ValidateAvatar(fileHeader, file)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		response.Error(c, response.ErrFileRejected.WithDetail("reason", err.Error()))
 		return
 	}
 
+	metrics.AddUploadBytes("avatar", result.FileSize)
+
 	// Construct full URL
 	fullURL := getFullURL(c, result.URL)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "File uploaded successfully",
-		"data": gin.H{
-			"file_name":    result.FileName,
-			"file_path":    result.FilePath,
-			"file_size":    result.FileSize,
-			"content_type": result.ContentType,
-			"url":          fullURL,
-		},
+	response.Message(c, "message.upload.success", gin.H{
+		"file_name":    result.FileName,
+		"file_path":    result.FilePath,
+		"file_size":    result.FileSize,
+		"content_type": result.ContentType,
+		"url":          fullURL,
 	})
 }
 
@@ -76,27 +69,22 @@ func (uc *UploadController) UploadAvatar(c *gin.Context) {
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Image file"
-// @Success 200 {object} utils.UploadResult
-// @Failure 400 {object} map[string]string
-// @Failure 413 {object} map[string]string
-// @Failure 500 {object} map[string]string
-// @Router /api/upload/image [post]
+// @Success 200 {object} response.MessageBody{data=utils.UploadResult}
+// @Failure 400 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/upload/image [post]
 func (uc *UploadController) UploadImage(c *gin.Context) {
 	// Get file from form
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No file uploaded or invalid file",
-		})
+		response.Error(c, response.ErrFileMissing.WithCause(err))
 		return
 	}
 
 	// Open file
 	file, err := fileHeader.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to open file",
-		})
+		response.Error(c, response.ErrInternal.WithCause(err))
 		return
 	}
 	defer file.Close()
@@ -105,31 +93,28 @@ func (uc *UploadController) UploadImage(c *gin.Context) {
 	config := utils.UploadConfig{
 		AllowedTypes: utils.AllowedImageTypes,
 		MaxSize:      int64(utils.MaxFileSize),
-		Directory:    "./uploads/images",
+		Directory:    utils.ImageDir,
 		GenerateName: true,
 	}
 
 	// Validate and save image
 	result, err := utils.ValidateAndSaveFile(fileHeader, file, config)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		response.Error(c, response.ErrFileRejected.WithDetail("reason", err.Error()))
 		return
 	}
 
+	metrics.AddUploadBytes("image", result.FileSize)
+
 	// Construct full URL
 	fullURL := getFullURL(c, result.URL)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "File uploaded successfully",
-		"data": gin.H{
-			"file_name":    result.FileName,
-			"file_path":    result.FilePath,
-			"file_size":    result.FileSize,
-			"content_type": result.ContentType,
-			"url":          fullURL,
-		},
+	response.Message(c, "message.upload.success", gin.H{
+		"file_name":    result.FileName,
+		"file_path":    result.FilePath,
+		"file_size":    result.FileSize,
+		"content_type": result.ContentType,
+		"url":          fullURL,
 	})
 }
 
@@ -140,27 +125,22 @@ func (uc *UploadController) UploadImage(c *gin.Context) {
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "Document file"
-// @Success 200 {object} utils.UploadResult
-// @Failure 400 {object} map[string]string
-// @Failure 413 {object} map[string]string
-// @Failure 500 {object} map[string]string
-// @Router /api/upload/document [post]
+// @Success 200 {object} response.MessageBody{data=utils.UploadResult}
+// @Failure 400 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/upload/document [post]
 func (uc *UploadController) UploadDocument(c *gin.Context) {
 	// Get file from form
 	fileHeader, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No file uploaded or invalid file",
-		})
+		response.Error(c, response.ErrFileMissing.WithCause(err))
 		return
 	}
 
 	// Open file
 	file, err := fileHeader.Open()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to open file",
-		})
+		response.Error(c, response.ErrInternal.WithCause(err))
 		return
 	}
 	defer file.Close()
@@ -168,24 +148,21 @@ func (uc *UploadController) UploadDocument(c *gin.Context) {
 	// Validate and save document
 	result, err := utils.ValidateDocument(fileHeader, file)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		response.Error(c, response.ErrFileRejected.WithDetail("reason", err.Error()))
 		return
 	}
 
+	metrics.AddUploadBytes("document", result.FileSize)
+
 	// Construct full URL
 	fullURL := getFullURL(c, result.URL)
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "File uploaded successfully",
-		"data": gin.H{
-			"file_name":    result.FileName,
-			"file_path":    result.FilePath,
-			"file_size":    result.FileSize,
-			"content_type": result.ContentType,
-			"url":          fullURL,
-		},
+	response.Message(c, "message.upload.success", gin.H{
+		"file_name":    result.FileName,
+		"file_path":    result.FilePath,
+		"file_size":    result.FileSize,
+		"content_type": result.ContentType,
+		"url":          fullURL,
 	})
 }
 
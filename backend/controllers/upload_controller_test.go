@@ -0,0 +1,79 @@
+package controllers_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"testing"
+
+	"tourism_recommendor/testutil"
+	"tourism_recommendor/utils"
+)
+
+func TestUpload(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		size        int
+		code        int
+		errCode     string
+	}{
+		{name: "avatar", path: "/api/v1/upload/avatar", contentType: "image/png", size: 128, code: http.StatusOK},
+		{name: "image", path: "/api/v1/upload/image", contentType: "image/jpeg", size: 128, code: http.StatusOK},
+		{name: "document", path: "/api/v1/upload/document", contentType: "application/pdf", size: 128, code: http.StatusOK},
+		{name: "avatar of wrong type", path: "/api/v1/upload/avatar", contentType: "application/pdf", size: 128, code: http.StatusBadRequest, errCode: "FILE_REJECTED"},
+		{name: "avatar too large", path: "/api/v1/upload/avatar", contentType: "image/png", size: utils.MaxAvatarSize + 1, code: http.StatusBadRequest, errCode: "FILE_REJECTED"},
+		{name: "missing file", path: "/api/v1/upload/image", code: http.StatusBadRequest, errCode: "FILE_MISSING"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := testutil.New(t)
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			if tt.contentType != "" {
+				header := textproto.MIMEHeader{}
+				header.Set("Content-Disposition", `form-data; name="file"; filename="upload.bin"`)
+				header.Set("Content-Type", tt.contentType)
+				part, err := writer.CreatePart(header)
+				if err != nil {
+					t.Fatal(err)
+				}
+				part.Write(bytes.Repeat([]byte{0}, tt.size))
+			}
+			writer.Close()
+
+			req := httptest.NewRequest("POST", tt.path, &body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			w := h.Serve(req)
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.code, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			var result struct {
+				FilePath string `json:"file_path"`
+				FileSize int64  `json:"file_size"`
+			}
+			h.Decode(w, &result)
+			info, err := os.Stat(result.FilePath)
+			if err != nil {
+				t.Fatalf("uploaded file not saved: %v", err)
+			}
+			if info.Size() != int64(tt.size) || result.FileSize != int64(tt.size) {
+				t.Fatalf("saved %d bytes, reported %d, want %d", info.Size(), result.FileSize, tt.size)
+			}
+		})
+	}
+}
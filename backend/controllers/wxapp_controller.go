@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// wxappSceneSortFields are the columns clients may sort Mini Program scenes by
+var wxappSceneSortFields = []string{"id", "target", "recommendor_id", "destination_id", "created_at"}
+
+// WxappSceneFilters are the filters accepted by the Mini Program scene list
+var WxappSceneFilters = utils.FilterSpec{
+	{Name: "target", Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Values: i18n.EnumValues("scene_target"), Description: "Page the scene opens"},
+	{Name: "recommendor_id", Type: utils.FilterInt, Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Description: "Recommendor ID"},
+	{Name: "destination_id", Type: utils.FilterInt, Ops: []utils.FilterOp{utils.OpEq, utils.OpIn}, Description: "Destination ID"},
+	{Name: "campaign", Ops: []utils.FilterOp{utils.OpEq, utils.OpLike}, Description: "Campaign"},
+}
+
+// WxappController handles the scenes of Mini Program codes
+type WxappController struct {
+	Recommendors *RecommendorController // draws the Mini Program codes
+}
+
+// NewWxappController creates a Mini Program controller
+func NewWxappController(recommendors *RecommendorController) *WxappController {
+	return &WxappController{Recommendors: recommendors}
+}
+
+// CreateWxappSceneRequest describes the page a new Mini Program scene opens
+type CreateWxappSceneRequest struct {
+	Target        string `json:"target" binding:"required,oneof=recommendor destination"`
+	RecommendorID uint   `json:"recommendor_id" binding:"required_if=Target recommendor"` // taken from the destination for destination scenes
+	DestinationID uint   `json:"destination_id" binding:"required_if=Target destination"`
+	Campaign      string `json:"campaign" binding:"omitempty,max=50"` // tells apart codes of the same page, e.g. per poster
+}
+
+// WxappSceneResponse is a scene with the Mini Program page it opens
+type WxappSceneResponse struct {
+	models.WxappScene
+	Page   string            `json:"page"`   // Mini Program page, e.g. pages/recommendor/detail
+	Params map[string]string `json:"params"` // query parameters of the page
+}
+
+// ResolveWxappScene resolves the scene of a scanned Mini Program code
+// @Summary Resolve Mini Program scene
+// @Description Resolve the scene token a Mini Program code carries to the page it opens and the parameters of the page.
+// @Description The Mini Program reads the token from the scene option of the page, decoded with decodeURIComponent.
+// @Tags wxapp
+// @Produce json
+// @Param token path string true "Scene token"
+// @Success 200 {object} WxappSceneResponse
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/wxapp/scene/{token} [get]
+func (wc *WxappController) ResolveWxappScene(c *gin.Context) {
+	var scene models.WxappScene
+	if err := dbWithContext(c).Where("token = ?", c.Param("token")).First(&scene).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrSceneNotFound))
+		return
+	}
+	response.OK(c, sceneResponse(scene))
+}
+
+// CreateWxappScene registers the scene of a Mini Program code
+// @Summary Create Mini Program scene
+// @Description Register a scene token opening the page of a recommender or destination, optionally per campaign. Scenes
+// @Description are unique per page and campaign: asking again returns the existing scene with 200.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateWxappSceneRequest true "Page of the scene"
+// @Success 201 {object} WxappSceneResponse
+// @Success 200 {object} WxappSceneResponse
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/wxapp/scenes [post]
+func (wc *WxappController) CreateWxappScene(c *gin.Context) {
+	var req CreateWxappSceneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err)
+		return
+	}
+
+	scene := models.WxappScene{Target: req.Target, Campaign: req.Campaign}
+	switch req.Target {
+	case models.SceneTargetRecommendor:
+		var recommendor models.Recommendor
+		if err := dbWithContext(c).Select("id").First(&recommendor, req.RecommendorID).Error; err != nil {
+			response.Error(c, lookupError(err, response.ErrRecommendorNotFound))
+			return
+		}
+		scene.RecommendorID = recommendor.ID
+	case models.SceneTargetDestination:
+		var destination models.Destination
+		if err := dbWithContext(c).Select("id", "recommendor_id").First(&destination, req.DestinationID).Error; err != nil {
+			response.Error(c, lookupError(err, response.ErrDestinationNotFound))
+			return
+		}
+		scene.RecommendorID = destination.RecommendorID
+		scene.DestinationID = &destination.ID
+	}
+
+	created, err := findOrCreateScene(c.Request.Context(), &scene)
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+	if created {
+		response.Created(c, sceneResponse(scene))
+		return
+	}
+	response.OK(c, sceneResponse(scene))
+}
+
+// GetWxappScenes lists the registered Mini Program scenes
+// @Summary List Mini Program scenes
+// @Description List the scenes of Mini Program codes with the pages they open. Scenes of the recommender codes are
+// @Description registered when the codes are generated.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param sort query string false "Comma-separated sort fields, prefix - for descending (id, target, recommendor_id, destination_id, created_at)"
+// @Filters WxappSceneFilters
+// @Success 200 {object} utils.PaginationResponse{data=[]WxappSceneResponse}
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/wxapp/scenes [get]
+func (wc *WxappController) GetWxappScenes(c *gin.Context) {
+	pr, ok := paginationRequest(c, wxappSceneSortFields)
+	if !ok {
+		return
+	}
+	filters, ok := listFilters(c, WxappSceneFilters)
+	if !ok {
+		return
+	}
+	query := utils.ApplyFilters(dbWithContext(c).Model(&models.WxappScene{}), filters)
+
+	var scenes []models.WxappScene
+	page, err := utils.Paginate(query, pr, &scenes, &models.WxappScene{})
+	if err != nil {
+		response.Error(c, response.ErrDatabase.WithCause(err))
+		return
+	}
+
+	result := make([]WxappSceneResponse, len(scenes))
+	for i, scene := range scenes {
+		result[i] = sceneResponse(scene)
+	}
+	page.Data = result
+	response.Paginated(c, page)
+}
+
+// GetWxappSceneQRCode draws the Mini Program code of a scene
+// @Summary Get Mini Program scene code
+// @Description Draw the Mini Program code of a scene as a PNG image. Without a WeChat AppSecret a plain QR code of the
+// @Description Mini Program page is drawn instead, with the configured branding.
+// @Tags admin
+// @Produce png
+// @Security BearerAuth
+// @Param token path string true "Scene token"
+// @Param size query int false "Image size in pixels" minimum(64) maximum(1024) default(256)
+// @Param level query string false "Error correction level of plain QR codes, H when a logo is drawn" Enums(L,M,Q,H)
+// @Param fg query string false "Module color of plain QR codes such as 1a1a1a"
+// @Param bg query string false "Background color of plain QR codes such as ffffff"
+// @Param logo query bool false "Draw the configured center logo on plain QR codes" default(true)
+// @Success 200 {file} file "PNG image"
+// @Failure 400 {object} response.Body
+// @Failure 401 {object} response.Body
+// @Failure 403 {object} response.Body
+// @Failure 404 {object} response.Body
+// @Failure 500 {object} response.Body
+// @Router /api/v1/admin/wxapp/scenes/{token}/qrcode.png [get]
+// @x-envelope false
+func (wc *WxappController) GetWxappSceneQRCode(c *gin.Context) {
+	qrConfig, err := wc.Recommendors.qrCodeConfig()
+	if err != nil {
+		response.Error(c, response.ErrQRCodeGenerate.WithCause(err))
+		return
+	}
+	opts, ok := qrCodeOptions(c, qrConfig.Options)
+	if !ok {
+		return
+	}
+
+	var scene models.WxappScene
+	if err := dbWithContext(c).Where("token = ?", c.Param("token")).First(&scene).Error; err != nil {
+		response.Error(c, lookupError(err, response.ErrSceneNotFound))
+		return
+	}
+
+	path := utils.WxappScenePath(qrConfig.MinAppPath, scenePage(scene.Target), scene.Token)
+	image, err := utils.GenerateWxappQRCode(c.Request.Context(), qrConfig.WeChat, qrConfig.MinAppID, path, opts)
+	if err != nil {
+		response.Error(c, response.ErrQRCodeGenerate.WithCause(err))
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, http.DetectContentType(image), image)
+}
+
+// sceneResponse adds the page a scene opens and its parameters to the scene
+func sceneResponse(scene models.WxappScene) WxappSceneResponse {
+	params := map[string]string{"id": strconv.FormatUint(uint64(scene.RecommendorID), 10)}
+	if scene.Target == models.SceneTargetDestination && scene.DestinationID != nil {
+		params["id"] = strconv.FormatUint(uint64(*scene.DestinationID), 10)
+		params["recommendor_id"] = strconv.FormatUint(uint64(scene.RecommendorID), 10)
+	}
+	if scene.Campaign != "" {
+		params["campaign"] = scene.Campaign
+	}
+	return WxappSceneResponse{WxappScene: scene, Page: scenePage(scene.Target), Params: params}
+}
+
+// scenePage returns the Mini Program page of a scene target
+func scenePage(target string) string {
+	if target == models.SceneTargetDestination {
+		return utils.WxappDestinationPage
+	}
+	return utils.WxappRecommendorPage
+}
+
+// recommendorSceneToken returns the token of the scene the Mini Program code of a recommender
+// carries, creating the scene on first use
+func recommendorSceneToken(ctx context.Context, recommendorID uint) (string, error) {
+	scene := models.WxappScene{Target: models.SceneTargetRecommendor, RecommendorID: recommendorID}
+	if _, err := findOrCreateScene(ctx, &scene); err != nil {
+		return "", err
+	}
+	return scene.Token, nil
+}
+
+// findOrCreateScene loads the scene with the target, IDs and campaign of scene into it,
+// registering it with a new token when there is none yet. It reports whether the scene was created.
+func findOrCreateScene(ctx context.Context, scene *models.WxappScene) (bool, error) {
+	var destinationID uint
+	if scene.DestinationID != nil {
+		destinationID = *scene.DestinationID
+	}
+	scene.Key = fmt.Sprintf("%s:%d:%d:%s", scene.Target, scene.RecommendorID, destinationID, scene.Campaign)
+
+	db := config.DB.WithContext(ctx)
+	for attempt := 0; attempt < 3; attempt++ {
+		var existing models.WxappScene
+		err := db.Where("scene_key = ?", scene.Key).First(&existing).Error
+		if err == nil {
+			*scene = existing
+			return false, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+
+		token, err := utils.NewShortCode()
+		if err != nil {
+			return false, err
+		}
+		// As with short links, a concurrent registration or a taken token is settled by looking again
+		candidate := *scene
+		candidate.Token = token
+		created := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&candidate)
+		if created.Error != nil {
+			return false, created.Error
+		}
+		if created.RowsAffected == 1 {
+			*scene = candidate
+			return true, nil
+		}
+	}
+	return false, errors.New("failed to allocate a scene token")
+}
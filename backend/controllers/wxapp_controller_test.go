@@ -0,0 +1,166 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/models"
+	"tourism_recommendor/testutil"
+	"tourism_recommendor/utils"
+)
+
+func TestRecommendorCodeCarriesScene(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+
+	w := h.Do("POST", fmt.Sprintf("/api/v1/admin/recommendors/%d/qrcodes", recommendor.ID), nil, h.AdminToken())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	// The Mini Program code carries a scene token instead of the query of the page
+	scenes := h.WeChat.Scenes()
+	if len(scenes) != 1 || len(scenes[0]) != utils.ShortCodeLength {
+		t.Fatalf("scenes = %q, want one token", scenes)
+	}
+
+	w = h.Do("GET", "/api/v1/wxapp/scene/"+scenes[0], nil, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var scene controllers.WxappSceneResponse
+	h.Decode(w, &scene)
+	want := strconv.FormatUint(uint64(recommendor.ID), 10)
+	if scene.Target != models.SceneTargetRecommendor || scene.Page != utils.WxappRecommendorPage || len(scene.Params) != 1 || scene.Params["id"] != want {
+		t.Fatalf("scene = %+v, want the page of recommendor %s", scene, want)
+	}
+
+	// Generating the codes again reuses the scene
+	h.Do("POST", fmt.Sprintf("/api/v1/admin/recommendors/%d/qrcodes", recommendor.ID), nil, h.AdminToken())
+	if scenes := h.WeChat.Scenes(); len(scenes) != 2 || scenes[1] != scenes[0] {
+		t.Fatalf("scenes = %q, want the same token twice", scenes)
+	}
+}
+
+func TestResolveWxappScene(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+	destination := h.CreateDestination(recommendor)
+	scene := models.WxappScene{Token: "Dest2345", Key: "destination", Target: models.SceneTargetDestination, RecommendorID: recommendor.ID, DestinationID: &destination.ID, Campaign: "spring"}
+	h.DB.Create(&scene)
+
+	tests := []struct {
+		name    string
+		token   string
+		status  int
+		errCode string
+		page    string
+		params  map[string]string
+	}{
+		{
+			name:   "destination",
+			token:  scene.Token,
+			status: http.StatusOK,
+			page:   utils.WxappDestinationPage,
+			params: map[string]string{
+				"id":             strconv.FormatUint(uint64(destination.ID), 10),
+				"recommendor_id": strconv.FormatUint(uint64(recommendor.ID), 10),
+				"campaign":       "spring",
+			},
+		},
+		{name: "unknown", token: "missing", status: http.StatusNotFound, errCode: "SCENE_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("GET", "/api/v1/wxapp/scene/"+tt.token, nil, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.errCode != "" {
+				return
+			}
+
+			var got controllers.WxappSceneResponse
+			h.Decode(w, &got)
+			if got.Page != tt.page || fmt.Sprint(got.Params) != fmt.Sprint(tt.params) {
+				t.Fatalf("scene = %+v, want %s with %v", got, tt.page, tt.params)
+			}
+		})
+	}
+}
+
+func TestCreateWxappScene(t *testing.T) {
+	h := testutil.New(t)
+	recommendor := h.CreateRecommendor()
+	destination := h.CreateDestination(recommendor)
+
+	tests := []struct {
+		name    string
+		body    controllers.CreateWxappSceneRequest
+		status  int
+		errCode string
+	}{
+		{name: "recommendor", body: controllers.CreateWxappSceneRequest{Target: "recommendor", RecommendorID: recommendor.ID}, status: http.StatusCreated},
+		{name: "same recommendor", body: controllers.CreateWxappSceneRequest{Target: "recommendor", RecommendorID: recommendor.ID}, status: http.StatusOK},
+		{name: "campaign", body: controllers.CreateWxappSceneRequest{Target: "recommendor", RecommendorID: recommendor.ID, Campaign: "poster-a"}, status: http.StatusCreated},
+		{name: "destination", body: controllers.CreateWxappSceneRequest{Target: "destination", DestinationID: destination.ID}, status: http.StatusCreated},
+		{name: "unknown target", body: controllers.CreateWxappSceneRequest{Target: "region", RecommendorID: recommendor.ID}, status: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "missing destination", body: controllers.CreateWxappSceneRequest{Target: "destination"}, status: http.StatusBadRequest, errCode: "VALIDATION_FAILED"},
+		{name: "unknown recommendor", body: controllers.CreateWxappSceneRequest{Target: "recommendor", RecommendorID: 999999}, status: http.StatusNotFound, errCode: "RECOMMENDOR_NOT_FOUND"},
+		{name: "unknown destination", body: controllers.CreateWxappSceneRequest{Target: "destination", DestinationID: 999999}, status: http.StatusNotFound, errCode: "DESTINATION_NOT_FOUND"},
+	}
+
+	tokens := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.Do("POST", "/api/v1/admin/wxapp/scenes", tt.body, h.AdminToken())
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if code := h.ErrorCode(w); code != tt.errCode {
+				t.Fatalf("error code = %q, want %q", code, tt.errCode)
+			}
+			if tt.errCode != "" {
+				return
+			}
+
+			var scene controllers.WxappSceneResponse
+			h.Decode(w, &scene)
+			if scene.RecommendorID != recommendor.ID || scene.Campaign != tt.body.Campaign {
+				t.Fatalf("scene = %+v", scene)
+			}
+			if tokens[scene.Token] != (tt.status == http.StatusOK) {
+				t.Fatalf("token %q reused = %v, want %v", scene.Token, tokens[scene.Token], tt.status == http.StatusOK)
+			}
+			tokens[scene.Token] = true
+		})
+	}
+
+	t.Run("list", func(t *testing.T) {
+		w := h.Do("GET", fmt.Sprintf("/api/v1/admin/wxapp/scenes?target=destination&destination_id=%d", destination.ID), nil, h.AdminToken())
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+		var scenes []controllers.WxappSceneResponse
+		h.Decode(w, &scenes)
+		if len(scenes) != 1 || scenes[0].Page != utils.WxappDestinationPage {
+			t.Fatalf("scenes = %+v, want the destination scene", scenes)
+		}
+
+		// The code of the scene carries its token
+		w = h.Do("GET", "/api/v1/admin/wxapp/scenes/"+scenes[0].Token+"/qrcode.png", nil, h.AdminToken())
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+			t.Fatalf("status = %d, content type %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		if got := h.WeChat.Scenes(); len(got) != 1 || got[0] != scenes[0].Token {
+			t.Fatalf("scenes drawn = %q, want %q", got, scenes[0].Token)
+		}
+	})
+}
@@ -0,0 +1,12 @@
+// Package docs embeds the OpenAPI document generated from the handler annotations.
+// Regenerate it with `go generate ./docs` after changing an annotation.
+package docs
+
+import _ "embed"
+
+//go:generate go run ../cmd/openapi -root .. -o openapi.json
+
+// OpenAPI is the generated OpenAPI 3 document served at /api/v1/openapi.json
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -0,0 +1,47 @@
+package i18n
+
+// EnumOption is a value of an enumerated field with its localized label
+type EnumOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// enums lists the values of every enumerated field that clients display.
+// Labels live in the message catalogs under "enum.<name>.<value>".
+var enums = map[string][]string{
+	"gender":               {"male", "female", "other"},
+	"status":               {"active", "inactive"},
+	"admin_status":         {"active", "inactive", "locked"},
+	"admin_role":           {"super_admin", "admin"},
+	"destination_category": {"scenic_spot", "food", "accommodation"},
+	"qr_status":            {"pending", "ready", "failed"},
+	"scan_source":          {"wechat", "alipay", "mobile", "desktop", "bot", "unknown"},
+	"scene_target":         {"recommendor", "destination"},
+}
+
+// EnumValues returns the values of an enum, or nil when the enum is unknown
+func EnumValues(enum string) []string {
+	return append([]string(nil), enums[enum]...)
+}
+
+// Label returns the localized label of an enum value, or the value itself when unknown
+func Label(locale, enum, value string) string {
+	key := "enum." + enum + "." + value
+	if message, ok := lookup(locale, key); ok {
+		return message
+	}
+	return value
+}
+
+// EnumOptions returns every enum with localized labels, keyed by enum name
+func EnumOptions(locale string) map[string][]EnumOption {
+	result := make(map[string][]EnumOption, len(enums))
+	for name, values := range enums {
+		options := make([]EnumOption, 0, len(values))
+		for _, value := range values {
+			options = append(options, EnumOption{Value: value, Label: Label(locale, name, value)})
+		}
+		result[name] = options
+	}
+	return result
+}
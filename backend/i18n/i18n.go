@@ -0,0 +1,149 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Supported locales
+const (
+	LocaleZH = "zh-CN"
+	LocaleEN = "en"
+)
+
+// SourceLocale is the language of the content stored on records themselves (bios,
+// destination names and descriptions); other locales are kept as translations.
+// It does not follow DEFAULT_LOCALE, which only picks the language of responses.
+const SourceLocale = LocaleZH
+
+// contextKey is the type of context keys owned by this package
+type contextKey struct{}
+
+// localeKey stores the negotiated locale in a context
+var localeKey = contextKey{}
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	// supportedTags lists the locales in matcher order
+	supportedTags = []language.Tag{language.MustParse(LocaleZH), language.MustParse(LocaleEN)}
+
+	// matcher negotiates Accept-Language against the supported locales
+	matcher = language.NewMatcher(supportedTags)
+
+	// catalogs holds the messages of every locale, keyed by message key
+	catalogs = mustLoadCatalogs()
+
+	// defaultLocale is used when the client does not ask for a supported locale
+	defaultLocale = LocaleZH
+)
+
+// Locales returns the supported locales
+func Locales() []string {
+	return []string{LocaleZH, LocaleEN}
+}
+
+// IsSupported reports whether the locale has a message catalog
+func IsSupported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// SetDefaultLocale changes the fallback locale; unsupported locales are ignored
+func SetDefaultLocale(locale string) {
+	if IsSupported(locale) {
+		defaultLocale = locale
+	}
+}
+
+// DefaultLocale returns the fallback locale
+func DefaultLocale() string {
+	return defaultLocale
+}
+
+// Negotiate picks the best supported locale for an Accept-Language header value
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return defaultLocale
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return defaultLocale
+	}
+	return Locales()[index]
+}
+
+// WithLocale returns a copy of ctx carrying the locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// FromContext returns the locale stored in ctx, or the default locale
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey).(string); ok {
+		return locale
+	}
+	return defaultLocale
+}
+
+// T translates a message key, formatting it with args when given.
+// Missing keys fall back to the default locale, then English, then the key itself.
+func T(locale, key string, args ...interface{}) string {
+	message, ok := lookup(locale, key)
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Has reports whether a message key exists in the locale's catalog
+func Has(locale, key string) bool {
+	_, ok := catalogs[locale][key]
+	return ok
+}
+
+// lookup finds a message, falling back through the default locale and English
+func lookup(locale, key string) (string, bool) {
+	for _, candidate := range []string{locale, defaultLocale, LocaleEN} {
+		if message, ok := catalogs[candidate][key]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// mustLoadCatalogs reads the embedded locales/<locale>.json message catalogs
+func mustLoadCatalogs() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: failed to read message catalogs: %v", err))
+	}
+
+	result := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to read %s: %v", entry.Name(), err))
+		}
+
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid message catalog %s: %v", entry.Name(), err))
+		}
+		result[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+
+	return result
+}
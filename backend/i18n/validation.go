@@ -0,0 +1,47 @@
+package i18n
+
+import (
+	"fmt"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+// universal holds the validator message translators of every supported locale
+var universal = ut.New(en.New(), en.New(), zh.New())
+
+// translatorNames maps supported locales to universal-translator locale names
+var translatorNames = map[string]string{
+	LocaleEN: "en",
+	LocaleZH: "zh",
+}
+
+// RegisterValidator installs the default validator messages of every supported locale
+func RegisterValidator(v *validator.Validate) error {
+	enTrans := ValidationTranslator(LocaleEN)
+	if err := en_translations.RegisterDefaultTranslations(v, enTrans); err != nil {
+		return fmt.Errorf("register en validation translations: %w", err)
+	}
+
+	zhTrans := ValidationTranslator(LocaleZH)
+	if err := zh_translations.RegisterDefaultTranslations(v, zhTrans); err != nil {
+		return fmt.Errorf("register zh validation translations: %w", err)
+	}
+
+	return nil
+}
+
+// ValidationTranslator returns the validator message translator for the locale
+func ValidationTranslator(locale string) ut.Translator {
+	name, ok := translatorNames[locale]
+	if !ok {
+		name = translatorNames[defaultLocale]
+	}
+
+	trans, _ := universal.GetTranslator(name)
+	return trans
+}
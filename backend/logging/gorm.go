@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger writes GORM query logs through slog so they carry request attributes and redaction
+type GormLogger struct {
+	Level         gormlogger.LogLevel
+	SlowThreshold time.Duration
+}
+
+// NewGormLogger creates a GORM logger with the given level and slow query threshold
+func NewGormLogger(level gormlogger.LogLevel, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{Level: level, SlowThreshold: slowThreshold}
+}
+
+// LogMode returns a copy of the logger with a different level
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.Level = level
+	return &clone
+}
+
+// Info logs informational GORM messages
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Info {
+		FromContext(ctx).InfoContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+// Warn logs GORM warnings
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Warn {
+		FromContext(ctx).WarnContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+// Error logs GORM errors
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.Level >= gormlogger.Error {
+		FromContext(ctx).ErrorContext(ctx, fmt.Sprintf(msg, args...), "component", "gorm")
+	}
+}
+
+// Trace logs executed SQL statements, slow queries and query errors
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	logger := FromContext(ctx)
+
+	switch {
+	case err != nil && l.Level >= gormlogger.Error && !errors.Is(err, gormlogger.ErrRecordNotFound):
+		sql, rows := fc()
+		logger.ErrorContext(ctx, "query failed", "component", "gorm", "sql", sql, "rows", rows,
+			"duration_ms", elapsed.Milliseconds(), "error", err)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.Level >= gormlogger.Warn:
+		sql, rows := fc()
+		logger.WarnContext(ctx, "slow query", "component", "gorm", "sql", sql, "rows", rows,
+			"duration_ms", elapsed.Milliseconds(), "threshold_ms", l.SlowThreshold.Milliseconds())
+	case l.Level >= gormlogger.Info:
+		sql, rows := fc()
+		logger.DebugContext(ctx, "query", "component", "gorm", "sql", sql, "rows", rows,
+			"duration_ms", elapsed.Milliseconds())
+	}
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// contextKey is the type of context keys owned by this package
+type contextKey struct{}
+
+// loggerKey stores the request-scoped logger in a context
+var loggerKey = contextKey{}
+
+// Setup installs a redacting structured logger as the process-wide default.
+// The standard library log package is routed through it as well.
+func Setup(level, format string) *slog.Logger {
+	logger := New(os.Stdout, level, format)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// New creates a redacting structured logger writing to w
+func New(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(NewRedactingHandler(handler))
+}
+
+// ParseLevel converts a level name into a slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithLogger returns a copy of ctx carrying the given logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the request-scoped logger, or the default logger if none is set
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// StdLogger returns a standard library logger that writes through slog at the given level
+func StdLogger(level slog.Level) *log.Logger {
+	return slog.NewLogLogger(slog.Default().Handler(), level)
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces sensitive values in log output
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys (matched case-insensitively as substrings) whose values are never logged
+var sensitiveKeys = []string{
+	"password",
+	"secret",
+	"token",
+	"authorization",
+	"cookie",
+	"id_number",
+}
+
+var (
+	// queryParamPattern matches credentials passed as URL query or form parameters
+	queryParamPattern = regexp.MustCompile(`(?i)\b(access_token|secret|password|token|appsecret)=([^&\s"']+)`)
+
+	// jsonFieldPattern matches credentials embedded in JSON documents
+	jsonFieldPattern = regexp.MustCompile(`(?i)"(access_token|secret|password|token|id_number)"\s*:\s*"[^"]*"`)
+
+	// bearerPattern matches bearer credentials in authorization headers
+	bearerPattern = regexp.MustCompile(`(?i)\bBearer\s+[A-Za-z0-9\-_.~+/]+=*`)
+
+	// idNumberPattern matches mainland China resident identity card numbers
+	idNumberPattern = regexp.MustCompile(`\b(\d{6})\d{8}(\d{3}[\dXx])\b`)
+)
+
+// RedactingHandler wraps a slog.Handler and scrubs secrets, tokens and ID numbers
+type RedactingHandler struct {
+	next slog.Handler
+}
+
+// NewRedactingHandler creates a handler that redacts sensitive data before delegating
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{next: next}
+}
+
+// Enabled reports whether the wrapped handler handles records at the level
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle redacts the record message and attributes and passes it on
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, RedactString(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs returns a handler whose pre-set attributes are redacted
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted)}
+}
+
+// WithGroup returns a handler that nests attributes under the group name
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name)}
+}
+
+// RedactString scrubs credentials and ID numbers from free-form text
+func RedactString(s string) string {
+	s = queryParamPattern.ReplaceAllString(s, "$1="+Redacted)
+	s = jsonFieldPattern.ReplaceAllString(s, `"$1":"`+Redacted+`"`)
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+Redacted)
+	s = idNumberPattern.ReplaceAllString(s, "$1********$2")
+	return s
+}
+
+// IsSensitiveKey reports whether values stored under key must never be logged
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr redacts a single attribute, recursing into groups
+func redactAttr(attr slog.Attr) slog.Attr {
+	if IsSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, Redacted)
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, RedactString(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, member := range group {
+			redacted[i] = redactAttr(member)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, RedactString(err.Error()))
+		}
+	}
+
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,79 +11,105 @@ import (
 	"time"
 
 	"tourism_recommendor/config"
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/logging"
+	"tourism_recommendor/metrics"
 	"tourism_recommendor/routes"
+	"tourism_recommendor/tracing"
 	"tourism_recommendor/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Println("Warning: .env file not found, using environment variables")
+	// Load configuration from defaults, config file, .env and environment
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Set Gin mode based on environment
-	mode := os.Getenv("GIN_MODE")
-	if mode == "" {
-		mode = gin.DebugMode
-	}
-	gin.SetMode(mode)
-
-	// Log environment configuration
-	log.Println("========================================")
-	log.Println("📋 Environment Configuration:")
-	log.Printf("  GIN_MODE: %s", mode)
-	log.Printf("  SERVER_PORT: %s", os.Getenv("SERVER_PORT"))
-	log.Printf("  DATABASE_URL: *** (%s)", func() string {
-		if os.Getenv("DATABASE_URL") != "" {
-			return "configured"
+	// Install the structured logger (the standard log package is routed through it)
+	logging.Setup(cfg.Log.Level, cfg.Log.Format)
+
+	// Set Gin mode based on configuration
+	gin.SetMode(cfg.Server.Mode)
+
+	// Apply JWT settings
+	utils.SetJWTSecret(cfg.JWT.Secret.Value())
+	utils.SetTokenExpiration(cfg.JWT.Expiration)
+	utils.SetTouristTokenExpiration(cfg.JWT.TouristExpiration)
+
+	// Apply the fallback locale for clients without a supported Accept-Language
+	i18n.SetDefaultLocale(cfg.I18n.DefaultLocale)
+
+	// Log configuration (secrets are redacted)
+	slog.Info("configuration loaded", "config", cfg)
+
+	// Initialize tracing (exported over OTLP/HTTP)
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+			ServiceName: cfg.Tracing.ServiceName,
+			Endpoint:    cfg.Tracing.Endpoint,
+			Insecure:    cfg.Tracing.Insecure,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			fatal("failed to initialize tracing", err)
 		}
-		return "not set"
-	}())
-	log.Printf("  DB_HOST: %s", os.Getenv("DB_HOST"))
-	log.Printf("  DB_PORT: %s", os.Getenv("DB_PORT"))
-	log.Printf("  DB_USER: %s", os.Getenv("DB_USER"))
-	log.Printf("  DB_NAME: %s", os.Getenv("DB_NAME"))
-	log.Println("  DB_PASSWORD: *** (hidden)")
-	log.Println("")
-	log.Println("🔐 Default Admin Configuration:")
-	log.Printf("  Username: %s", os.Getenv("DEFAULT_ADMIN_USERNAME"))
-	log.Printf("  Password: %s", os.Getenv("DEFAULT_ADMIN_PASSWORD"))
-	log.Printf("  Email: %s", os.Getenv("DEFAULT_ADMIN_EMAIL"))
-	log.Println("========================================")
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				slog.Error("failed to flush traces", "error", err)
+			}
+		}()
+	}
 
 	// Initialize database
-	log.Println("Initializing database connection...")
-	if err := config.InitDatabaseFromEnv(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	if err := config.InitDatabase(cfg.Database); err != nil {
+		fatal("failed to initialize database", err)
 	}
 	defer config.CloseDatabase()
 
+	// Export connection pool gauges
+	if cfg.Metrics.Enabled {
+		sqlDB, err := config.DB.DB()
+		if err != nil {
+			fatal("failed to get database instance", err)
+		}
+		if err := metrics.RegisterDBStats(sqlDB, cfg.Database.DBName); err != nil {
+			fatal("failed to register database metrics", err)
+		}
+	}
+
 	// Auto-migrate database tables
-	log.Println("Running database migrations...")
+	slog.Info("running database migrations")
 	if err := routes.AutoMigrate(config.DB); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+		fatal("failed to run migrations", err)
+	}
+	if err := routes.MigrateQRCodeURLs(config.DB, cfg.QRCode.BaseURL); err != nil {
+		fatal("failed to migrate QR code URLs", err)
+	}
+	slog.Info("database migrations completed")
+
+	// Exports left running by a stopped process cannot be resumed
+	if err := controllers.FailAbandonedExports(config.DB); err != nil {
+		fatal("failed to mark abandoned exports as failed", err)
 	}
-	log.Println("Database migrations completed successfully")
 
 	// Seed initial data
-	log.Println("Seeding initial data...")
-	if err := routes.SeedDatabase(config.DB); err != nil {
-		log.Fatalf("Failed to seed initial data: %v", err)
+	if err := routes.SeedDatabase(config.DB, cfg.Admin); err != nil {
+		fatal("failed to seed initial data", err)
 	}
-	log.Println("Initial data seeding completed successfully")
 
 	// Initialize upload directories
-	log.Println("Initializing upload directories...")
 	if err := utils.InitUploadDirectories(); err != nil {
-		log.Fatalf("Failed to initialize upload directories: %v", err)
+		fatal("failed to initialize upload directories", err)
 	}
-	log.Println("Upload directories initialized successfully")
 
 	// Create Gin router
-	router := gin.Default()
+	router := gin.New()
 
 	// Configure trusted proxies for Render's reverse proxy
 	// Render terminates SSL and forwards requests via reverse proxy
@@ -90,29 +117,36 @@ func main() {
 	router.SetTrustedProxies([]string{"0.0.0.0/0"})
 
 	// Setup middleware
-	routes.SetupMiddleware(router)
+	routes.SetupMiddleware(router, cfg)
 
 	// Setup routes
-	routes.SetupRoutes(router, config.DB)
+	qrCodes := routes.SetupRoutes(router, config.DB, cfg)
 
-	// Get server port from environment
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080"
-	}
+	// Generate queued QR codes in the background
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		qrCodes.Run(workerCtx)
+	}()
+
+	// Delete expired export archives and fail exports whose lease has passed
+	go controllers.SweepExports(workerCtx, config.DB)
+
+	port := cfg.Server.Port
 
 	// Create HTTP server
 	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
+		Addr:     ":" + port,
+		Handler:  router,
+		ErrorLog: logging.StdLogger(slog.LevelError),
 	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting server on port %s...", port)
-		log.Printf("Server URL: http://localhost:%s", port)
+		slog.Info("starting server", "port", port, "url", "http://localhost:"+port)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal("failed to start server", err)
 		}
 	}()
 
@@ -120,15 +154,41 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server")
+
+	// Fail readiness first so the load balancer stops routing new traffic
+	controllers.SetShuttingDown(true)
+	if cfg.Server.ShutdownDrainDelay > 0 {
+		slog.Info("waiting for in-flight traffic to drain", "delay", cfg.Server.ShutdownDrainDelay.String())
+		time.Sleep(cfg.Server.ShutdownDrainDelay)
+	}
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("server forced to shutdown", err)
 	}
 
-	log.Println("Server exited successfully")
+	// Let the QR code job in progress finish; one cut off is picked up again once its lease passes
+	stopWorker()
+	select {
+	case <-workerDone:
+	case <-ctx.Done():
+		slog.Warn("QR code job still running at shutdown")
+	}
+
+	// Let running exports finish; those cut off are marked failed once their lease passes
+	if err := controllers.WaitForExports(ctx); err != nil {
+		slog.Warn("exports still running at shutdown", "error", err)
+	}
+
+	slog.Info("server exited")
+}
+
+// fatal logs an error and exits the process
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Namespace prefixes every metric exported by the API
+const Namespace = "tourism"
+
+// Registry holds all application metrics; it is separate from the global default registry
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestsTotal counts handled HTTP requests by method, route template and status
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests handled.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes HTTP request latency by method, route template and status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// UploadBytesTotal counts bytes stored by the upload endpoints by kind
+	UploadBytesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Total number of bytes uploaded.",
+	}, []string{"kind"})
+
+	// QRCodeGenerationsTotal counts QR code generations by type (web, wxapp) and result
+	QRCodeGenerationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "qrcode_generations_total",
+		Help:      "Total number of QR code generations.",
+	}, []string{"type", "result"})
+
+	// WeChatAPIDuration observes WeChat API call latency by endpoint and result
+	WeChatAPIDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "wechat_api_duration_seconds",
+		Help:      "WeChat API call latency in seconds.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"endpoint", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		UploadBytesTotal,
+		QRCodeGenerationsTotal,
+		WeChatAPIDuration,
+	)
+}
+
+// RegisterDBStats exports connection pool gauges for the given database
+func RegisterDBStats(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveHTTPRequest records a handled HTTP request
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	statusLabel := strconv.Itoa(status)
+	HTTPRequestsTotal.WithLabelValues(method, route, statusLabel).Inc()
+	HTTPRequestDuration.WithLabelValues(method, route, statusLabel).Observe(duration.Seconds())
+}
+
+// AddUploadBytes records the size of a stored upload
+func AddUploadBytes(kind string, size int64) {
+	UploadBytesTotal.WithLabelValues(kind).Add(float64(size))
+}
+
+// ObserveQRCodeGeneration records the outcome of a QR code generation
+func ObserveQRCodeGeneration(qrType string, err error) {
+	QRCodeGenerationsTotal.WithLabelValues(qrType, resultLabel(err)).Inc()
+}
+
+// ObserveWeChatCall records the latency and outcome of a WeChat API call
+func ObserveWeChatCall(endpoint string, start time.Time, err error) {
+	WeChatAPIDuration.WithLabelValues(endpoint, resultLabel(err)).Observe(time.Since(start).Seconds())
+}
+
+// resultLabel converts an error into a success/failure label value
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"tourism_recommendor/logging"
 	"tourism_recommendor/models"
+	"tourism_recommendor/response"
 	"tourism_recommendor/utils"
 
 	"github.com/gin-gonic/gin"
@@ -27,10 +30,7 @@ func AuthRequired() gin.HandlerFunc {
 
 		// Check if header exists
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header is required",
-			})
-			c.Abort()
+			response.Error(c, response.ErrAuthHeaderMissing)
 			return
 		}
 
@@ -39,20 +39,19 @@ func AuthRequired() gin.HandlerFunc {
 		tokenString := strings.TrimPrefix(authHeader, BearerScheme+" ")
 		if tokenString == authHeader {
 			// Token doesn't start with "Bearer "
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid authorization header format",
-			})
-			c.Abort()
+			response.Error(c, response.ErrAuthHeaderInvalid)
 			return
 		}
 
 		// Validate token
 		claims, err := utils.ValidateToken(tokenString)
+		if errors.Is(err, utils.ErrWrongAudience) {
+			// A tourist token of the Mini Program
+			response.Error(c, response.ErrAdminRequired)
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid or expired token",
-			})
-			c.Abort()
+			response.Error(c, response.ErrTokenInvalid)
 			return
 		}
 
@@ -60,40 +59,64 @@ func AuthRequired() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		setRequestLogger(c, logging.FromContext(c.Request.Context()).With("user_id", claims.UserID))
 
 		// Continue to next handler
 		c.Next()
 	}
 }
 
+// TouristRequired is a middleware that requires the token of a Mini Program tourist.
+// Admin tokens are refused, as tourist tokens are by AuthRequired.
+func TouristRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader(AuthorizationHeader)
+		if authHeader == "" {
+			response.Error(c, response.ErrAuthHeaderMissing)
+			return
+		}
+		tokenString := strings.TrimPrefix(authHeader, BearerScheme+" ")
+		if tokenString == authHeader {
+			response.Error(c, response.ErrAuthHeaderInvalid)
+			return
+		}
+
+		claims, err := utils.ValidateTouristToken(tokenString)
+		if errors.Is(err, utils.ErrWrongAudience) {
+			response.Error(c, response.ErrTouristRequired)
+			return
+		}
+		if err != nil {
+			response.Error(c, response.ErrTokenInvalid)
+			return
+		}
+
+		c.Set("tourist_id", claims.TouristID)
+		setRequestLogger(c, logging.FromContext(c.Request.Context()).With("tourist_id", claims.TouristID))
+
+		c.Next()
+	}
+}
+
 // AdminRequired is a middleware that requires admin role
 func AdminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get user role from context (should be set by AuthRequired)
 		role, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authentication required",
-			})
-			c.Abort()
+			response.Error(c, response.ErrUnauthorized)
 			return
 		}
 
 		roleStr, ok := role.(string)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Invalid role type",
-			})
-			c.Abort()
+			response.Error(c, response.ErrInternal)
 			return
 		}
 
 		// Check if user is admin
 		if roleStr != string(models.AdminRoleAdmin) && roleStr != string(models.AdminRoleSuperAdmin) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Admin role required",
-			})
-			c.Abort()
+			response.Error(c, response.ErrAdminRequired)
 			return
 		}
 
@@ -108,28 +131,19 @@ func SuperAdminRequired() gin.HandlerFunc {
 		// Get user role from context
 		role, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authentication required",
-			})
-			c.Abort()
+			response.Error(c, response.ErrUnauthorized)
 			return
 		}
 
 		roleStr, ok := role.(string)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Invalid role type",
-			})
-			c.Abort()
+			response.Error(c, response.ErrInternal)
 			return
 		}
 
 		// Check if user is super admin
 		if roleStr != string(models.AdminRoleSuperAdmin) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Super admin role required",
-			})
-			c.Abort()
+			response.Error(c, response.ErrSuperAdminRequired)
 			return
 		}
 
@@ -159,6 +173,27 @@ func GetUserID(c *gin.Context) (uint, error) {
 	return id, nil
 }
 
+// GetTouristID retrieves the tourist ID set by TouristRequired from context
+func GetTouristID(c *gin.Context) (uint, error) {
+	touristID, exists := c.Get("tourist_id")
+	if !exists {
+		return 0, gin.Error{
+			Err:  fmt.Errorf("tourist_id not found in context"),
+			Type: gin.ErrorTypePublic,
+		}
+	}
+
+	id, ok := touristID.(uint)
+	if !ok {
+		return 0, gin.Error{
+			Err:  fmt.Errorf("invalid tourist_id type in context"),
+			Type: gin.ErrorTypePrivate,
+		}
+	}
+
+	return id, nil
+}
+
 // GetUsername retrieves username from context
 func GetUsername(c *gin.Context) (string, error) {
 	username, exists := c.Get("username")
@@ -269,6 +304,7 @@ func OptionalAuth() gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		setRequestLogger(c, logging.FromContext(c.Request.Context()).With("user_id", claims.UserID))
 
 		// Continue to next handler
 		c.Next()
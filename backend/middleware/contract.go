@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"tourism_recommendor/logging"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+// Contract violation kinds
+const (
+	ContractRequest  = "request"
+	ContractResponse = "response"
+)
+
+// ContractViolation describes a request or response that does not match the OpenAPI document
+type ContractViolation struct {
+	Kind   string
+	Method string
+	Route  string
+	Status int
+	Err    error
+}
+
+// binaryContentTypes are response types whose bodies are not decoded for validation
+var binaryContentTypes = []string{"image/png", "image/svg+xml", "application/pdf", "application/zip"}
+
+func init() {
+	for _, contentType := range binaryContentTypes {
+		openapi3filter.RegisterBodyDecoder(contentType, openapi3filter.FileBodyDecoder)
+	}
+}
+
+// ContractReporter receives the contract violations found by ContractValidator
+type ContractReporter func(c *gin.Context, violation ContractViolation)
+
+// ContractValidator validates requests and responses of documented routes against the
+// OpenAPI document. Violations are handed to report (logged when report is nil);
+// the request itself is never altered or rejected, so this is safe to enable outside release.
+func ContractValidator(spec []byte, report ContractReporter) (gin.HandlerFunc, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	if report == nil {
+		report = logContractViolation
+	}
+
+	options := &openapi3filter.Options{
+		// Authentication is enforced by the handlers; a missing token shows up as a documented 401
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		IncludeResponseStatus: true,
+		MultiError:            true,
+		SkipSettingDefaults:   true,
+	}
+
+	return func(c *gin.Context) {
+		route := contractRoute(doc, c)
+		if route == nil {
+			// Static files, the docs UI and unknown paths are not part of the contract
+			c.Next()
+			return
+		}
+
+		pathParams := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			pathParams[param.Key] = param.Value
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			report(c, ContractViolation{Kind: ContractRequest, Method: route.Method, Route: route.Path, Err: err})
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		status := recorder.Status()
+		err := openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 status,
+			Header:                 recorder.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+			Options:                options,
+		})
+		if err != nil {
+			report(c, ContractViolation{Kind: ContractResponse, Method: route.Method, Route: route.Path, Status: status, Err: err})
+		}
+	}, nil
+}
+
+// OpenAPIPath converts a gin route template (/recommendors/:id) into an OpenAPI path (/recommendors/{id})
+func OpenAPIPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// contractRoute returns the documented operation of the matched gin route, or nil
+func contractRoute(doc *openapi3.T, c *gin.Context) *routers.Route {
+	if c.FullPath() == "" {
+		return nil
+	}
+	path := OpenAPIPath(c.FullPath())
+	item := doc.Paths.Value(path)
+	if item == nil {
+		return nil
+	}
+	operation := item.GetOperation(c.Request.Method)
+	if operation == nil {
+		return nil
+	}
+	return &routers.Route{
+		Spec:      doc,
+		Path:      path,
+		PathItem:  item,
+		Method:    c.Request.Method,
+		Operation: operation,
+	}
+}
+
+// logContractViolation is the default reporter; it logs through the request logger
+func logContractViolation(c *gin.Context, violation ContractViolation) {
+	logging.FromContext(c.Request.Context()).Warn("openapi contract violation",
+		"kind", violation.Kind,
+		"route", violation.Method+" "+violation.Route,
+		"status", violation.Status,
+		"error", violation.Err.Error(),
+	)
+}
+
+// bodyRecorder keeps a copy of the response body for validation while writing it through
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
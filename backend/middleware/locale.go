@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"tourism_recommendor/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocalizedContentKey is the gin context key marking routes that return content in the request locale
+const LocalizedContentKey = "localized_content"
+
+// Locale negotiates the response locale from Accept-Language and stores it in the request context
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		locale := i18n.Negotiate(c.GetHeader("Accept-Language"))
+
+		c.Request = c.Request.WithContext(i18n.WithLocale(c.Request.Context(), locale))
+		c.Writer.Header().Set("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		c.Next()
+	}
+}
+
+// LocalizedContent marks the routes of a group as serving translated content
+// (recommender bios, destination names and descriptions) in the request locale
+func LocalizedContent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(LocalizedContentKey, true)
+		c.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"tourism_recommendor/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsMiddleware records request counts and latencies labelled by route template
+func MetricsMiddleware(metricsPath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Don't measure the scrapes themselves
+		if c.Request.URL.Path == metricsPath {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		// Use the route template (e.g. /api/v1/recommendors/:id) to keep label cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// MetricsHandler serves the Prometheus metrics, requiring a bearer token when one is configured
+// @Summary Prometheus metrics
+// @Description Expose Prometheus metrics; requires the metrics token as a bearer token when one is configured
+// @Tags health
+// @Produce plain
+// @Security BearerAuth
+// @Success 200 {string} string "Metrics in the Prometheus text format"
+// @Failure 401 {object} map[string]string
+// @Router /metrics [get]
+func MetricsHandler(token string) gin.HandlerFunc {
+	handler := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+
+	return func(c *gin.Context) {
+		if token != "" {
+			provided := strings.TrimPrefix(c.GetHeader(AuthorizationHeader), BearerScheme+" ")
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "Invalid metrics token",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
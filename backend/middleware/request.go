@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"tourism_recommendor/logging"
+	"tourism_recommendor/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader is the HTTP header carrying the request ID
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDKey is the gin context key of the request ID
+	RequestIDKey = "request_id"
+)
+
+// validRequestID limits accepted incoming request IDs to a safe character set and length
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9\-_.]{1,64}$`)
+
+// RequestID assigns every request an ID (reusing a valid incoming X-Request-ID),
+// echoes it in the response and attaches a request-scoped logger to the request context
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Writer.Header().Set(RequestIDHeader, requestID)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		logger := slog.Default().With(
+			"request_id", requestID,
+			"method", c.Request.Method,
+			"route", route,
+		)
+		setRequestLogger(c, logger)
+
+		c.Next()
+	}
+}
+
+// RequestLogger writes one structured access log line per request
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"status", status,
+			"path", c.Request.URL.Path,
+			"client_ip", c.ClientIP(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"bytes", c.Writer.Size(),
+			"user_agent", c.Request.UserAgent(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		logger := logging.FromContext(c.Request.Context())
+		switch {
+		case status >= 500:
+			logger.Error("request completed", attrs...)
+		case status >= 400:
+			logger.Warn("request completed", attrs...)
+		default:
+			logger.Info("request completed", attrs...)
+		}
+	}
+}
+
+// Recovery recovers from panics, logs them with the request attributes and responds with INTERNAL_ERROR
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("panic recovered",
+			"panic", fmt.Sprint(recovered),
+			"stack", string(debug.Stack()),
+		)
+		response.Error(c, response.ErrInternal)
+	})
+}
+
+// GetRequestID retrieves the request ID from context
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// setRequestLogger stores the logger in the request context
+func setRequestLogger(c *gin.Context, logger *slog.Logger) {
+	c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+}
+
+// newRequestID generates a random 16-byte hex request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
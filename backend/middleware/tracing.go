@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"tourism_recommendor/logging"
+	"tourism_recommendor/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// TraceIDHeader is the HTTP header carrying the trace ID of the request
+const TraceIDHeader = "X-Trace-ID"
+
+// Tracing starts a server span for every request, continuing incoming W3C trace context.
+// Requests to skipPaths (probes, metrics scrapes) are not traced.
+func Tracing(serviceName string, skipPaths ...string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithFilter(func(r *http.Request) bool {
+		for _, path := range skipPaths {
+			if r.URL.Path == path {
+				return false
+			}
+		}
+		return true
+	}))
+}
+
+// TraceContext adds the trace ID to the response header and request-scoped logger.
+// It must run after Tracing and RequestID.
+func TraceContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceID := tracing.TraceID(c.Request.Context())
+		if traceID == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Set(TraceIDHeader, traceID)
+		setRequestLogger(c, logging.FromContext(c.Request.Context()).With("trace_id", traceID))
+
+		c.Next()
+	}
+}
+
+// ErrorTraceID adds a "trace_id" field to JSON error responses (status >= 400)
+// so clients can quote it when reporting problems
+func ErrorTraceID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceID := tracing.TraceID(c.Request.Context())
+		if traceID == "" {
+			c.Next()
+			return
+		}
+
+		writer := &errorBodyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		writer.flush(traceID)
+	}
+}
+
+// errorBodyWriter buffers JSON error bodies so they can be annotated before being sent
+type errorBodyWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	buffering bool
+}
+
+// Write buffers JSON error bodies and passes everything else through
+func (w *errorBodyWriter) Write(data []byte) (int, error) {
+	if w.buffering || w.shouldBuffer() {
+		w.buffering = true
+		return w.buf.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString buffers JSON error bodies and passes everything else through
+func (w *errorBodyWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// shouldBuffer reports whether the response is a JSON error
+func (w *errorBodyWriter) shouldBuffer() bool {
+	return w.Status() >= 400 && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+}
+
+// flush writes the buffered body, adding the trace ID to JSON objects
+func (w *errorBodyWriter) flush(traceID string) {
+	if !w.buffering {
+		return
+	}
+
+	body := w.buf.Bytes()
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if _, exists := payload["trace_id"]; !exists {
+			payload["trace_id"] = traceID
+			if annotated, err := json.Marshal(payload); err == nil {
+				body = annotated
+			}
+		}
+	}
+
+	w.ResponseWriter.Write(body)
+}
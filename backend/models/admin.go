@@ -1,7 +1,7 @@
 package models
 
 import (
-	"log"
+	"log/slog"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -49,33 +49,28 @@ func (a *Admin) IsSuperAdmin() bool {
 
 // SetPassword hashes the password using bcrypt
 func (a *Admin) SetPassword(password string) error {
-	log.Printf("🔒 Hashing password for user '%s' (password length: %d)", a.Username, len(password))
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Printf("❌ Failed to hash password for user '%s': %v", a.Username, err)
+		slog.Error("failed to hash password", "username", a.Username, "error", err)
 		return err
 	}
 	a.Password = string(hashedPassword)
-	log.Printf("✅ Password hashed successfully for user '%s' (hash length: %d)", a.Username, len(a.Password))
 	return nil
 }
 
 // CheckPassword checks if the provided password matches the stored hash
 func (a *Admin) CheckPassword(password string) error {
-	log.Printf("🔑 Checking password for user '%s' (input length: %d, stored hash length: %d)", a.Username, len(password), len(a.Password))
-	err := bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(password))
-	if err != nil {
-		log.Printf("❌ Password check failed for user '%s': %v", a.Username, err)
-	} else {
-		log.Printf("✅ Password check successful for user '%s'", a.Username)
-	}
-	return err
+	return bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(password))
 }
 
 // BeforeCreate hook to hash password before creating admin
 func (a *Admin) BeforeCreate(tx *gorm.DB) error {
-	// Password hashing should be done explicitly, but this hook ensures it's always hashed
-	if a.Password != "" {
+	// Password hashing should be done explicitly, but this hook ensures it's always hashed.
+	// Values that are already bcrypt hashes (set via SetPassword) are stored as-is.
+	if a.Password == "" {
+		return nil
+	}
+	if _, err := bcrypt.Cost([]byte(a.Password)); err != nil {
 		return a.SetPassword(a.Password)
 	}
 	return nil
@@ -8,7 +8,7 @@ import (
 type Destination struct {
 	ID            uint           `gorm:"primaryKey" json:"id"`
 	RecommendorID uint           `gorm:"not null;index" json:"recommendor_id"`
-	Recommendor   Recommendor    `gorm:"foreignKey:RecommendorID" json:"recommendor,omitempty"`
+	Recommendor   *Recommendor   `gorm:"foreignKey:RecommendorID" json:"recommendor,omitempty"` // loaded with include=recommendor
 	Name          string         `gorm:"type:varchar(200);not null" json:"name"`
 	Description   string         `gorm:"type:text" json:"description"`
 	Image         string         `gorm:"type:text" json:"image"` // JSON array of image URLs
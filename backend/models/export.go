@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Export job statuses
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+	ExportExpired   = "expired"
+)
+
+// ExportJob tracks a background export of recommender QR codes and badges into a ZIP archive
+type ExportJob struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Status      string     `gorm:"type:varchar(20);not null;index" json:"status"`
+	Params      string     `gorm:"type:text;not null" json:"-"` // JSON of the export request
+	Locale      string     `gorm:"type:varchar(20);not null" json:"locale"`
+	Total       int        `gorm:"not null;default:0" json:"total"`
+	Done        int        `gorm:"not null;default:0" json:"done"`
+	Failed      int        `gorm:"not null;default:0" json:"failed"` // recommenders whose files could not be generated
+	FilePath    string     `gorm:"type:varchar(500)" json:"-"`
+	FileSize    int64      `json:"file_size"`
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedBy   uint       `json:"created_by"`
+	LockedUntil *time.Time `json:"-"` // lease of the process running the job, renewed until it finishes
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// TableName specifies the table name for ExportJob model
+func (ExportJob) TableName() string {
+	return "export_jobs"
+}
+
+// Finished reports whether the job will make no further progress
+func (j *ExportJob) Finished() bool {
+	return j.Status == ExportCompleted || j.Status == ExportFailed || j.Status == ExportExpired
+}
@@ -0,0 +1,18 @@
+package models
+
+// All returns every model managed by database migrations
+func All() []interface{} {
+	return []interface{}{
+		&Admin{},
+		&Region{},
+		&Recommendor{},
+		&Destination{},
+		&Translation{},
+		&ExportJob{},
+		&QRCodeJob{},
+		&ShortLink{},
+		&ScanEvent{},
+		&WxappScene{},
+		&Tourist{},
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// QR code statuses of a recommender
+const (
+	QRStatusPending = "pending" // generation is queued
+	QRStatusReady   = "ready"   // the stored codes match the current settings
+	QRStatusFailed  = "failed"  // generation gave up after its retries
+)
+
+// QR code job statuses
+const (
+	QRCodeJobPending = "pending"
+	QRCodeJobRunning = "running"
+	QRCodeJobFailed  = "failed"
+)
+
+// QRCodeJob is a queued QR code generation for a recommender. A recommender has at most
+// one job; it is deleted once the codes are stored and kept when generation fails for good.
+type QRCodeJob struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	RecommendorID uint         `gorm:"not null;uniqueIndex" json:"recommendor_id"`
+	Recommendor   *Recommendor `gorm:"foreignKey:RecommendorID" json:"recommendor,omitempty"`
+	Status        string       `gorm:"type:varchar(20);not null;index" json:"status"`
+	Attempts      int          `gorm:"not null;default:0" json:"attempts"`
+	LastError     string       `gorm:"type:text" json:"last_error,omitempty"`
+	RunAt         time.Time    `gorm:"not null;index" json:"run_at"` // earliest time of the next attempt
+	LockedUntil   *time.Time   `json:"-"`                            // lease of the worker running the job
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// TableName specifies the table name for QRCodeJob model
+func (QRCodeJob) TableName() string {
+	return "qrcode_jobs"
+}
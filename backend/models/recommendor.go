@@ -47,6 +47,8 @@ type Recommendor struct {
 	Rating      float64        `gorm:"default:0" json:"rating"`
 	QRCodeWeb   string         `gorm:"type:text" json:"qr_code_web,omitempty"`
 	QRCodeWxapp string         `gorm:"type:text" json:"qr_code_wxapp,omitempty"`
+	QRStatus    string         `gorm:"type:varchar(20);default:'pending';index" json:"qr_status"`
+	QRInputs    string         `gorm:"type:varchar(64)" json:"-"` // fingerprint of the settings the stored codes were drawn with
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
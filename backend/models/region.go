@@ -8,6 +8,7 @@ import (
 type Region struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
 	Name        string         `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	Code        string         `gorm:"type:varchar(20);index" json:"code"` // administrative division code, matched against recommendor address codes
 	Description string         `gorm:"type:text" json:"description"`
 	CreatedAt   int64          `json:"created_at"`
 	UpdatedAt   int64          `json:"updated_at"`
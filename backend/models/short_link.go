@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// ShortLink is the /r/{code} address a plain QR code of a recommender points to. The
+// redirect counts scans and its target can be changed after the codes are printed.
+type ShortLink struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	Code          string       `gorm:"type:varchar(16);not null;uniqueIndex" json:"code"`
+	RecommendorID uint         `gorm:"not null;uniqueIndex:idx_short_links_recommendor_type" json:"recommendor_id"`
+	Recommendor   *Recommendor `gorm:"foreignKey:RecommendorID" json:"recommendor,omitempty"`
+	Type          string       `gorm:"type:varchar(20);not null;uniqueIndex:idx_short_links_recommendor_type" json:"type"` // QR code type: web or wxapp
+	TargetURL     string       `gorm:"type:varchar(500)" json:"target_url,omitempty"`                                      // replaces the default target when set
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// TableName specifies the table name for ShortLink model
+func (ShortLink) TableName() string {
+	return "short_links"
+}
+
+// ScanEvent records one visit of a short link
+type ScanEvent struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ShortLinkID   uint      `gorm:"not null;index" json:"short_link_id"`
+	RecommendorID uint      `gorm:"not null;index" json:"recommendor_id"`
+	Source        string    `gorm:"type:varchar(20);not null;index" json:"source"` // coarse client, see utils.ScanSources
+	UserAgent     string    `gorm:"type:varchar(500)" json:"user_agent"`
+	Referrer      string    `gorm:"type:varchar(500)" json:"referrer"`
+	Day           string    `gorm:"type:varchar(10);not null;index" json:"day"` // local calendar day (2006-01-02) the daily report groups by
+	ScannedAt     time.Time `gorm:"not null;index" json:"scanned_at"`
+}
+
+// TableName specifies the table name for ScanEvent model
+func (ScanEvent) TableName() string {
+	return "scan_events"
+}
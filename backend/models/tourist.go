@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Tourist is a Mini Program user, identified by the openid WeChat assigns per Mini Program
+type Tourist struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	OpenID      string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	UnionID     string     `gorm:"type:varchar(64);index" json:"-"` // set when the Mini Program is bound to an Open Platform account
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for Tourist model
+func (Tourist) TableName() string {
+	return "tourists"
+}
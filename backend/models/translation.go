@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// Translatable entity types: recommendor (bio) and destination (name, description)
+const (
+	TranslationEntityRecommendor = "recommendor"
+	TranslationEntityDestination = "destination"
+)
+
+// Translation stores the value of a content field in a non-default locale.
+// The entity's own column holds the default-language value used as fallback.
+type Translation struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	EntityType string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_translation_entry" json:"entity_type"`
+	EntityID   uint      `gorm:"not null;uniqueIndex:idx_translation_entry" json:"entity_id"`
+	Locale     string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_translation_entry" json:"locale"`
+	Field      string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_translation_entry" json:"field"`
+	Value      string    `gorm:"type:text;not null" json:"value"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Translation model
+func (Translation) TableName() string {
+	return "translations"
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Targets of a Mini Program scene
+const (
+	SceneTargetRecommendor = "recommendor"
+	SceneTargetDestination = "destination"
+)
+
+// WxappScene is a compact token passed as the scene of a Mini Program code. WeChat limits
+// scenes to 32 characters, so the code carries the token and the Mini Program resolves it
+// to a page and its parameters.
+type WxappScene struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Token         string    `gorm:"type:varchar(32);not null;uniqueIndex" json:"token"`
+	Key           string    `gorm:"column:scene_key;type:varchar(100);not null;uniqueIndex" json:"-"` // target, IDs and campaign; one scene per key
+	Target        string    `gorm:"type:varchar(20);not null;index" json:"target"`
+	RecommendorID uint      `gorm:"not null;index" json:"recommendor_id"` // the recommender the scan is attributed to
+	DestinationID *uint     `gorm:"index" json:"destination_id,omitempty"`
+	Campaign      string    `gorm:"type:varchar(50)" json:"campaign,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for WxappScene model
+func (WxappScene) TableName() string {
+	return "wxapp_scenes"
+}
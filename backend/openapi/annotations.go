@@ -0,0 +1,218 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// operation is a handler documented with swag-style annotations
+type operation struct {
+	handler     string
+	pkg         string
+	summary     string
+	description string
+	tags        []string
+	accept      []string
+	produce     []string
+	params      []param
+	filters     []string
+	responses   []responseDoc
+	security    []string
+	extensions  map[string]string
+	routes      []route
+}
+
+// param is a parsed @Param annotation
+type param struct {
+	name     string
+	in       string
+	typ      string
+	required bool
+	desc     string
+	attrs    map[string]string
+}
+
+// responseDoc is a parsed @Success or @Failure annotation
+type responseDoc struct {
+	status  int
+	failure bool
+	kind    string
+	typ     string
+	desc    string
+}
+
+// route is a parsed @Router annotation
+type route struct {
+	path   string
+	method string
+}
+
+var (
+	paramPattern    = regexp.MustCompile(`^(\S+)\s+(\S+)\s+(\S+)\s+(true|false)\s+"([^"]*)"\s*(.*)$`)
+	attrPattern     = regexp.MustCompile(`(\w+)\(([^)]*)\)`)
+	responsePattern = regexp.MustCompile(`^(\d+)\s+\{(\w+)\}\s+(\S+)(?:\s+"([^"]*)")?$`)
+	routerPattern   = regexp.MustCompile(`^(\S+)\s+\[(\w+)\]$`)
+)
+
+// parseDirs collects the annotated handlers of the Go files in dirs
+func parseDirs(dirs ...string) ([]*operation, error) {
+	var operations []*operation
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+
+		for _, path := range files {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			ops, err := parseFile(path)
+			if err != nil {
+				return nil, err
+			}
+			operations = append(operations, ops...)
+		}
+	}
+	return operations, nil
+}
+
+// parseFile collects the annotated handlers of a single Go file
+func parseFile(path string) ([]*operation, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []*operation
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Doc == nil {
+			continue
+		}
+
+		op := &operation{handler: handlerName(fn), pkg: file.Name.Name}
+		for _, comment := range fn.Doc.List {
+			line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+			if !strings.HasPrefix(line, "@") {
+				continue
+			}
+			if err := op.parse(line); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, op.handler, err)
+			}
+		}
+		if len(op.routes) > 0 {
+			operations = append(operations, op)
+		}
+	}
+	return operations, nil
+}
+
+// handlerName returns Receiver.Method or Func for a function declaration
+func handlerName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	if ident, ok := recv.(*ast.Ident); ok {
+		return ident.Name + "." + fn.Name.Name
+	}
+	return fn.Name.Name
+}
+
+// parse applies a single annotation line to the operation
+func (op *operation) parse(line string) error {
+	keyword, value, _ := strings.Cut(line, " ")
+	value = strings.TrimSpace(value)
+
+	switch strings.ToLower(keyword) {
+	case "@summary":
+		op.summary = value
+	case "@description":
+		if op.description != "" {
+			op.description += "\n"
+		}
+		op.description += value
+	case "@tags":
+		op.tags = append(op.tags, splitList(value)...)
+	case "@accept":
+		op.accept = append(op.accept, splitList(value)...)
+	case "@produce":
+		op.produce = append(op.produce, splitList(value)...)
+	case "@security":
+		op.security = append(op.security, value)
+	case "@param":
+		m := paramPattern.FindStringSubmatch(value)
+		if m == nil {
+			return fmt.Errorf("malformed @Param %q", value)
+		}
+		p := param{name: m[1], in: m[2], typ: m[3], required: m[4] == "true", desc: m[5], attrs: map[string]string{}}
+		for _, attr := range attrPattern.FindAllStringSubmatch(m[6], -1) {
+			p.attrs[strings.ToLower(attr[1])] = attr[2]
+		}
+		op.params = append(op.params, p)
+	case "@filters":
+		op.filters = append(op.filters, splitList(value)...)
+	case "@success", "@failure":
+		m := responsePattern.FindStringSubmatch(value)
+		if m == nil {
+			return fmt.Errorf("malformed %s %q", keyword, value)
+		}
+		status, _ := strconv.Atoi(m[1])
+		typ := m[3]
+		if m[2] == "array" {
+			// {array} T documents a list of T
+			typ = "[]" + typ
+		}
+		op.responses = append(op.responses, responseDoc{
+			status:  status,
+			failure: strings.EqualFold(keyword, "@failure"),
+			kind:    m[2],
+			typ:     typ,
+			desc:    m[4],
+		})
+	case "@router":
+		m := routerPattern.FindStringSubmatch(value)
+		if m == nil {
+			return fmt.Errorf("malformed @Router %q", value)
+		}
+		op.routes = append(op.routes, route{path: m[1], method: strings.ToUpper(m[2])})
+	default:
+		if strings.HasPrefix(keyword, "@x-") {
+			if op.extensions == nil {
+				op.extensions = map[string]string{}
+			}
+			op.extensions[strings.TrimPrefix(keyword, "@")] = value
+			return nil
+		}
+		return fmt.Errorf("unknown annotation %s", keyword)
+	}
+	return nil
+}
+
+// splitList splits a comma separated annotation value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,609 @@
+// Package openapi generates the OpenAPI 3 document of the API from the
+// swag-style annotations on the handlers (@Summary, @Param, @Success, @Router, ...).
+//
+// Responses are shaped by route: /api/v1 handlers are wrapped in the response
+// envelope, legacy /api handlers keep their original bodies and error shape,
+// and everything else (probes, metrics) is documented exactly as annotated.
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"tourism_recommendor/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Document metadata
+const (
+	Title   = "Tourism Recommender API"
+	Version = "1.0.0"
+)
+
+// Directories scanned for annotated handlers, relative to the backend root
+var SourceDirs = []string{"controllers", "middleware"}
+
+const (
+	v1Prefix  = "/api/v1/"
+	apiPrefix = "/api/"
+
+	bearerAuth     = "BearerAuth"
+	acceptLanguage = "AcceptLanguage"
+	errorSchema    = "ErrorResponse"
+	legacySchema   = "LegacyErrorResponse"
+)
+
+// generator turns parsed operations into an OpenAPI document
+type generator struct {
+	doc     *openapi3.T
+	schemas *schemaBuilder
+}
+
+// Generate builds and validates the OpenAPI document from the handlers in dirs
+func Generate(dirs ...string) (*openapi3.T, error) {
+	operations, err := parseDirs(dirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{doc: newDocument(), schemas: newSchemaBuilder()}
+	for _, op := range operations {
+		for _, r := range op.routes {
+			if err := g.addOperation(op, r); err != nil {
+				return nil, fmt.Errorf("%s %s (%s): %w", r.method, r.path, op.handler, err)
+			}
+		}
+	}
+	if err := g.addErrorSchemas(); err != nil {
+		return nil, err
+	}
+	for name, schema := range g.schemas.components {
+		g.doc.Components.Schemas[name] = schema
+	}
+
+	// Round-trip through the loader so references are resolved before validation
+	data, err := json.Marshal(g.doc)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("generated document is invalid: %w", err)
+	}
+	return doc, nil
+}
+
+// Marshal renders the document as indented JSON with a trailing newline
+func Marshal(doc *openapi3.T) ([]byte, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// newDocument returns the document skeleton shared by every operation
+func newDocument() *openapi3.T {
+	return &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:   Title,
+			Version: Version,
+			Description: "Routes under /api/v1 wrap every response in the envelope " +
+				"{success, data, message, meta, error, request_id, trace_id}. " +
+				"Legacy routes under /api return bare resources and {\"error\": message} on failure.",
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{},
+			Parameters: openapi3.ParametersMap{
+				acceptLanguage: &openapi3.ParameterRef{Value: openapi3.NewHeaderParameter("Accept-Language").
+					WithDescription("Preferred language of messages and content (zh-CN, en)").
+					WithSchema(openapi3.NewStringSchema())},
+			},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				bearerAuth: &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme()},
+			},
+		},
+	}
+}
+
+// addOperation documents the handler op at route r
+func (g *generator) addOperation(op *operation, r route) error {
+	path := r.path
+	legacy := strings.HasPrefix(path, apiPrefix) && !strings.HasPrefix(path, v1Prefix)
+	envelope := strings.HasPrefix(path, v1Prefix) && op.extensions["x-envelope"] != "false"
+
+	if item := g.doc.Paths.Value(path); item != nil && item.GetOperation(r.method) != nil {
+		return fmt.Errorf("documented more than once")
+	}
+
+	operation := openapi3.NewOperation()
+	operation.Summary = op.summary
+	operation.Description = op.description
+	operation.Tags = append([]string(nil), op.tags...)
+	if legacy {
+		operation.Tags = append(operation.Tags, "legacy")
+	}
+	if len(op.extensions) > 0 {
+		operation.Extensions = map[string]interface{}{}
+	}
+	for key, value := range op.extensions {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+			decoded = value
+		}
+		operation.Extensions[key] = decoded
+	}
+
+	if err := g.addParameters(operation, op); err != nil {
+		return err
+	}
+	if strings.HasPrefix(path, apiPrefix) {
+		operation.Parameters = append(operation.Parameters, &openapi3.ParameterRef{Ref: "#/components/parameters/" + acceptLanguage})
+	}
+
+	// Admin groups sit behind AuthRequired and AdminRequired, even for handlers shared with public routes
+	securityNames := op.security
+	responses := op.responses
+	if isAdminPath(path) {
+		if len(securityNames) == 0 {
+			securityNames = []string{bearerAuth}
+		}
+		responses = withFailures(responses, http.StatusUnauthorized, http.StatusForbidden)
+	}
+
+	if len(securityNames) > 0 {
+		security := openapi3.NewSecurityRequirements()
+		for _, name := range securityNames {
+			security.With(openapi3.NewSecurityRequirement().Authenticate(name))
+		}
+		operation.Security = security
+	}
+
+	mediaType := "application/json"
+	if len(op.produce) > 0 {
+		mediaType = mimeType(op.produce[0])
+	}
+	operation.Responses = openapi3.NewResponses()
+	operation.Responses.Delete("default")
+	for _, res := range responses {
+		var schema *openapi3.SchemaRef
+		var err error
+		switch {
+		case res.failure && envelope:
+			schema = openapi3.NewSchemaRef("#/components/schemas/"+errorSchema, nil)
+		case res.failure && legacy:
+			schema = openapi3.NewSchemaRef("#/components/schemas/"+legacySchema, nil)
+		case envelope:
+			schema, err = g.envelopeSchema(res.typ, op.pkg)
+		default:
+			schema, err = g.typeSchema(res.typ, op.pkg)
+		}
+		if err != nil {
+			return err
+		}
+
+		description := res.desc
+		if description == "" {
+			description = http.StatusText(res.status)
+		}
+		if res.status == http.StatusNotModified {
+			// Conditional requests are answered without a body
+			operation.AddResponse(res.status, openapi3.NewResponse().WithDescription(description))
+			continue
+		}
+		if res.status >= 300 && res.status < 400 {
+			// Redirects carry their target in the Location header
+			location := &openapi3.HeaderRef{Value: &openapi3.Header{Parameter: openapi3.Parameter{
+				Description: "Target of the redirect",
+				Schema:      openapi3.NewStringSchema().NewRef(),
+			}}}
+			redirect := openapi3.NewResponse().WithDescription(description)
+			redirect.Headers = openapi3.Headers{"Location": location}
+			operation.AddResponse(res.status, redirect)
+			continue
+		}
+		contentType := mediaType
+		if res.failure && strings.HasPrefix(path, apiPrefix) {
+			contentType = "application/json"
+		}
+		operation.AddResponse(res.status, openapi3.NewResponse().
+			WithDescription(description).
+			WithContent(openapi3.NewContentWithSchemaRef(schema, []string{contentType})))
+	}
+
+	g.doc.AddOperation(path, r.method, operation)
+	return nil
+}
+
+// addParameters documents the @Param annotations as parameters or a request body
+func (g *generator) addParameters(operation *openapi3.Operation, op *operation) error {
+	var form *openapi3.Schema
+	for _, p := range op.params {
+		switch p.in {
+		case "body":
+			schema, err := g.typeSchema(p.typ, op.pkg)
+			if err != nil {
+				return err
+			}
+			body := openapi3.NewRequestBody().WithDescription(p.desc).WithJSONSchemaRef(schema)
+			body.Required = p.required
+			operation.RequestBody = &openapi3.RequestBodyRef{Value: body}
+
+		case "formData":
+			if form == nil {
+				form = openapi3.NewObjectSchema()
+			}
+			schema, err := primitiveSchema(p.typ)
+			if err != nil {
+				return err
+			}
+			schema.Description = p.desc
+			form.WithProperty(p.name, schema)
+			if p.required {
+				form.Required = append(form.Required, p.name)
+			}
+
+		case "path", "query", "header":
+			schema, err := primitiveSchema(p.typ)
+			if err != nil {
+				return err
+			}
+			if err := applyAttrs(schema, p.attrs); err != nil {
+				return fmt.Errorf("@Param %s: %w", p.name, err)
+			}
+			parameter := &openapi3.Parameter{
+				Name:        p.name,
+				In:          p.in,
+				Description: p.desc,
+				Required:    p.required || p.in == "path",
+				Schema:      schema.NewRef(),
+			}
+			operation.AddParameter(parameter)
+
+		default:
+			return fmt.Errorf("@Param %s: unknown location %q", p.name, p.in)
+		}
+	}
+
+	if form != nil {
+		body := openapi3.NewRequestBody().WithRequired(true).
+			WithContent(openapi3.NewContentWithFormDataSchema(form))
+		operation.RequestBody = &openapi3.RequestBodyRef{Value: body}
+	}
+
+	for _, name := range op.filters {
+		spec, ok := filterSpecs[qualify(name, op.pkg)]
+		if !ok {
+			return fmt.Errorf("@Filters %s: unknown filter spec", name)
+		}
+		for _, field := range spec {
+			for _, parameter := range filterParameters(field) {
+				operation.AddParameter(parameter)
+			}
+		}
+	}
+	return nil
+}
+
+// filterParameters documents a filter field as a name=value parameter for its default
+// operator and a name[op]=value parameter for each operator
+func filterParameters(field utils.FilterField) []*openapi3.Parameter {
+	ops := field.Operators()
+	names := make([]string, len(ops))
+	for i, op := range ops {
+		names[i] = string(op)
+	}
+
+	parameters := []*openapi3.Parameter{{
+		Name:        field.Name,
+		In:          "query",
+		Description: fmt.Sprintf("%s; operators: %s", field.Description, strings.Join(names, ", ")),
+		Schema:      filterSchema(field, ops[0]).NewRef(),
+	}}
+	for _, op := range ops {
+		parameters = append(parameters, &openapi3.Parameter{
+			Name:        field.Name + "[" + string(op) + "]",
+			In:          "query",
+			Description: fmt.Sprintf("%s: %s", op, field.Description),
+			Schema:      filterSchema(field, op).NewRef(),
+		})
+	}
+	return parameters
+}
+
+// filterSchema returns the schema of a filter value; in takes a comma-separated string
+func filterSchema(field utils.FilterField, op utils.FilterOp) *openapi3.Schema {
+	if op == utils.OpIn {
+		return openapi3.NewStringSchema()
+	}
+
+	var schema *openapi3.Schema
+	switch field.Type {
+	case utils.FilterInt:
+		schema = openapi3.NewIntegerSchema()
+	case utils.FilterNumber:
+		schema = openapi3.NewFloat64Schema()
+	default:
+		schema = openapi3.NewStringSchema()
+		for _, value := range field.Values {
+			schema.Enum = append(schema.Enum, value)
+		}
+	}
+	return schema
+}
+
+// envelopeSchema wraps the annotated success type in the v1 response envelope.
+// Paginated pages move their items to data and the counters to meta;
+// message bodies move their message next to the data.
+func (g *generator) envelopeSchema(expr, pkg string) (*openapi3.SchemaRef, error) {
+	body, err := g.schemas.ref(types["response.Body"])
+	if err != nil {
+		return nil, err
+	}
+
+	base, overrides := splitOverrides(expr)
+	extra := openapi3.NewObjectSchema()
+	extra.Required = []string{"success"}
+
+	switch qualify(base, pkg) {
+	case "utils.PaginationResponse":
+		items := overrides["data"]
+		if items == "" {
+			items = "[]object"
+		}
+		data, err := g.typeSchema(items, pkg)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := g.schemas.ref(types["response.PageMeta"])
+		if err != nil {
+			return nil, err
+		}
+		extra.WithPropertyRef("data", data).WithPropertyRef("meta", meta)
+		extra.Required = append(extra.Required, "data", "meta")
+
+	case "response.MessageBody":
+		extra.WithProperty("message", openapi3.NewStringSchema())
+		extra.Required = append(extra.Required, "message")
+		if overrides["data"] != "" {
+			data, err := g.typeSchema(overrides["data"], pkg)
+			if err != nil {
+				return nil, err
+			}
+			extra.WithPropertyRef("data", data)
+			extra.Required = append(extra.Required, "data")
+		}
+
+	default:
+		data, err := g.typeSchema(expr, pkg)
+		if err != nil {
+			return nil, err
+		}
+		extra.WithPropertyRef("data", data)
+		extra.Required = append(extra.Required, "data")
+	}
+
+	return openapi3.NewSchemaRef("", &openapi3.Schema{AllOf: openapi3.SchemaRefs{body, extra.NewRef()}}), nil
+}
+
+// typeSchema resolves an annotation type expression such as models.Region,
+// []models.Region, map[string]string or utils.PaginationResponse{data=[]models.Region}
+func (g *generator) typeSchema(expr, pkg string) (*openapi3.SchemaRef, error) {
+	base, overrides := splitOverrides(expr)
+	if len(overrides) > 0 {
+		schema, err := g.typeSchema(base, pkg)
+		if err != nil {
+			return nil, err
+		}
+		extra := openapi3.NewObjectSchema()
+		for _, field := range sortedKeys(overrides) {
+			property, err := g.typeSchema(overrides[field], pkg)
+			if err != nil {
+				return nil, err
+			}
+			extra.WithPropertyRef(field, property)
+		}
+		return openapi3.NewSchemaRef("", &openapi3.Schema{AllOf: openapi3.SchemaRefs{schema, extra.NewRef()}}), nil
+	}
+
+	switch {
+	case strings.HasPrefix(expr, "[]"):
+		items, err := g.typeSchema(strings.TrimPrefix(expr, "[]"), pkg)
+		if err != nil {
+			return nil, err
+		}
+		schema := openapi3.NewArraySchema()
+		schema.Items = items
+		return schema.NewRef(), nil
+
+	case strings.HasPrefix(expr, "map[string]"):
+		values, err := g.typeSchema(strings.TrimPrefix(expr, "map[string]"), pkg)
+		if err != nil {
+			return nil, err
+		}
+		schema := openapi3.NewObjectSchema()
+		schema.AdditionalProperties = openapi3.AdditionalProperties{Schema: values}
+		return schema.NewRef(), nil
+	}
+
+	if schema, err := primitiveSchema(expr); err == nil {
+		return schema.NewRef(), nil
+	}
+
+	t, ok := types[qualify(expr, pkg)]
+	if !ok {
+		return nil, fmt.Errorf("type %s is not registered in openapi.types", qualify(expr, pkg))
+	}
+	return g.schemas.ref(t)
+}
+
+// addErrorSchemas defines the error bodies of v1 and legacy routes
+func (g *generator) addErrorSchemas() error {
+	body, err := g.schemas.ref(types["response.Body"])
+	if err != nil {
+		return err
+	}
+	errorBody := openapi3.NewObjectSchema()
+	errorBody.Required = []string{"success", "error"}
+	g.doc.Components.Schemas[errorSchema] = openapi3.NewSchemaRef("", &openapi3.Schema{
+		Description: "Error envelope of /api/v1 routes",
+		AllOf:       openapi3.SchemaRefs{body, errorBody.NewRef()},
+	})
+
+	legacy := openapi3.NewObjectSchema().WithProperty("error", openapi3.NewStringSchema())
+	legacy.Description = "Error body of legacy /api routes; error details are added as extra properties"
+	legacy.Required = []string{"error"}
+	g.doc.Components.Schemas[legacySchema] = legacy.NewRef()
+	return nil
+}
+
+// isAdminPath reports whether path belongs to the admin route groups
+func isAdminPath(path string) bool {
+	return strings.HasPrefix(path, v1Prefix+"admin/") || strings.HasPrefix(path, apiPrefix+"admin/")
+}
+
+// withFailures adds the failure statuses that are not documented yet
+func withFailures(responses []responseDoc, statuses ...int) []responseDoc {
+	result := append([]responseDoc(nil), responses...)
+	for _, status := range statuses {
+		documented := false
+		for _, res := range responses {
+			documented = documented || res.status == status
+		}
+		if !documented {
+			result = append(result, responseDoc{status: status, failure: true, kind: "object", typ: "response.Body"})
+		}
+	}
+	return result
+}
+
+// primitiveSchema returns the schema of a builtin annotation type
+func primitiveSchema(typ string) (*openapi3.Schema, error) {
+	switch typ {
+	case "string":
+		return openapi3.NewStringSchema(), nil
+	case "int", "integer":
+		return openapi3.NewIntegerSchema(), nil
+	case "number":
+		return openapi3.NewFloat64Schema(), nil
+	case "bool", "boolean":
+		return openapi3.NewBoolSchema(), nil
+	case "file":
+		return openapi3.NewStringSchema().WithFormat("binary"), nil
+	case "object":
+		return openapi3.NewObjectSchema(), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// applyAttrs applies @Param attributes such as default(1), minimum(1) or Enums(a,b)
+func applyAttrs(schema *openapi3.Schema, attrs map[string]string) error {
+	for _, key := range sortedKeys(attrs) {
+		value := attrs[key]
+		switch key {
+		case "default":
+			schema.Default = typedValue(schema, value)
+		case "enums":
+			for _, item := range splitList(value) {
+				schema.Enum = append(schema.Enum, typedValue(schema, item))
+			}
+		case "minimum", "maximum":
+			var n float64
+			if _, err := fmt.Sscan(value, &n); err != nil {
+				return fmt.Errorf("%s(%s): %w", key, value, err)
+			}
+			if key == "minimum" {
+				schema.Min = &n
+			} else {
+				schema.Max = &n
+			}
+		default:
+			return fmt.Errorf("unknown attribute %s", key)
+		}
+	}
+	return nil
+}
+
+// typedValue converts an annotation literal to the schema type
+func typedValue(schema *openapi3.Schema, value string) interface{} {
+	if schema.Type.Is(openapi3.TypeInteger) || schema.Type.Is(openapi3.TypeNumber) || schema.Type.Is(openapi3.TypeBoolean) {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+			return decoded
+		}
+	}
+	return value
+}
+
+// splitOverrides splits "Type{field=Type,...}" into the base type and its field overrides
+func splitOverrides(expr string) (string, map[string]string) {
+	open := strings.Index(expr, "{")
+	if open < 0 || !strings.HasSuffix(expr, "}") {
+		return expr, nil
+	}
+	overrides := map[string]string{}
+	for _, pair := range strings.Split(expr[open+1:len(expr)-1], ",") {
+		field, typ, ok := strings.Cut(pair, "=")
+		if ok {
+			overrides[strings.TrimSpace(field)] = strings.TrimSpace(typ)
+		}
+	}
+	return expr[:open], overrides
+}
+
+// qualify prefixes unqualified type names with the package of the handler
+func qualify(name, pkg string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// mimeType expands the swag shorthands used in @Accept and @Produce
+func mimeType(value string) string {
+	switch value {
+	case "json":
+		return "application/json"
+	case "plain":
+		return "text/plain"
+	case "html":
+		return "text/html"
+	case "mpfd":
+		return "multipart/form-data"
+	case "png":
+		return "image/png"
+	case "svg":
+		return "image/svg+xml"
+	case "pdf":
+		return "application/pdf"
+	case "zip":
+		return "application/zip"
+	}
+	return value
+}
+
+// sortedKeys returns the keys of m in order, keeping the output deterministic
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,31 @@
+package openapi
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"tourism_recommendor/docs"
+)
+
+// TestGeneratedDocumentUpToDate fails when docs/openapi.json was not regenerated
+// after a handler annotation changed (run `go generate ./docs`)
+func TestGeneratedDocumentUpToDate(t *testing.T) {
+	dirs := make([]string, 0, len(SourceDirs))
+	for _, dir := range SourceDirs {
+		dirs = append(dirs, filepath.Join("..", dir))
+	}
+
+	doc, err := Generate(dirs...)
+	if err != nil {
+		t.Fatalf("failed to generate document: %v", err)
+	}
+	data, err := Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to encode document: %v", err)
+	}
+
+	if !bytes.Equal(data, docs.OpenAPI) {
+		t.Fatal("docs/openapi.json is out of date; run `go generate ./docs`")
+	}
+}
@@ -0,0 +1,226 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaBuilder reflects Go types into component schemas.
+// Named structs become components so recursive models (recommendor <-> destination) stay finite.
+type schemaBuilder struct {
+	components openapi3.Schemas
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		components: openapi3.Schemas{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+// ref returns a schema for t, registering struct types as components
+func (b *schemaBuilder) ref(t reflect.Type) (*openapi3.SchemaRef, error) {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	if t.Kind() == reflect.Struct && t != timeType {
+		name, err := b.component(t)
+		if err != nil {
+			return nil, err
+		}
+		ref := openapi3.NewSchemaRef("#/components/schemas/"+name, nil)
+		if nullable {
+			// $ref siblings are ignored, so nullable refs go through allOf
+			return openapi3.NewSchemaRef("", &openapi3.Schema{
+				Nullable: true,
+				AllOf:    openapi3.SchemaRefs{ref},
+			}), nil
+		}
+		return ref, nil
+	}
+
+	schema, err := b.inline(t)
+	if err != nil {
+		return nil, err
+	}
+	schema.Nullable = schema.Nullable || nullable
+	return schema.NewRef(), nil
+}
+
+// inline builds the schema of a non-struct type
+func (b *schemaBuilder) inline(t reflect.Type) (*openapi3.Schema, error) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return openapi3.NewBoolSchema(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return openapi3.NewIntegerSchema(), nil
+	case reflect.Int64:
+		return openapi3.NewInt64Schema(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return openapi3.NewIntegerSchema().WithMin(0), nil
+	case reflect.Float32, reflect.Float64:
+		return openapi3.NewFloat64Schema(), nil
+	case reflect.String:
+		return openapi3.NewStringSchema(), nil
+	case reflect.Interface:
+		// interface{} carries arbitrary JSON
+		return &openapi3.Schema{}, nil
+	case reflect.Struct:
+		if t == timeType {
+			return openapi3.NewDateTimeSchema(), nil
+		}
+	case reflect.Slice, reflect.Array:
+		items, err := b.ref(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema := openapi3.NewArraySchema()
+		schema.Items = items
+		return schema, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key of %s must be a string", t)
+		}
+		values, err := b.ref(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema := openapi3.NewObjectSchema()
+		schema.AdditionalProperties = openapi3.AdditionalProperties{Schema: values}
+		return schema, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// component registers the struct t under its package-qualified name and returns the name
+func (b *schemaBuilder) component(t reflect.Type) (string, error) {
+	if name, ok := b.names[t]; ok {
+		return name, nil
+	}
+
+	name := componentName(t)
+	b.names[t] = name
+
+	schema := openapi3.NewObjectSchema()
+	b.components[name] = schema.NewRef()
+	if err := b.addFields(schema, t); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// addFields adds the JSON fields of t (including embedded structs) to schema
+func (b *schemaBuilder) addFields(schema *openapi3.Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := b.addFields(schema, embedded); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, err := b.ref(field.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		applyBinding(property, field.Tag.Get("binding"))
+		schema.WithPropertyRef(name, property)
+
+		if bindingRequired(field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return nil
+}
+
+// componentName names the schema of t after its package and type, e.g. models.Recommendor
+func componentName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return pkg + "." + t.Name()
+}
+
+// bindingRequired reports whether a gin binding tag marks the field as required
+func bindingRequired(tag string) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBinding copies the validator rules that have an OpenAPI equivalent onto an inline schema.
+// Rules after dive apply to the items of an array.
+func applyBinding(property *openapi3.SchemaRef, tag string) {
+	schema := property.Value
+	if tag == "" || property.Ref != "" || schema == nil {
+		return
+	}
+
+	isString := schema.Type.Is(openapi3.TypeString)
+	for _, rule := range strings.Split(tag, ",") {
+		key, param, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			if schema.Items != nil {
+				_, items, _ := strings.Cut(tag, "dive,")
+				applyBinding(schema.Items, items)
+			}
+			return
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "min", "max":
+			var n uint64
+			if _, err := fmt.Sscan(param, &n); err != nil {
+				continue
+			}
+			switch {
+			case isString && key == "min":
+				schema.MinLength = n
+			case isString && key == "max":
+				schema.MaxLength = &n
+			case key == "min":
+				schema.Min = openapi3.Float64Ptr(float64(n))
+			default:
+				schema.Max = openapi3.Float64Ptr(float64(n))
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package openapi
+
+import (
+	"reflect"
+
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
+)
+
+// types maps the type names used in annotations (package.Type) to Go types.
+// A handler annotation naming a type that is missing here fails generation.
+var types = registry(
+	controllers.AdminInfo{},
+	controllers.ChangePasswordRequest{},
+	controllers.CreateDestinationRequest{},
+	controllers.CreateExportRequest{},
+	controllers.CreateRecommendorRequest{},
+	controllers.CreateRegionRequest{},
+	controllers.CreateWxappSceneRequest{},
+	controllers.DatabaseStatsResponse{},
+	controllers.DailyScans{},
+	controllers.DestinationTranslationRequest{},
+	controllers.ExportJobResponse{},
+	controllers.HealthResponse{},
+	controllers.LoginRequest{},
+	controllers.LoginResponse{},
+	controllers.RecommendorScans{},
+	controllers.RecommendorTranslationRequest{},
+	controllers.ShortLinkResponse{},
+	controllers.TranslationsResponse{},
+	controllers.UpdateDestinationRequest{},
+	controllers.UpdateRecommendorRequest{},
+	controllers.UpdateRegionRequest{},
+	controllers.UpdateShortLinkRequest{},
+	controllers.WxappLoginRequest{},
+	controllers.WxappLoginResponse{},
+	controllers.WxappSceneResponse{},
+	i18n.EnumOption{},
+	models.Destination{},
+	models.ExportJob{},
+	models.QRCodeJob{},
+	models.Recommendor{},
+	models.Region{},
+	models.ShortLink{},
+	models.Tourist{},
+	models.WxappScene{},
+	response.Body{},
+	response.MessageBody{},
+	response.PageMeta{},
+	utils.PaginationResponse{},
+	utils.UploadResult{},
+)
+
+// filterSpecs maps the names used in @Filters annotations to filter specs
+var filterSpecs = map[string]utils.FilterSpec{
+	"controllers.DestinationFilters": controllers.DestinationFilters,
+	"controllers.QRCodeJobFilters":   controllers.QRCodeJobFilters,
+	"controllers.RecommendorFilters": controllers.RecommendorFilters,
+	"controllers.RegionFilters":      controllers.RegionFilters,
+	"controllers.WxappSceneFilters":  controllers.WxappSceneFilters,
+}
+
+func registry(values ...interface{}) map[string]reflect.Type {
+	result := make(map[string]reflect.Type, len(values))
+	for _, value := range values {
+		t := reflect.TypeOf(value)
+		result[componentName(t)] = t
+	}
+	return result
+}
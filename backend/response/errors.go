@@ -0,0 +1,177 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"tourism_recommendor/i18n"
+)
+
+// Code is a stable, machine-readable error code returned to API clients
+type Code string
+
+// APIError is an error that maps to an HTTP status, an error code and a localized message
+type APIError struct {
+	Code    Code
+	Status  int
+	Fields  []FieldError
+	Details map[string]interface{}
+	cause   error
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %v", e.Code, e.cause)
+	}
+	return string(e.Code)
+}
+
+// Unwrap returns the underlying cause
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+// Is matches API errors by code so callers can use errors.Is against catalog entries
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// MessageKey returns the message catalog key of the error
+func (e *APIError) MessageKey() string {
+	return "error." + string(e.Code)
+}
+
+// Message returns the localized message of the error
+func (e *APIError) Message(locale string) string {
+	return i18n.T(locale, e.MessageKey())
+}
+
+// WithCause returns a copy of the error carrying the underlying cause.
+// The cause is logged but never sent to clients.
+func (e *APIError) WithCause(err error) *APIError {
+	clone := *e
+	clone.cause = err
+	return &clone
+}
+
+// WithDetail returns a copy of the error with an extra client-visible detail
+func (e *APIError) WithDetail(key string, value interface{}) *APIError {
+	clone := *e
+	clone.Details = make(map[string]interface{}, len(e.Details)+1)
+	for k, v := range e.Details {
+		clone.Details[k] = v
+	}
+	clone.Details[key] = value
+	return &clone
+}
+
+// WithFields returns a copy of the error with field-level validation errors
+func (e *APIError) WithFields(fields []FieldError) *APIError {
+	clone := *e
+	clone.Fields = fields
+	return &clone
+}
+
+// newError defines a catalog entry; its messages live in the i18n catalogs under "error.<code>"
+func newError(code Code, status int) *APIError {
+	return &APIError{Code: code, Status: status}
+}
+
+// Error catalog. Codes are part of the public API contract and must not change.
+var (
+	// Generic
+	ErrBadRequest     = newError("BAD_REQUEST", http.StatusBadRequest)
+	ErrInvalidBody    = newError("INVALID_BODY", http.StatusBadRequest)
+	ErrValidation     = newError("VALIDATION_FAILED", http.StatusBadRequest)
+	ErrInvalidID      = newError("INVALID_ID", http.StatusBadRequest)
+	ErrInvalidSort    = newError("INVALID_SORT", http.StatusBadRequest)
+	ErrInvalidCursor  = newError("INVALID_CURSOR", http.StatusBadRequest)
+	ErrInvalidFilter  = newError("INVALID_FILTER", http.StatusBadRequest)
+	ErrInvalidFields  = newError("INVALID_FIELDS", http.StatusBadRequest)
+	ErrRouteNotFound  = newError("ROUTE_NOT_FOUND", http.StatusNotFound)
+	ErrInternal       = newError("INTERNAL_ERROR", http.StatusInternalServerError)
+	ErrDatabase       = newError("DATABASE_ERROR", http.StatusInternalServerError)
+	ErrUnavailable    = newError("SERVICE_UNAVAILABLE", http.StatusServiceUnavailable)
+	ErrQRCodeGenerate = newError("QRCODE_GENERATION_FAILED", http.StatusInternalServerError)
+
+	// Authentication and authorization
+	ErrAuthHeaderMissing  = newError("AUTH_HEADER_MISSING", http.StatusUnauthorized)
+	ErrAuthHeaderInvalid  = newError("AUTH_HEADER_INVALID", http.StatusUnauthorized)
+	ErrTokenInvalid       = newError("TOKEN_INVALID", http.StatusUnauthorized)
+	ErrUnauthorized       = newError("UNAUTHORIZED", http.StatusUnauthorized)
+	ErrInvalidCredentials = newError("INVALID_CREDENTIALS", http.StatusUnauthorized)
+	ErrAccountInactive    = newError("ACCOUNT_INACTIVE", http.StatusForbidden)
+	ErrAdminRequired      = newError("ADMIN_REQUIRED", http.StatusForbidden)
+	ErrSuperAdminRequired = newError("SUPER_ADMIN_REQUIRED", http.StatusForbidden)
+	ErrTouristRequired    = newError("TOURIST_REQUIRED", http.StatusForbidden)
+	ErrWxappCodeInvalid   = newError("WXAPP_CODE_INVALID", http.StatusUnauthorized)
+	ErrWxappUnavailable   = newError("WXAPP_LOGIN_UNAVAILABLE", http.StatusServiceUnavailable)
+	ErrOldPasswordWrong   = newError("OLD_PASSWORD_INCORRECT", http.StatusBadRequest)
+	ErrPasswordUnchanged  = newError("PASSWORD_UNCHANGED", http.StatusBadRequest)
+
+	// Resources
+	ErrUserNotFound        = newError("USER_NOT_FOUND", http.StatusNotFound)
+	ErrRegionNotFound      = newError("REGION_NOT_FOUND", http.StatusNotFound)
+	ErrRegionInUse         = newError("REGION_IN_USE", http.StatusBadRequest)
+	ErrRecommendorNotFound = newError("RECOMMENDOR_NOT_FOUND", http.StatusNotFound)
+	ErrIDNumberExists      = newError("ID_NUMBER_EXISTS", http.StatusBadRequest)
+	ErrDestinationNotFound = newError("DESTINATION_NOT_FOUND", http.StatusNotFound)
+
+	// Translations
+	ErrUnsupportedLocale       = newError("UNSUPPORTED_LOCALE", http.StatusBadRequest)
+	ErrTranslationSourceLocale = newError("TRANSLATION_SOURCE_LOCALE", http.StatusBadRequest)
+	ErrTranslationNotFound     = newError("TRANSLATION_NOT_FOUND", http.StatusNotFound)
+
+	// Uploads
+	ErrFileMissing  = newError("FILE_MISSING", http.StatusBadRequest)
+	ErrFileRejected = newError("FILE_REJECTED", http.StatusBadRequest)
+
+	// QR codes
+	ErrInvalidQRCode     = newError("INVALID_QRCODE_OPTIONS", http.StatusBadRequest)
+	ErrQRCodeJobNotFound = newError("QRCODE_JOB_NOT_FOUND", http.StatusNotFound)
+
+	// Exports
+	ErrExportEmpty    = newError("EXPORT_EMPTY", http.StatusBadRequest)
+	ErrExportNotFound = newError("EXPORT_NOT_FOUND", http.StatusNotFound)
+	ErrExportNotReady = newError("EXPORT_NOT_READY", http.StatusConflict)
+
+	// Short links and scans
+	ErrShortLinkNotFound = newError("SHORT_LINK_NOT_FOUND", http.StatusNotFound)
+	ErrInvalidDateRange  = newError("INVALID_DATE_RANGE", http.StatusBadRequest)
+
+	// Mini Program scenes
+	ErrSceneNotFound = newError("SCENE_NOT_FOUND", http.StatusNotFound)
+)
+
+// Catalog lists every error code the API can return
+func Catalog() []*APIError {
+	return []*APIError{
+		ErrBadRequest, ErrInvalidBody, ErrValidation, ErrInvalidID, ErrRouteNotFound,
+		ErrInvalidSort, ErrInvalidCursor, ErrInvalidFilter, ErrInvalidFields,
+		ErrInternal, ErrDatabase, ErrUnavailable, ErrQRCodeGenerate,
+		ErrAuthHeaderMissing, ErrAuthHeaderInvalid, ErrTokenInvalid, ErrUnauthorized,
+		ErrInvalidCredentials, ErrAccountInactive, ErrAdminRequired, ErrSuperAdminRequired,
+		ErrTouristRequired, ErrWxappCodeInvalid, ErrWxappUnavailable,
+		ErrOldPasswordWrong, ErrPasswordUnchanged,
+		ErrUserNotFound, ErrRegionNotFound, ErrRegionInUse, ErrRecommendorNotFound,
+		ErrIDNumberExists, ErrDestinationNotFound,
+		ErrUnsupportedLocale, ErrTranslationSourceLocale, ErrTranslationNotFound,
+		ErrFileMissing, ErrFileRejected,
+		ErrInvalidQRCode, ErrQRCodeJobNotFound,
+		ErrExportEmpty, ErrExportNotFound, ErrExportNotReady,
+		ErrShortLinkNotFound, ErrInvalidDateRange,
+		ErrSceneNotFound,
+	}
+}
+
+// asAPIError converts any error into an API error; unknown errors become internal errors
+func asAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	return ErrInternal.WithCause(err)
+}
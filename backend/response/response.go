@@ -0,0 +1,194 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+
+	"tourism_recommendor/i18n"
+	"tourism_recommendor/tracing"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// envelopeKey marks requests whose responses use the envelope
+const envelopeKey = "response_envelope"
+
+// requestIDHeader is the response header set by the request ID middleware
+const requestIDHeader = "X-Request-ID"
+
+// Body is the response envelope used by the /api/v1 routes
+type Body struct {
+	Success   bool        `json:"success"`
+	Data      interface{} `json:"data,omitempty"`
+	Message   string      `json:"message,omitempty"`
+	Meta      *PageMeta   `json:"meta,omitempty"`
+	Error     *ErrorBody  `json:"error,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	TraceID   string      `json:"trace_id,omitempty"`
+}
+
+// ErrorBody describes a failed request inside the envelope
+type ErrorBody struct {
+	Code    Code                   `json:"code"`
+	Message string                 `json:"message"`
+	Fields  []FieldError           `json:"fields,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// MessageBody is the legacy body of Message responses
+type MessageBody struct {
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// PageMeta holds pagination information of list responses; cursor pages
+// omit the page number and include the total only on request
+type PageMeta struct {
+	Total      *int64 `json:"total,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	TotalPages *int   `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Envelope enables the response envelope for every route in the group.
+// Routes without it (the legacy /api routes) keep their original response shapes.
+func Envelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		EnableEnvelope(c)
+		c.Next()
+	}
+}
+
+// EnableEnvelope switches the current request to envelope responses
+func EnableEnvelope(c *gin.Context) {
+	c.Set(envelopeKey, true)
+}
+
+// UsesEnvelope reports whether the current route responds with the envelope
+func UsesEnvelope(c *gin.Context) bool {
+	return c.GetBool(envelopeKey)
+}
+
+// Locale returns the locale negotiated for the request
+func Locale(c *gin.Context) string {
+	return i18n.FromContext(c.Request.Context())
+}
+
+// LegacyLocale is the language of the messages of the legacy /api routes, which keep
+// their original English strings whatever the request asks for
+const LegacyLocale = i18n.LocaleEN
+
+// messageLocale returns the locale of response messages: the negotiated locale with the
+// envelope, LegacyLocale on legacy routes
+func messageLocale(c *gin.Context) string {
+	if !UsesEnvelope(c) {
+		return LegacyLocale
+	}
+	return Locale(c)
+}
+
+// OK responds with 200 and the data (bare on legacy routes)
+func OK(c *gin.Context, data interface{}) {
+	success(c, http.StatusOK, data, "", nil, data)
+}
+
+// Created responds with 201 and the created resource (bare on legacy routes)
+func Created(c *gin.Context, data interface{}) {
+	success(c, http.StatusCreated, data, "", nil, data)
+}
+
+// Accepted responds with 202 and the resource tracking work that continues in the background
+func Accepted(c *gin.Context, data interface{}) {
+	success(c, http.StatusAccepted, data, "", nil, data)
+}
+
+// Message responds with 200, the localized message for messageKey and optional data
+// (legacy routes receive {"message": ..., "data": ...} in LegacyLocale)
+func Message(c *gin.Context, messageKey string, data interface{}) {
+	message := i18n.T(messageLocale(c), messageKey)
+	success(c, http.StatusOK, data, message, nil, MessageBody{Message: message, Data: data})
+}
+
+// Paginated responds with 200 and a page of results; the envelope moves the
+// pagination fields into meta while legacy routes receive the page as-is
+func Paginated(c *gin.Context, page *utils.PaginationResponse) {
+	meta := &PageMeta{
+		Total:      page.Total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+	success(c, http.StatusOK, page.Data, "", meta, page)
+}
+
+// Error responds with the status, code and localized message of err and aborts the request.
+// Errors that are not *APIError are reported as INTERNAL_ERROR; causes are logged, never sent.
+func Error(c *gin.Context, err error) {
+	apiErr := asAPIError(err)
+	if apiErr.cause != nil {
+		_ = c.Error(apiErr.cause)
+	}
+
+	locale := messageLocale(c)
+	if !UsesEnvelope(c) {
+		c.AbortWithStatusJSON(apiErr.Status, legacyError(apiErr, locale))
+		return
+	}
+
+	c.AbortWithStatusJSON(apiErr.Status, Body{
+		Success: false,
+		Error: &ErrorBody{
+			Code:    apiErr.Code,
+			Message: apiErr.Message(locale),
+			Fields:  apiErr.Fields,
+			Details: apiErr.Details,
+		},
+		RequestID: c.Writer.Header().Get(requestIDHeader),
+		TraceID:   tracing.TraceID(c.Request.Context()),
+	})
+}
+
+// BadRequest responds with the API error for a failed ShouldBind* call
+func BadRequest(c *gin.Context, err error) {
+	Error(c, BindError(err, messageLocale(c)))
+}
+
+// success writes either the envelope or the legacy body
+func success(c *gin.Context, status int, data interface{}, message string, meta *PageMeta, legacy interface{}) {
+	if !UsesEnvelope(c) {
+		c.JSON(status, legacy)
+		return
+	}
+
+	c.JSON(status, Body{
+		Success:   true,
+		Data:      data,
+		Message:   message,
+		Meta:      meta,
+		RequestID: c.Writer.Header().Get(requestIDHeader),
+		TraceID:   tracing.TraceID(c.Request.Context()),
+	})
+}
+
+// legacyError renders an API error in the original {"error": "..."} shape
+func legacyError(apiErr *APIError, locale string) gin.H {
+	message := apiErr.Message(locale)
+	if len(apiErr.Fields) > 0 {
+		parts := make([]string, 0, len(apiErr.Fields))
+		for _, field := range apiErr.Fields {
+			parts = append(parts, field.Message)
+		}
+		message += ": " + strings.Join(parts, "; ")
+	}
+
+	body := gin.H{"error": message}
+	for key, value := range apiErr.Details {
+		body[key] = value
+	}
+	return body
+}
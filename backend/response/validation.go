@@ -0,0 +1,77 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"tourism_recommendor/i18n"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Report JSON field names instead of Go struct field names
+	v.RegisterTagNameFunc(jsonFieldName)
+
+	// Translate validator messages into every supported locale
+	if err := i18n.RegisterValidator(v); err != nil {
+		slog.Error("failed to register validation translations", "error", err)
+	}
+}
+
+// jsonFieldName returns the JSON name of a struct field
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// BindError converts an error returned by gin's ShouldBind* into an API error.
+// Validator failures become VALIDATION_FAILED with per-field details in the locale.
+func BindError(err error, locale string) *APIError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		trans := i18n.ValidationTranslator(locale)
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fe.Field(),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: fe.Translate(trans),
+			})
+		}
+		return ErrValidation.WithCause(err).WithFields(fields)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		field := FieldError{Field: typeErr.Field, Rule: "type", Param: typeErr.Type.String()}
+		field.Message = i18n.T(locale, "validation.type", field.Field, field.Param)
+		return ErrValidation.WithCause(err).WithFields([]FieldError{field})
+	}
+
+	return ErrInvalidBody.WithCause(err)
+}
@@ -0,0 +1,503 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/docs"
+	"tourism_recommendor/middleware"
+	"tourism_recommendor/models"
+	"tourism_recommendor/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// pngPixel is a valid 1x1 PNG used for the image upload endpoints
+var pngPixel = []byte{
+	0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
+	0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x08, 0x06, 0x00, 0x00, 0x00, 0x1f, 0x15, 0xc4,
+	0x89, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x44, 0x41, 0x54, 0x78, 0x9c, 0x63, 0xf8, 0x0f, 0x00, 0x00,
+	0x01, 0x01, 0x00, 0x05, 0x18, 0xd8, 0x4d, 0x00, 0x00, 0x00, 0x00, 0x49, 0x45, 0x4e, 0x44, 0xae,
+	0x42, 0x60, 0x82,
+}
+
+// contractCase is one request of the contract suite. Paths may reference values captured
+// by earlier cases as {name}; invalid cases send requests the document rejects on purpose.
+// Waiting cases are sent once the background exports have finished.
+type contractCase struct {
+	name    string
+	method  string
+	path    string
+	body    interface{}
+	upload  string
+	auth    bool
+	tourist bool // sends the tourist token instead of the admin token
+	status  int
+	invalid bool
+	capture string
+	wait    bool
+}
+
+// contractServer is the full application wired to a throwaway SQLite database
+type contractServer struct {
+	router     *gin.Engine
+	violations []middleware.ContractViolation
+	exercised  map[string]bool
+}
+
+func newContractServer(t *testing.T) *contractServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "contract.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := AutoMigrate(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	previousDB := config.DB
+	uploadDir, avatarDir, imageDir, documentDir, qrCodeDir := utils.UploadDir, utils.AvatarDir, utils.ImageDir, utils.DocumentDir, utils.QRCodeDir
+	exportDir := utils.ExportDir
+	config.DB = db
+	utils.UploadDir = filepath.Join(dir, "uploads")
+	utils.AvatarDir = filepath.Join(utils.UploadDir, "avatars")
+	utils.ImageDir = filepath.Join(utils.UploadDir, "images")
+	utils.DocumentDir = filepath.Join(utils.UploadDir, "documents")
+	utils.QRCodeDir = filepath.Join(utils.UploadDir, "qrcodes")
+	utils.ExportDir = filepath.Join(dir, "exports")
+	t.Cleanup(func() {
+		controllers.WaitForExports(context.Background())
+		config.DB = previousDB
+		utils.UploadDir, utils.AvatarDir, utils.ImageDir, utils.DocumentDir, utils.QRCodeDir = uploadDir, avatarDir, imageDir, documentDir, qrCodeDir
+		utils.ExportDir = exportDir
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := utils.InitUploadDirectories(); err != nil {
+		t.Fatalf("failed to create upload directories: %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.QRCode.WxAppSecret = ""
+	cfg.OpenAPI.Validation = "off"
+	utils.SetJWTSecret(cfg.JWT.Secret.Value())
+	utils.SetTokenExpiration(cfg.JWT.Expiration)
+	utils.SetTouristTokenExpiration(cfg.JWT.TouristExpiration)
+
+	if err := SeedDatabase(db, cfg.Admin); err != nil {
+		t.Fatalf("failed to seed database: %v", err)
+	}
+
+	s := &contractServer{router: gin.New(), exercised: map[string]bool{}}
+	validator, err := middleware.ContractValidator(docs.OpenAPI, func(c *gin.Context, violation middleware.ContractViolation) {
+		s.violations = append(s.violations, violation)
+	})
+	if err != nil {
+		t.Fatalf("failed to load docs/openapi.json: %v", err)
+	}
+
+	SetupMiddleware(s.router, cfg)
+	s.router.Use(func(c *gin.Context) {
+		if c.FullPath() != "" {
+			s.exercised[c.Request.Method+" "+middleware.OpenAPIPath(c.FullPath())] = true
+		}
+		c.Next()
+	})
+	s.router.Use(validator)
+	SetupRoutes(s.router, db, cfg)
+
+	return s
+}
+
+// do sends one case and returns the response, with {name} placeholders resolved from values
+func (s *contractServer) do(t *testing.T, tc contractCase, values map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	pairs := make([]string, 0, len(values)*2)
+	for name, value := range values {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	path := strings.NewReplacer(pairs...).Replace(tc.path)
+
+	var body bytes.Buffer
+	contentType := ""
+	switch {
+	case tc.upload != "":
+		writer := multipart.NewWriter(&body)
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, tc.upload))
+		header.Set("Content-Type", mime.TypeByExtension(filepath.Ext(tc.upload)))
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			t.Fatalf("failed to build multipart body: %v", err)
+		}
+		if strings.HasSuffix(tc.upload, ".png") {
+			part.Write(pngPixel)
+		} else {
+			part.Write([]byte("contract test\n"))
+		}
+		writer.Close()
+		contentType = writer.FormDataContentType()
+	case tc.body != nil:
+		if err := json.NewEncoder(&body).Encode(tc.body); err != nil {
+			t.Fatalf("failed to encode body: %v", err)
+		}
+		contentType = "application/json"
+	}
+
+	req := httptest.NewRequest(tc.method, path, &body)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if tc.auth {
+		req.Header.Set("Authorization", "Bearer "+values["token"])
+	}
+	if tc.tourist {
+		req.Header.Set("Authorization", "Bearer "+values["tourist_token"])
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// capturedValue reads field from a v1 envelope (data.field, or data[0].field for lists) or
+// a legacy body (field)
+func capturedValue(t *testing.T, w *httptest.ResponseRecorder, field string) string {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	switch data := body["data"].(type) {
+	case map[string]interface{}:
+		body = data
+	case []interface{}:
+		if len(data) > 0 {
+			body, _ = data[0].(map[string]interface{})
+		}
+	}
+	switch value := body[field].(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	}
+	t.Fatalf("response has no %q field: %s", field, w.Body.String())
+	return ""
+}
+
+// TestAPIContract drives every documented operation through the router and fails when a
+// request or response drifts from docs/openapi.json. Keep a case per operation in the
+// table below when adding routes.
+func TestAPIContract(t *testing.T) {
+	s := newContractServer(t)
+
+	recommendor := func(idNumber string) gin.H {
+		return gin.H{
+			"name":           "张三",
+			"gender":         "male",
+			"age":            30,
+			"id_number":      idNumber,
+			"bio":            "资深导游",
+			"valid_from":     "2024-01-01T00:00:00Z",
+			"valid_until":    "2030-01-01T00:00:00Z",
+			"province_code":  "110000",
+			"city_code":      "110100",
+			"district_code":  "110101",
+			"region_address": "北京市/北京市/东城区",
+		}
+	}
+	destination := func(recommendorID string) string {
+		return `{"recommendor_id": ` + recommendorID + `, "name": "故宫", "category": "scenic", "rating": 4.5}`
+	}
+
+	cases := []contractCase{
+		// Probes, metrics and reference data
+		{name: "liveness", method: "GET", path: "/healthz", status: 200},
+		{name: "readiness", method: "GET", path: "/readyz", status: 200},
+		{name: "v1 health", method: "GET", path: "/api/v1/health", status: 200},
+		{name: "metrics", method: "GET", path: "/metrics", status: 200},
+		{name: "openapi document", method: "GET", path: "/api/v1/openapi.json", status: 200},
+		{name: "enums", method: "GET", path: "/api/v1/enums", status: 200},
+
+		// Authentication
+		{name: "login invalid body", method: "POST", path: "/api/v1/auth/login", body: gin.H{}, status: 400, invalid: true},
+		{name: "login wrong password", method: "POST", path: "/api/v1/auth/login", body: gin.H{"username": "admin", "password": "wrong"}, status: 401},
+		{name: "legacy login", method: "POST", path: "/api/auth/login", body: gin.H{"username": "admin", "password": "admin123456"}, status: 200},
+		{name: "login", method: "POST", path: "/api/v1/auth/login", body: gin.H{"username": "admin", "password": "admin123456"}, status: 200, capture: "token"},
+		{name: "me unauthenticated", method: "GET", path: "/api/v1/auth/me", status: 401},
+		{name: "me", method: "GET", path: "/api/v1/auth/me", auth: true, status: 200},
+		{name: "legacy me", method: "GET", path: "/api/auth/me", auth: true, status: 200},
+		{name: "refresh token", method: "POST", path: "/api/v1/auth/refresh-token", auth: true, status: 200},
+		{name: "legacy refresh token", method: "POST", path: "/api/auth/refresh-token", auth: true, status: 200},
+		{name: "change password", method: "PUT", path: "/api/v1/auth/change-password", auth: true, body: gin.H{"old_password": "admin123456", "new_password": "changed123"}, status: 200},
+		{name: "legacy change password", method: "PUT", path: "/api/auth/change-password", auth: true, body: gin.H{"old_password": "changed123", "new_password": "admin123456"}, status: 200},
+		{name: "logout", method: "POST", path: "/api/v1/auth/logout", auth: true, status: 200},
+		{name: "legacy logout", method: "POST", path: "/api/auth/logout", auth: true, status: 200},
+
+		// Uploads
+		{name: "upload avatar", method: "POST", path: "/api/v1/upload/avatar", upload: "avatar.png", status: 200},
+		{name: "upload image", method: "POST", path: "/api/v1/upload/image", upload: "image.png", status: 200},
+		{name: "upload document", method: "POST", path: "/api/v1/upload/document", upload: "notes.txt", status: 200},
+
+		// Regions
+		{name: "create region unauthenticated", method: "POST", path: "/api/v1/admin/regions", body: gin.H{"name": "华北"}, status: 401},
+		{name: "create region invalid", method: "POST", path: "/api/v1/admin/regions", auth: true, body: gin.H{}, status: 400, invalid: true},
+		{name: "create region", method: "POST", path: "/api/v1/admin/regions", auth: true, body: gin.H{"name": "华北", "description": "北方地区"}, status: 201, capture: "region"},
+		{name: "legacy create region", method: "POST", path: "/api/admin/regions", auth: true, body: gin.H{"name": "华南"}, status: 201, capture: "legacy_region"},
+		{name: "create region used by recommendors", method: "POST", path: "/api/v1/admin/regions", auth: true, body: gin.H{"name": "北京市"}, status: 201, capture: "used_region"},
+		{name: "list regions", method: "GET", path: "/api/v1/admin/regions?page=1&page_size=5&sort_order=desc", auth: true, status: 200},
+		{name: "legacy list regions", method: "GET", path: "/api/admin/regions", auth: true, status: 200},
+		{name: "get region", method: "GET", path: "/api/v1/admin/regions/{region}", auth: true, status: 200},
+		{name: "get region not found", method: "GET", path: "/api/v1/admin/regions/999999", auth: true, status: 404},
+		{name: "get region invalid id", method: "GET", path: "/api/v1/admin/regions/abc", auth: true, status: 400, invalid: true},
+		{name: "legacy get region", method: "GET", path: "/api/admin/regions/{legacy_region}", auth: true, status: 200},
+		{name: "update region", method: "PUT", path: "/api/v1/admin/regions/{region}", auth: true, body: gin.H{"description": "京津冀"}, status: 200},
+		{name: "legacy update region", method: "PUT", path: "/api/admin/regions/{legacy_region}", auth: true, body: gin.H{"name": "华南地区"}, status: 200},
+
+		// Recommendors
+		{name: "create recommendor invalid", method: "POST", path: "/api/v1/admin/recommendors", auth: true, body: gin.H{"name": "张三", "gender": "unknown"}, status: 400, invalid: true},
+		{name: "create recommendor", method: "POST", path: "/api/v1/admin/recommendors", auth: true, body: recommendor("110101199001011234"), status: 201, capture: "recommendor"},
+		{name: "legacy create recommendor", method: "POST", path: "/api/admin/recommendors", auth: true, body: recommendor("110101199001015678"), status: 201, capture: "legacy_recommendor"},
+		{name: "create recommendor duplicate", method: "POST", path: "/api/v1/admin/recommendors", auth: true, body: recommendor("110101199001011234"), status: 400},
+		{name: "admin list recommendors", method: "GET", path: "/api/v1/admin/recommendors?gender=male&min_age=18", auth: true, status: 200},
+		{name: "legacy admin list recommendors", method: "GET", path: "/api/admin/recommendors", auth: true, status: 200},
+		{name: "admin get recommendor", method: "GET", path: "/api/v1/admin/recommendors/{recommendor}", auth: true, status: 200},
+		{name: "legacy admin get recommendor", method: "GET", path: "/api/admin/recommendors/{legacy_recommendor}", auth: true, status: 200},
+		{name: "update recommendor", method: "PUT", path: "/api/v1/admin/recommendors/{recommendor}", auth: true, body: gin.H{"bio": "金牌导游", "age": 31}, status: 200},
+		{name: "legacy update recommendor", method: "PUT", path: "/api/admin/recommendors/{legacy_recommendor}", auth: true, body: gin.H{"phone": "13900000000"}, status: 200},
+		{name: "regenerate qrcodes", method: "POST", path: "/api/v1/admin/recommendors/{recommendor}/qrcodes", auth: true, status: 200},
+		{name: "legacy regenerate qrcodes", method: "POST", path: "/api/admin/recommendors/{legacy_recommendor}/qrcodes", auth: true, status: 200},
+		{name: "recommendor short links", method: "GET", path: "/api/v1/admin/recommendors/{recommendor}/short-links", auth: true, status: 200, capture: "short_link"},
+		{name: "recommendor short links not found", method: "GET", path: "/api/v1/admin/recommendors/999999/short-links", auth: true, status: 404},
+		{name: "update short link", method: "PUT", path: "/api/v1/admin/short-links/{short_link}", auth: true, body: gin.H{"target_url": "https://example.com/campaign"}, status: 200},
+		{name: "update short link invalid", method: "PUT", path: "/api/v1/admin/short-links/{short_link}", auth: true, body: gin.H{"target_url": "not a url"}, status: 400},
+		{name: "update short link not found", method: "PUT", path: "/api/v1/admin/short-links/missing", auth: true, body: gin.H{"target_url": ""}, status: 404},
+		{name: "follow short link", method: "GET", path: "/r/{short_link}", status: 302},
+		{name: "follow short link not found", method: "GET", path: "/r/missing", status: 404},
+		{name: "scans per recommendor", method: "GET", path: "/api/v1/admin/scans/recommendors?source=desktop", auth: true, status: 200},
+		{name: "scans per day", method: "GET", path: "/api/v1/admin/scans/daily?recommendor_id={recommendor}&from=2024-01-01&to=2024-01-31", auth: true, status: 200},
+		{name: "scans per day invalid range", method: "GET", path: "/api/v1/admin/scans/daily?from=2024-02-01&to=2024-01-01", auth: true, status: 400},
+		{name: "recommendor badge", method: "GET", path: "/api/v1/admin/recommendors/{recommendor}/badge.pdf", auth: true, status: 200},
+		{name: "recommendor badge not found", method: "GET", path: "/api/v1/admin/recommendors/999999/badge.pdf", auth: true, status: 404},
+		{name: "create export", method: "POST", path: "/api/v1/admin/recommendors/exports", auth: true, body: json.RawMessage(`{"ids":[{recommendor}],"formats":["png","svg","badge"]}`), status: 202, capture: "export"},
+		{name: "create export empty", method: "POST", path: "/api/v1/admin/recommendors/exports", auth: true, body: gin.H{"province_code": "000000"}, status: 400},
+		{name: "create export invalid format", method: "POST", path: "/api/v1/admin/recommendors/exports", auth: true, body: gin.H{"formats": []string{"gif"}}, status: 400, invalid: true},
+		{name: "get export", method: "GET", path: "/api/v1/admin/recommendors/exports/{export}", auth: true, status: 200, wait: true},
+		{name: "get export not found", method: "GET", path: "/api/v1/admin/recommendors/exports/999999", auth: true, status: 404},
+		{name: "download export", method: "GET", path: "/api/v1/admin/recommendors/exports/{export}/download", auth: true, status: 200, wait: true},
+		{name: "download export not found", method: "GET", path: "/api/v1/admin/recommendors/exports/999999/download", auth: true, status: 404},
+		{name: "list qrcode jobs", method: "GET", path: "/api/v1/admin/recommendors/qrcode-jobs?status=pending&sort=-run_at", auth: true, status: 200},
+		{name: "retry qrcode job not found", method: "POST", path: "/api/v1/admin/recommendors/qrcode-jobs/999999/retry", auth: true, status: 404},
+		{name: "update recommendor translation", method: "PUT", path: "/api/v1/admin/recommendors/{recommendor}/translations/en", auth: true, body: gin.H{"bio": "Senior guide"}, status: 200},
+		{name: "get recommendor translations", method: "GET", path: "/api/v1/admin/recommendors/{recommendor}/translations", auth: true, status: 200},
+		{name: "public list recommendors", method: "GET", path: "/api/v1/recommendors?sort_by=age&sort_order=desc", status: 200},
+		{name: "public list recommendors by sort expression", method: "GET", path: "/api/v1/recommendors?sort=-rating,name", status: 200},
+		{name: "public list recommendors invalid sort", method: "GET", path: "/api/v1/recommendors?sort=id_number", status: 400},
+		{name: "public list recommendors by cursor", method: "GET", path: "/api/v1/recommendors?pagination=cursor&with_total=true&page_size=1", status: 200},
+		{name: "legacy public list recommendors by cursor", method: "GET", path: "/api/recommendors?pagination=cursor&page_size=1", status: 200},
+		{name: "public list recommendors invalid cursor", method: "GET", path: "/api/v1/recommendors?cursor=bogus", status: 400},
+		{name: "public list recommendors by filter operators", method: "GET", path: "/api/v1/recommendors?age[gte]=30&gender[in]=male,female", status: 200},
+		{name: "public list recommendors with fields", method: "GET", path: "/api/v1/recommendors?fields=id,name", status: 200},
+		{name: "public list recommendors invalid fields", method: "GET", path: "/api/v1/recommendors?fields=password", status: 400},
+		{name: "public list destinations with recommendor", method: "GET", path: "/api/v1/destinations?include=recommendor", status: 200},
+		{name: "public list recommendors invalid filter", method: "GET", path: "/api/v1/recommendors?age[like]=3", status: 400},
+		{name: "legacy public list recommendors", method: "GET", path: "/api/recommendors", status: 200},
+		{name: "public get recommendor", method: "GET", path: "/api/v1/recommendors/{recommendor}", status: 200},
+		{name: "public get recommendor not found", method: "GET", path: "/api/v1/recommendors/999999", status: 404},
+		{name: "legacy public get recommendor", method: "GET", path: "/api/recommendors/{recommendor}", status: 200},
+		{name: "recommendor qrcode", method: "GET", path: "/api/v1/recommendors/{recommendor}/qrcode.png", status: 200},
+		{name: "recommendor wxapp qrcode resized", method: "GET", path: "/api/v1/recommendors/{recommendor}/qrcode.png?type=wxapp&size=128", status: 200},
+		{name: "recommendor qrcode invalid size", method: "GET", path: "/api/v1/recommendors/{recommendor}/qrcode.png?size=10", status: 400, invalid: true},
+		{name: "recommendor qrcode not found", method: "GET", path: "/api/v1/recommendors/999999/qrcode.png", status: 404},
+		{name: "recommendor branded qrcode", method: "GET", path: "/api/v1/recommendors/{recommendor}/qrcode.png?level=H&fg=1a4d8f&bg=fff&logo=false", status: 200},
+		{name: "recommendor qrcode invalid level", method: "GET", path: "/api/v1/recommendors/{recommendor}/qrcode.png?level=X", status: 400, invalid: true},
+		{name: "recommendor qrcode svg", method: "GET", path: "/api/v1/recommendors/{recommendor}/qrcode.svg?type=wxapp", status: 200},
+
+		// Destinations
+		{name: "create destination", method: "POST", path: "/api/v1/admin/destinations", auth: true, body: json.RawMessage(destination("{recommendor}")), status: 201, capture: "destination"},
+		{name: "legacy create destination", method: "POST", path: "/api/admin/destinations", auth: true, body: json.RawMessage(destination("{legacy_recommendor}")), status: 201, capture: "legacy_destination"},
+		{name: "admin list destinations", method: "GET", path: "/api/v1/admin/destinations?category=scenic", auth: true, status: 200},
+		{name: "legacy admin list destinations", method: "GET", path: "/api/admin/destinations", auth: true, status: 200},
+		{name: "admin get destination", method: "GET", path: "/api/v1/admin/destinations/{destination}", auth: true, status: 200},
+		{name: "legacy admin get destination", method: "GET", path: "/api/admin/destinations/{legacy_destination}", auth: true, status: 200},
+		{name: "update destination", method: "PUT", path: "/api/v1/admin/destinations/{destination}", auth: true, body: gin.H{"rating": 4.8}, status: 200},
+		{name: "update destination invalid", method: "PUT", path: "/api/v1/admin/destinations/{destination}", auth: true, body: gin.H{"rating": 9}, status: 400, invalid: true},
+		{name: "legacy update destination", method: "PUT", path: "/api/admin/destinations/{legacy_destination}", auth: true, body: gin.H{"address": "北京市东城区"}, status: 200},
+		{name: "update destination translation", method: "PUT", path: "/api/v1/admin/destinations/{destination}/translations/en", auth: true, body: gin.H{"name": "Forbidden City"}, status: 200},
+		{name: "get destination translations", method: "GET", path: "/api/v1/admin/destinations/{destination}/translations", auth: true, status: 200},
+		{name: "legacy admin destinations by recommendor", method: "GET", path: "/api/admin/recommendors/{legacy_recommendor}/destinations", auth: true, status: 200},
+		{name: "public list destinations", method: "GET", path: "/api/v1/destinations", status: 200},
+		{name: "legacy public list destinations", method: "GET", path: "/api/destinations", status: 200},
+		{name: "public get destination", method: "GET", path: "/api/v1/destinations/{destination}", status: 200},
+		{name: "legacy public get destination", method: "GET", path: "/api/destinations/{destination}", status: 200},
+		{name: "public destinations by recommendor", method: "GET", path: "/api/v1/recommendors/{recommendor}/destinations", status: 200},
+
+		// Mini Program scenes
+		{name: "create destination scene", method: "POST", path: "/api/v1/admin/wxapp/scenes", auth: true, body: json.RawMessage(`{"target":"destination","destination_id":{destination},"campaign":"spring"}`), status: 201, capture: "scene"},
+		{name: "create existing scene", method: "POST", path: "/api/v1/admin/wxapp/scenes", auth: true, body: json.RawMessage(`{"target":"destination","destination_id":{destination},"campaign":"spring"}`), status: 200},
+		{name: "create scene invalid target", method: "POST", path: "/api/v1/admin/wxapp/scenes", auth: true, body: gin.H{"target": "region"}, status: 400, invalid: true},
+		{name: "create scene not found", method: "POST", path: "/api/v1/admin/wxapp/scenes", auth: true, body: gin.H{"target": "recommendor", "recommendor_id": 999999}, status: 404},
+		{name: "list scenes", method: "GET", path: "/api/v1/admin/wxapp/scenes?target=destination", auth: true, status: 200},
+		{name: "scene qrcode", method: "GET", path: "/api/v1/admin/wxapp/scenes/{scene}/qrcode.png?size=128", auth: true, status: 200},
+		{name: "scene qrcode not found", method: "GET", path: "/api/v1/admin/wxapp/scenes/missing/qrcode.png", auth: true, status: 404},
+		{name: "resolve scene", method: "GET", path: "/api/v1/wxapp/scene/{scene}", status: 200},
+		{name: "resolve scene not found", method: "GET", path: "/api/v1/wxapp/scene/missing", status: 404},
+
+		// Mini Program tourists (WeChat is not configured here, see the controller tests for sign ins)
+		{name: "wxapp login invalid body", method: "POST", path: "/api/v1/wxapp/login", body: gin.H{}, status: 400, invalid: true},
+		{name: "wxapp login unavailable", method: "POST", path: "/api/v1/wxapp/login", body: gin.H{"code": "0a1b2c"}, status: 503},
+		{name: "current tourist", method: "GET", path: "/api/v1/wxapp/me", tourist: true, status: 200},
+		{name: "current tourist with admin token", method: "GET", path: "/api/v1/wxapp/me", auth: true, status: 403},
+		{name: "admin with tourist token", method: "GET", path: "/api/v1/admin/recommendors", tourist: true, status: 403},
+		{name: "legacy public destinations by recommendor", method: "GET", path: "/api/recommendors/{recommendor}/destinations", status: 200},
+
+		// System monitoring
+		{name: "database stats", method: "GET", path: "/api/v1/admin/system/database", auth: true, status: 200},
+
+		// Deletes run last so the lookups above have data
+		{name: "delete destination translation", method: "DELETE", path: "/api/v1/admin/destinations/{destination}/translations/en", auth: true, status: 200},
+		{name: "delete recommendor translation", method: "DELETE", path: "/api/v1/admin/recommendors/{recommendor}/translations/en", auth: true, status: 200},
+		{name: "delete destination", method: "DELETE", path: "/api/v1/admin/destinations/{destination}", auth: true, status: 200},
+		{name: "legacy delete destination", method: "DELETE", path: "/api/admin/destinations/{legacy_destination}", auth: true, status: 200},
+		{name: "delete region in use", method: "DELETE", path: "/api/v1/admin/regions/{used_region}", auth: true, status: 400},
+		{name: "delete recommendor", method: "DELETE", path: "/api/v1/admin/recommendors/{recommendor}", auth: true, status: 200},
+		{name: "legacy delete recommendor", method: "DELETE", path: "/api/admin/recommendors/{legacy_recommendor}", auth: true, status: 200},
+		{name: "delete region", method: "DELETE", path: "/api/v1/admin/regions/{region}", auth: true, status: 200},
+		{name: "legacy delete region", method: "DELETE", path: "/api/admin/regions/{legacy_region}", auth: true, status: 200},
+		{name: "delete region not found", method: "DELETE", path: "/api/v1/admin/regions/{region}", auth: true, status: 404},
+	}
+
+	tourist := models.Tourist{OpenID: "contract-openid"}
+	if err := config.DB.Create(&tourist).Error; err != nil {
+		t.Fatalf("failed to create tourist: %v", err)
+	}
+	touristToken, err := utils.GenerateTouristToken(tourist.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]string{"tourist_token": touristToken}
+	for _, tc := range cases {
+		if raw, ok := tc.body.(json.RawMessage); ok {
+			pairs := make([]string, 0, len(values)*2)
+			for name, value := range values {
+				pairs = append(pairs, "{"+name+"}", value)
+			}
+			tc.body = json.RawMessage(strings.NewReplacer(pairs...).Replace(string(raw)))
+		}
+
+		if tc.wait {
+			if err := controllers.WaitForExports(context.Background()); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+		s.violations = nil
+		w := s.do(t, tc, values)
+		if w.Code != tc.status {
+			t.Fatalf("%s: %s %s returned %d, want %d: %s", tc.name, tc.method, tc.path, w.Code, tc.status, w.Body.String())
+		}
+
+		for _, violation := range s.violations {
+			if violation.Kind == middleware.ContractRequest && tc.invalid {
+				continue
+			}
+			t.Errorf("%s: %s violation on %s %s (status %d): %v",
+				tc.name, violation.Kind, violation.Method, violation.Route, violation.Status, violation.Err)
+		}
+		if tc.invalid && !hasViolation(s.violations, middleware.ContractRequest) {
+			t.Errorf("%s: request was expected to violate the document but passed validation", tc.name)
+		}
+
+		if tc.capture != "" {
+			field := "id"
+			switch tc.capture {
+			case "token", "scene":
+				field = "token"
+			case "short_link":
+				field = "code"
+			}
+			values[tc.capture] = capturedValue(t, w, field)
+		}
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(docs.OpenAPI)
+	if err != nil {
+		t.Fatalf("failed to load docs/openapi.json: %v", err)
+	}
+	var missing []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !s.exercised[method+" "+path] {
+				missing = append(missing, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(missing)
+	for _, operation := range missing {
+		t.Errorf("documented operation %s is not exercised by the contract suite", operation)
+	}
+}
+
+func hasViolation(violations []middleware.ContractViolation, kind string) bool {
+	for _, violation := range violations {
+		if violation.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// TestContractValidatorReportsDrift checks that the validator flags a response that does
+// not match the document, so a passing TestAPIContract is meaningful.
+func TestContractValidatorReportsDrift(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var violations []middleware.ContractViolation
+	validator, err := middleware.ContractValidator(docs.OpenAPI, func(c *gin.Context, violation middleware.ContractViolation) {
+		violations = append(violations, violation)
+	})
+	if err != nil {
+		t.Fatalf("failed to load docs/openapi.json: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(validator)
+	r.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": 42})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("validator altered the response: status %d", w.Code)
+	}
+	if !hasViolation(violations, middleware.ContractResponse) {
+		t.Fatalf("expected a response violation for a drifted /healthz body, got %v", violations)
+	}
+}
@@ -1,36 +1,64 @@
 package routes
 
 import (
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
+	"strings"
 
+	"tourism_recommendor/config"
 	"tourism_recommendor/controllers"
+	"tourism_recommendor/docs"
 	"tourism_recommendor/middleware"
 	"tourism_recommendor/models"
+	"tourism_recommendor/response"
+	"tourism_recommendor/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-// SetupRoutes initializes all the routes for the application
-func SetupRoutes(r *gin.Engine, db *gorm.DB) {
+// SetupRoutes initializes all the routes for the application and returns the QR code
+// queue for the caller to run
+func SetupRoutes(r *gin.Engine, db *gorm.DB, cfg *config.Config) *controllers.QRCodeQueue {
 	// Initialize controllers
+	wechat := newWeChatClient(cfg)
 	authController := controllers.NewAuthController(db)
 	uploadController := &controllers.UploadController{}
 	regionController := &controllers.RegionController{}
-	recommendorController := &controllers.RecommendorController{}
+	recommendorController := controllers.NewRecommendorController(cfg, wechat)
+	exportController := controllers.NewExportController(recommendorController)
+	shortLinkController := controllers.NewShortLinkController(cfg)
+	wxappController := controllers.NewWxappController(recommendorController)
+	touristController := controllers.NewTouristController(wechat)
 	destinationController := &controllers.DestinationController{}
+	systemController := &controllers.SystemController{}
+	metaController := &controllers.MetaController{}
+	translationController := &controllers.TranslationController{}
+	healthController := controllers.NewHealthController(db, cfg, wechat)
+	docsController := controllers.NewDocsController("/api/v1/openapi.json", "/api/v1/docs/")
+
+	// Kubernetes/Render style probes
+	r.GET("/healthz", healthController.Liveness)
+	r.GET("/readyz", healthController.Readiness)
+
+	// Short links encoded in the QR codes (outside the v1 envelope, they answer with redirects)
+	r.GET("/r/:code", shortLinkController.Redirect)
+
+	// Prometheus metrics (outside the auth groups, optionally protected by its own token)
+	if cfg.Metrics.Enabled {
+		r.GET(cfg.Metrics.Path, middleware.MetricsHandler(cfg.Metrics.Token.Value()))
+	}
+
+	// OpenAPI document and bundled docs UI (served as-is, outside the v1 envelope)
+	r.GET("/api/v1/openapi.json", docsController.GetOpenAPISpec)
+	r.GET("/api/v1/docs/*any", docsController.UI)
 
-	// API v1 routes
+	// API v1 routes (responses use the envelope; legacy /api routes keep their original shapes)
 	v1 := r.Group("/api/v1")
+	v1.Use(response.Envelope())
 	{
 		// Health check endpoint
-		v1.GET("/health", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"status":  "ok",
-				"message": "Tourism Recommender API is running",
-			})
-		})
+		v1.GET("/health", healthController.Ping)
 
 		// Public auth routes (no authentication required)
 		auth := v1.Group("/auth")
@@ -79,6 +107,24 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 				recommendors.PUT("/:id", recommendorController.UpdateRecommendor)
 				recommendors.DELETE("/:id", recommendorController.DeleteRecommendor)
 				recommendors.POST("/:id/qrcodes", recommendorController.RegenerateQRCodes)
+				recommendors.GET("/:id/badge.pdf", recommendorController.GetBadge)
+
+				// Bulk QR code and badge exports, built in the background
+				recommendors.POST("/exports", exportController.CreateExport)
+				recommendors.GET("/exports/:id", exportController.GetExport)
+				recommendors.GET("/exports/:id/download", exportController.DownloadExport)
+
+				// Background QR code generation
+				recommendors.GET("/qrcode-jobs", recommendorController.QRCodes.GetQRCodeJobs)
+				recommendors.POST("/qrcode-jobs/:id/retry", recommendorController.QRCodes.RetryQRCodeJob)
+
+				// Short links of the QR codes
+				recommendors.GET("/:id/short-links", shortLinkController.GetRecommendorShortLinks)
+
+				// Per-locale translations of the bio
+				recommendors.GET("/:id/translations", translationController.GetRecommendorTranslations)
+				recommendors.PUT("/:id/translations/:locale", translationController.UpdateRecommendorTranslation)
+				recommendors.DELETE("/:id/translations/:locale", translationController.DeleteRecommendorTranslation)
 			}
 
 			// Destination management
@@ -89,18 +135,51 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 				destinations.GET("/:id", destinationController.GetDestinationByID)
 				destinations.PUT("/:id", destinationController.UpdateDestination)
 				destinations.DELETE("/:id", destinationController.DeleteDestination)
+
+				// Per-locale translations of the name and description
+				destinations.GET("/:id/translations", translationController.GetDestinationTranslations)
+				destinations.PUT("/:id/translations/:locale", translationController.UpdateDestinationTranslation)
+				destinations.DELETE("/:id/translations/:locale", translationController.DeleteDestinationTranslation)
+			}
+
+			// Short links and QR code scan reports
+			admin.PUT("/short-links/:code", shortLinkController.UpdateShortLink)
+			scans := admin.Group("/scans")
+			{
+				scans.GET("/recommendors", shortLinkController.GetRecommendorScans)
+				scans.GET("/daily", shortLinkController.GetDailyScans)
+			}
+
+			// Scenes of Mini Program codes
+			wxapp := admin.Group("/wxapp")
+			{
+				wxapp.POST("/scenes", wxappController.CreateWxappScene)
+				wxapp.GET("/scenes", wxappController.GetWxappScenes)
+				wxapp.GET("/scenes/:token/qrcode.png", wxappController.GetWxappSceneQRCode)
+			}
+
+			// System monitoring
+			system := admin.Group("/system")
+			{
+				system.GET("/database", systemController.GetDatabaseStats)
 			}
 		}
 
-		// Public routes
+		// Public routes (content is returned in the request locale)
 		public := v1.Group("")
+		public.Use(middleware.LocalizedContent())
 		{
+			// Enum values with localized labels
+			public.GET("/enums", metaController.GetEnums)
+
 			// Public recommendor endpoints
 			recommendors := public.Group("/recommendors")
 			{
 				recommendors.GET("", recommendorController.GetRecommendors)
 				recommendors.GET("/:id", recommendorController.GetRecommendorByID)
 				recommendors.GET("/:id/destinations", destinationController.GetDestinationsByRecommendor)
+				recommendors.GET("/:id/qrcode.png", recommendorController.GetQRCode)
+				recommendors.GET("/:id/qrcode.svg", recommendorController.GetQRCodeSVG)
 			}
 
 			// Public destination endpoints
@@ -110,6 +189,20 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 				destinations.GET("/:id", destinationController.GetDestinationByID)
 			}
 		}
+
+		// Mini Program endpoints
+		wxapp := v1.Group("/wxapp")
+		{
+			wxapp.GET("/scene/:token", wxappController.ResolveWxappScene)
+			wxapp.POST("/login", touristController.WxappLogin)
+		}
+
+		// Mini Program tourist endpoints (require a tourist token from /wxapp/login)
+		tourist := v1.Group("/wxapp")
+		tourist.Use(middleware.TouristRequired())
+		{
+			tourist.GET("/me", touristController.GetCurrentTourist)
+		}
 	}
 
 	// Legacy API routes (without /v1 prefix) for backward compatibility
@@ -167,8 +260,9 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 		}
 	}
 
-	// Legacy public routes
+	// Legacy public routes (content is returned in the request locale)
 	public := r.Group("/api")
+	public.Use(middleware.LocalizedContent())
 	{
 		// Public recommendor endpoints
 		recommendors := public.Group("/recommendors")
@@ -203,24 +297,80 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB) {
 	// When user refreshes /recommendors/123, this route returns index.html,
 	// and React Router takes over to handle the route
 	r.NoRoute(func(c *gin.Context) {
+		// Unknown v1 API paths get a JSON error instead of the SPA shell
+		if strings.HasPrefix(c.Request.URL.Path, "/api/v1/") {
+			response.EnableEnvelope(c)
+			response.Error(c, response.ErrRouteNotFound)
+			return
+		}
 		c.File("./static/index.html")
 	})
+
+	return recommendorController.QRCodes
+}
+
+// newWeChatClient creates the WeChat API client shared by the controllers, or nil when no
+// AppSecret is configured
+func newWeChatClient(cfg *config.Config) utils.WeChatClient {
+	if cfg.QRCode.WxAppSecret == "" {
+		return nil
+	}
+	return utils.NewWeChatClient(utils.WeChatConfig{
+		BaseURL:   cfg.QRCode.WxAPIURL,
+		AppID:     cfg.QRCode.WxAppID,
+		AppSecret: cfg.QRCode.WxAppSecret.Value(),
+		Timeout:   cfg.QRCode.WxTimeout,
+		Retries:   cfg.QRCode.WxRetries,
+	})
 }
 
 // SetupMiddleware configures middleware for the router
-func SetupMiddleware(r *gin.Engine) {
+func SetupMiddleware(r *gin.Engine, cfg *config.Config) {
+	// Tracing middleware starts a span per request (probes and metrics scrapes are skipped)
+	if cfg.Tracing.Enabled {
+		r.Use(middleware.Tracing(cfg.Tracing.ServiceName, "/healthz", "/readyz", cfg.Metrics.Path))
+	}
+
+	// Request ID middleware assigns X-Request-ID and a request-scoped logger
+	r.Use(middleware.RequestID())
+
+	// Trace context middleware exposes the trace ID in X-Trace-ID and log lines
+	r.Use(middleware.TraceContext())
+
+	// Locale middleware negotiates the response language from Accept-Language
+	r.Use(middleware.Locale())
+
+	// Logger middleware writes one structured access log line per request
+	r.Use(middleware.RequestLogger())
+
 	// Recovery middleware recovers from any panics and writes a 500 if there was one
-	r.Use(gin.Recovery())
+	r.Use(middleware.Recovery())
+
+	// Contract validation reports requests and responses that drift from the OpenAPI document
+	if cfg.OpenAPI.Validation == "log" {
+		validator, err := middleware.ContractValidator(docs.OpenAPI, nil)
+		if err != nil {
+			slog.Error("failed to load OpenAPI document, contract validation disabled", "error", err)
+		} else {
+			r.Use(validator)
+		}
+	}
 
-	// Logger middleware writes the logs to gin.DefaultWriter
-	r.Use(gin.Logger())
+	// Error responses carry the trace ID so clients can report it
+	r.Use(middleware.ErrorTraceID())
+
+	// Metrics middleware records request counts and latencies
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.MetricsMiddleware(cfg.Metrics.Path))
+	}
 
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, traceparent, tracestate, Accept-Language")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Trace-ID, Content-Language")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -234,37 +384,66 @@ func SetupMiddleware(r *gin.Engine) {
 // AutoMigrate runs database migrations
 func AutoMigrate(db *gorm.DB) error {
 	// Auto migrate all models
-	err := db.AutoMigrate(
-		&models.Admin{},
-		&models.Region{},
-		&models.Recommendor{},
-		&models.Destination{},
-	)
-	return err
+	return db.AutoMigrate(models.All()...)
 }
 
-// SeedDatabase creates default data (admin user) if they don't exist
-func SeedDatabase(db *gorm.DB) error {
-	// Get default admin credentials from environment or use defaults
-	defaultUsername := os.Getenv("DEFAULT_ADMIN_USERNAME")
-	if defaultUsername == "" {
-		defaultUsername = "admin"
+// MigrateQRCodeURLs moves QR codes stored inline as base64 data URLs into the upload storage
+// and points them, and QR code URLs stored host-relative, at the image endpoint under baseURL
+func MigrateQRCodeURLs(db *gorm.DB, baseURL string) error {
+	var recommendors []models.Recommendor
+	migrated := 0
+	err := db.Unscoped().Model(&models.Recommendor{}).
+		Select("id", "qr_code_web", "qr_code_wxapp").
+		Where("qr_code_web LIKE ? OR qr_code_wxapp LIKE ? OR qr_code_web LIKE ? OR qr_code_wxapp LIKE ?", "data:%", "data:%", "/%", "/%").
+		FindInBatches(&recommendors, 100, func(tx *gorm.DB, batch int) error {
+			for _, r := range recommendors {
+				updates := map[string]interface{}{}
+				for column, value := range map[string]string{"qr_code_web": r.QRCodeWeb, "qr_code_wxapp": r.QRCodeWxapp} {
+					kind := strings.TrimPrefix(column, "qr_code_")
+					if strings.HasPrefix(value, "/") {
+						updates[column] = strings.TrimSuffix(baseURL, "/") + value
+						continue
+					}
+					if !utils.IsDataURL(value) {
+						continue
+					}
+					image, err := utils.DecodeDataURL(value)
+					if err != nil {
+						// Unreadable images are dropped; they are generated again on first request
+						slog.Warn("dropping unreadable inline QR code", "recommendor_id", r.ID, "type", kind, "error", err)
+						updates[column] = ""
+						continue
+					}
+					if image, err = utils.StoreQRCode(r.ID, kind, image); err != nil {
+						return fmt.Errorf("failed to store QR code of recommendor %d: %w", r.ID, err)
+					}
+					updates[column] = utils.QRCodeURL(baseURL, r.ID, kind, image)
+				}
+				if err := db.Unscoped().Model(&models.Recommendor{}).Where("id = ?", r.ID).UpdateColumns(updates).Error; err != nil {
+					return err
+				}
+				migrated++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to migrate QR code URLs: %w", err)
 	}
 
-	defaultPassword := os.Getenv("DEFAULT_ADMIN_PASSWORD")
-	if defaultPassword == "" {
-		defaultPassword = "admin123456"
+	if migrated > 0 {
+		slog.Info("migrated QR code URLs", "recommendors", migrated)
 	}
+	return nil
+}
 
-	defaultEmail := os.Getenv("DEFAULT_ADMIN_EMAIL")
-	if defaultEmail == "" {
-		defaultEmail = "admin@tourism.com"
-	}
+// SeedDatabase creates default data (admin user) if they don't exist
+func SeedDatabase(db *gorm.DB, adminConfig config.AdminConfig) error {
+	defaultUsername := adminConfig.Username
+	defaultPassword := adminConfig.Password.Value()
+	defaultEmail := adminConfig.Email
 
-	log.Printf("🔐 Seeding default admin account:")
-	log.Printf("  Username: %s", defaultUsername)
-	log.Printf("  Password: %s", defaultPassword)
-	log.Printf("  Email: %s", defaultEmail)
+	logger := slog.With("username", defaultUsername)
+	logger.Info("seeding default admin account", "email", defaultEmail)
 
 	// Check if admin already exists
 	var existingAdmin models.Admin
@@ -272,27 +451,25 @@ func SeedDatabase(db *gorm.DB) error {
 
 	if result.Error == nil {
 		// Admin already exists, update password and email to ensure correct credentials
-		log.Printf("📝 Admin '%s' already exists, updating password and email...", defaultUsername)
 		existingAdmin.Email = defaultEmail
 		if err := existingAdmin.SetPassword(defaultPassword); err != nil {
-			log.Printf("❌ Failed to update password for existing admin '%s': %v", defaultUsername, err)
+			logger.Error("failed to update password for existing admin", "error", err)
 			return err
 		}
 		if err := db.Save(&existingAdmin).Error; err != nil {
-			log.Printf("❌ Failed to save updated admin '%s': %v", defaultUsername, err)
+			logger.Error("failed to save updated admin", "error", err)
 			return err
 		}
-		log.Printf("✅ Admin '%s' updated successfully with new password", defaultUsername)
+		logger.Info("default admin account updated", "id", existingAdmin.ID)
 		return nil
 	}
 
 	if result.Error != nil && result.Error.Error() != "record not found" {
-		log.Printf("❌ Error checking for existing admin: %v", result.Error)
+		logger.Error("failed to check for existing admin", "error", result.Error)
 		return result.Error
 	}
 
 	// Create new admin
-	log.Printf("🆕 Creating new admin account for user '%s'...", defaultUsername)
 	admin := &models.Admin{
 		Username: defaultUsername,
 		Email:    defaultEmail,
@@ -304,22 +481,18 @@ func SeedDatabase(db *gorm.DB) error {
 
 	// Set password (this will hash it automatically)
 	if err := admin.SetPassword(defaultPassword); err != nil {
-		log.Printf("❌ Failed to set password: %v", err)
+		logger.Error("failed to set password", "error", err)
 		return err
 	}
 
 	// Save to database
 	if err := db.Create(admin).Error; err != nil {
-		log.Printf("❌ Failed to create admin in database: %v", err)
+		logger.Error("failed to create admin in database", "error", err)
 		return err
 	}
 
-	log.Printf("✅ Default admin account created successfully:")
-	log.Printf("  Username: %s", admin.Username)
-	log.Printf("  Email: %s", admin.Email)
-	log.Printf("  ID: %d", admin.ID)
-	log.Printf("  Role: %s", admin.Role)
-	log.Printf("\n⚠️  IMPORTANT: Please change the default password after first login!")
+	logger.Info("default admin account created", "id", admin.ID, "role", admin.Role)
+	logger.Warn("please change the default admin password after first login")
 
 	return nil
 }
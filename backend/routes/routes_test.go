@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/docs"
+	"tourism_recommendor/middleware"
+	"tourism_recommendor/models"
+	"tourism_recommendor/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestRoutesDocumented fails when a route registered by SetupRoutes is missing from
+// the OpenAPI document, or the document describes a route that is not registered.
+// Run `go generate ./docs` after adding or changing handler annotations.
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupRoutes(r, nil, config.Default())
+
+	doc, err := openapi3.NewLoader().LoadFromData(docs.OpenAPI)
+	if err != nil {
+		t.Fatalf("failed to load docs/openapi.json: %v", err)
+	}
+
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		// Static file servers and the docs UI are catch-all routes, not API operations
+		if strings.Contains(route.Path, "*") {
+			continue
+		}
+		path := middleware.OpenAPIPath(route.Path)
+		registered[route.Method+" "+path] = true
+
+		item := doc.Paths.Value(path)
+		if item == nil || item.GetOperation(route.Method) == nil {
+			t.Errorf("route %s %s (%s) is not documented; add @Router %s [%s] to its handler",
+				route.Method, route.Path, route.Handler, path, strings.ToLower(route.Method))
+		}
+	}
+
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !registered[method+" "+path] {
+				t.Errorf("documented operation %s %s is not registered by SetupRoutes", method, path)
+			}
+		}
+	}
+}
+
+func TestMigrateQRCodeURLs(t *testing.T) {
+	dir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "migrate.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := AutoMigrate(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	qrCodeDir := utils.QRCodeDir
+	utils.QRCodeDir = filepath.Join(dir, "qrcodes")
+	t.Cleanup(func() { utils.QRCodeDir = qrCodeDir })
+
+	inline := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngPixel)
+	relative := utils.QRCodePath(1, utils.QRCodeWxapp) + "&v=abc"
+	recommendor := models.Recommendor{Name: "张三", IDNumber: "110101199001011234", QRCodeWeb: inline, QRCodeWxapp: relative}
+	if err := db.Create(&recommendor).Error; err != nil {
+		t.Fatalf("failed to create recommendor: %v", err)
+	}
+
+	const baseURL = "https://api.example.com/"
+	if err := MigrateQRCodeURLs(db, baseURL); err != nil {
+		t.Fatalf("MigrateQRCodeURLs: %v", err)
+	}
+
+	var stored models.Recommendor
+	db.First(&stored, recommendor.ID)
+	if want := utils.QRCodeURL(baseURL, recommendor.ID, utils.QRCodeWeb, pngPixel); stored.QRCodeWeb != want {
+		t.Fatalf("web QR code URL = %q, want %q", stored.QRCodeWeb, want)
+	}
+	if want := "https://api.example.com" + relative; stored.QRCodeWxapp != want {
+		t.Fatalf("wxapp QR code URL = %q, want %q", stored.QRCodeWxapp, want)
+	}
+	if image, err := utils.LoadQRCode(recommendor.ID, utils.QRCodeWeb); err != nil || !bytes.Equal(image, pngPixel) {
+		t.Fatalf("stored web QR code = %v, %v", image, err)
+	}
+}
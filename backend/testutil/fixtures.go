@@ -0,0 +1,128 @@
+package testutil
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"tourism_recommendor/models"
+	"tourism_recommendor/utils"
+)
+
+// AdminPassword is the password of admins created by CreateAdmin
+const AdminPassword = "password123"
+
+// sequence makes usernames, emails and ID numbers of fixtures unique
+var sequence atomic.Int64
+
+func next() int64 {
+	return sequence.Add(1)
+}
+
+// CreateAdmin inserts an active admin with AdminPassword, applying overrides before the insert
+func (h *Harness) CreateAdmin(overrides ...func(*models.Admin)) *models.Admin {
+	h.t.Helper()
+
+	n := next()
+	admin := &models.Admin{
+		Username: fmt.Sprintf("admin%d", n),
+		Email:    fmt.Sprintf("admin%d@example.com", n),
+		Name:     "测试管理员",
+		Role:     models.AdminRoleAdmin,
+		Status:   "active",
+	}
+	if err := admin.SetPassword(AdminPassword); err != nil {
+		h.t.Fatalf("failed to hash admin password: %v", err)
+	}
+	for _, override := range overrides {
+		override(admin)
+	}
+
+	if err := h.DB.Create(admin).Error; err != nil {
+		h.t.Fatalf("failed to create admin: %v", err)
+	}
+	return admin
+}
+
+// CreateRecommendor inserts an active recommendor valid for a year, applying overrides before the insert
+func (h *Harness) CreateRecommendor(overrides ...func(*models.Recommendor)) *models.Recommendor {
+	h.t.Helper()
+
+	n := next()
+	now := time.Now()
+	recommendor := &models.Recommendor{
+		Name:          fmt.Sprintf("推荐官%d", n),
+		Gender:        models.GenderFemale,
+		Age:           30,
+		IDNumber:      fmt.Sprintf("1101011990%08d", n),
+		Bio:           "资深导游",
+		ValidFrom:     now.AddDate(0, -1, 0),
+		ValidUntil:    now.AddDate(1, 0, 0),
+		Phone:         "13800000000",
+		ProvinceCode:  "110000",
+		CityCode:      "110100",
+		DistrictCode:  "110101",
+		RegionAddress: "北京市/北京市/东城区",
+		Status:        "active",
+	}
+	for _, override := range overrides {
+		override(recommendor)
+	}
+
+	if err := h.DB.Create(recommendor).Error; err != nil {
+		h.t.Fatalf("failed to create recommendor: %v", err)
+	}
+	return recommendor
+}
+
+// CreateDestination inserts an active destination of recommendor, applying overrides before the insert
+func (h *Harness) CreateDestination(recommendor *models.Recommendor, overrides ...func(*models.Destination)) *models.Destination {
+	h.t.Helper()
+
+	n := next()
+	destination := &models.Destination{
+		RecommendorID: recommendor.ID,
+		Name:          fmt.Sprintf("景点%d", n),
+		Description:   "值得一去",
+		Address:       "北京市东城区",
+		Category:      "scenic_spot",
+		Rating:        4.5,
+		Status:        "active",
+	}
+	for _, override := range overrides {
+		override(destination)
+	}
+
+	if err := h.DB.Create(destination).Error; err != nil {
+		h.t.Fatalf("failed to create destination: %v", err)
+	}
+	return destination
+}
+
+// CreateRegion inserts a region
+func (h *Harness) CreateRegion(name string) *models.Region {
+	h.t.Helper()
+
+	region := &models.Region{Name: name}
+	if err := h.DB.Create(region).Error; err != nil {
+		h.t.Fatalf("failed to create region: %v", err)
+	}
+	return region
+}
+
+// Token issues a JWT for admin
+func (h *Harness) Token(admin *models.Admin) string {
+	h.t.Helper()
+
+	token, err := utils.GenerateToken(admin.ID, admin.Username, string(admin.Role))
+	if err != nil {
+		h.t.Fatalf("failed to generate token: %v", err)
+	}
+	return token
+}
+
+// AdminToken creates an admin and returns a token for it
+func (h *Harness) AdminToken() string {
+	h.t.Helper()
+	return h.Token(h.CreateAdmin())
+}
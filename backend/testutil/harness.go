@@ -0,0 +1,221 @@
+// Package testutil is the integration test harness: it runs the API router built by
+// routes.SetupRoutes against an ephemeral Postgres database (or SQLite, see
+// TEST_DATABASE_URL), one rolled-back transaction per test.
+//
+// Use it from an external test package:
+//
+//	func TestMain(m *testing.M) { os.Exit(testutil.Main(m)) }
+//
+//	func TestSomething(t *testing.T) {
+//		h := testutil.New(t)
+//		admin := h.CreateAdmin()
+//		w := h.Do("GET", "/api/v1/auth/me", nil, h.Token(admin))
+//	}
+//
+// Tests using the harness share config.DB and must not call t.Parallel.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"tourism_recommendor/config"
+	"tourism_recommendor/controllers"
+	"tourism_recommendor/response"
+	"tourism_recommendor/routes"
+	"tourism_recommendor/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	// database is the migrated test database shared by every test of the package
+	database *gorm.DB
+	// unavailable explains why database is nil; tests are skipped with this reason
+	unavailable error
+)
+
+// Main starts the test database, runs the migrations and then the package's tests.
+// When no database can be started the tests using New are skipped, not failed.
+func Main(m *testing.M) int {
+	gin.SetMode(gin.TestMode)
+	// Keep request and seed logs out of the test output
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	server, err := startPostgres()
+	if err != nil {
+		unavailable = err
+		return m.Run()
+	}
+	defer server.stop()
+
+	dbConfig := config.DatabaseConfig{URL: config.Secret(server.dsn)}
+	if dbConfig.Driver() == config.DriverSQLite && dbConfig.DSN() == ":memory:" {
+		// Health checks ping outside the test's transaction, so every connection
+		// must see the same in-memory database
+		dbConfig.URL = "file::memory:?cache=shared"
+	}
+	db, err := gorm.Open(dbConfig.Dialector(), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		unavailable = err
+		return m.Run()
+	}
+	if err := routes.AutoMigrate(db); err != nil {
+		unavailable = fmt.Errorf("migrations failed: %w", err)
+		return m.Run()
+	}
+
+	database = db
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	return m.Run()
+}
+
+// Harness is the per-test view of the application
+type Harness struct {
+	t *testing.T
+
+	// DB is the test's transaction; everything written through it is rolled back
+	DB *gorm.DB
+	// Config is the configuration the router was built with
+	Config *config.Config
+	// Router is the full application: middleware and routes
+	Router *gin.Engine
+	// WeChat is the fake WeChat API the router's client talks to
+	WeChat *WeChat
+	// QRCodes is the router's QR code queue; tests run it with RunPending
+	QRCodes *controllers.QRCodeQueue
+}
+
+// New begins a transaction for the test, points config.DB at it and builds the router
+// against a fake WeChat API. The transaction is rolled back when the test finishes.
+func New(t *testing.T) *Harness {
+	t.Helper()
+	if database == nil {
+		if unavailable == nil {
+			t.Fatal("testutil.New requires testutil.Main to be called from TestMain")
+		}
+		t.Skipf("test database is not available: %v", unavailable)
+	}
+
+	tx := database.Begin()
+	if tx.Error != nil {
+		t.Fatalf("failed to begin transaction: %v", tx.Error)
+	}
+
+	previousDB := config.DB
+	uploadDir, avatarDir, imageDir, documentDir, qrCodeDir, exportDir := utils.UploadDir, utils.AvatarDir, utils.ImageDir, utils.DocumentDir, utils.QRCodeDir, utils.ExportDir
+	config.DB = tx
+	utils.UploadDir = filepath.Join(t.TempDir(), "uploads")
+	utils.AvatarDir = filepath.Join(utils.UploadDir, "avatars")
+	utils.ImageDir = filepath.Join(utils.UploadDir, "images")
+	utils.DocumentDir = filepath.Join(utils.UploadDir, "documents")
+	utils.QRCodeDir = filepath.Join(utils.UploadDir, "qrcodes")
+	utils.ExportDir = filepath.Join(t.TempDir(), "exports")
+	t.Cleanup(func() {
+		tx.Rollback()
+		config.DB = previousDB
+		utils.UploadDir, utils.AvatarDir, utils.ImageDir, utils.DocumentDir, utils.QRCodeDir, utils.ExportDir = uploadDir, avatarDir, imageDir, documentDir, qrCodeDir, exportDir
+	})
+	if err := utils.InitUploadDirectories(); err != nil {
+		t.Fatalf("failed to create upload directories: %v", err)
+	}
+
+	wechat := NewWeChat(t)
+	cfg := config.Default()
+	cfg.QRCode.WxAppID = wechat.AppID
+	cfg.QRCode.WxAppSecret = config.Secret(wechat.AppSecret)
+	cfg.QRCode.WxAPIURL = wechat.URL
+	cfg.OpenAPI.Validation = "off"
+	utils.SetJWTSecret(cfg.JWT.Secret.Value())
+	utils.SetTokenExpiration(cfg.JWT.Expiration)
+	utils.SetTouristTokenExpiration(cfg.JWT.TouristExpiration)
+
+	r := gin.New()
+	routes.SetupMiddleware(r, cfg)
+	qrCodes := routes.SetupRoutes(r, tx, cfg)
+
+	return &Harness{t: t, DB: tx, Config: cfg, Router: r, WeChat: wechat, QRCodes: qrCodes}
+}
+
+// Do sends a request through the router. A non-nil body is sent as JSON; a non-empty
+// token is sent as a bearer token.
+func (h *Harness) Do(method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return h.Serve(req)
+}
+
+// Serve sends a prepared request through the router
+func (h *Harness) Serve(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.Router.ServeHTTP(w, req)
+	return w
+}
+
+// Envelope is a decoded /api/v1 response with the data left raw
+type Envelope struct {
+	Success bool                `json:"success"`
+	Data    json.RawMessage     `json:"data"`
+	Message string              `json:"message"`
+	Meta    *response.PageMeta  `json:"meta"`
+	Error   *response.ErrorBody `json:"error"`
+}
+
+// Decode decodes a /api/v1 response envelope, decoding its data into data when non-nil
+func (h *Harness) Decode(w *httptest.ResponseRecorder, data interface{}) Envelope {
+	h.t.Helper()
+
+	var envelope Envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		h.t.Fatalf("failed to decode response %q: %v", w.Body.String(), err)
+	}
+	if data != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, data); err != nil {
+			h.t.Fatalf("failed to decode response data %s: %v", envelope.Data, err)
+		}
+	}
+	return envelope
+}
+
+// ErrorCode returns the error code of a failed /api/v1 response, or "" on success
+func (h *Harness) ErrorCode(w *httptest.ResponseRecorder) string {
+	h.t.Helper()
+
+	envelope := h.Decode(w, nil)
+	if envelope.Error == nil {
+		return ""
+	}
+	return string(envelope.Error.Code)
+}
@@ -0,0 +1,156 @@
+package testutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+)
+
+// Environment variables that control where the test database comes from
+const (
+	// EnvDatabaseURL points the harness at an existing Postgres server instead of starting one,
+	// or at SQLite with a sqlite: URL (e.g. sqlite::memory:)
+	EnvDatabaseURL = "TEST_DATABASE_URL"
+	// EnvPostgresBin is a directory containing initdb and pg_ctl (defaults to PATH)
+	EnvPostgresBin = "TEST_POSTGRES_BIN"
+	// EnvPostgresCache is where downloaded Postgres binaries are cached (defaults to ~/.embedded-postgres-go)
+	EnvPostgresCache = "TEST_POSTGRES_CACHE"
+)
+
+// postgresServer is a Postgres server used by the test run
+type postgresServer struct {
+	dsn  string
+	stop func() error
+}
+
+// startPostgres returns a server to run the tests against. In order of preference it uses
+// TEST_DATABASE_URL, a throwaway cluster created with the local initdb, or the embedded
+// Postgres binaries (downloaded once into the cache, then reused without network access).
+func startPostgres() (*postgresServer, error) {
+	if dsn := os.Getenv(EnvDatabaseURL); dsn != "" {
+		return &postgresServer{dsn: dsn, stop: func() error { return nil }}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "tourism-postgres-")
+	if err != nil {
+		return nil, err
+	}
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	server, localErr := startLocalPostgres(dir, port)
+	if localErr == nil {
+		return server, nil
+	}
+
+	server, embeddedErr := startEmbeddedPostgres(dir, port)
+	if embeddedErr == nil {
+		return server, nil
+	}
+
+	os.RemoveAll(dir)
+	return nil, fmt.Errorf("local initdb: %v; embedded postgres: %v", localErr, embeddedErr)
+}
+
+// startLocalPostgres creates a cluster in dir with the initdb found on the machine
+func startLocalPostgres(dir string, port int) (*postgresServer, error) {
+	initdb, err := postgresBinary("initdb")
+	if err != nil {
+		return nil, err
+	}
+	pgCtl, err := postgresBinary("pg_ctl")
+	if err != nil {
+		return nil, err
+	}
+
+	dataDir := filepath.Join(dir, "data")
+	if err := run(initdb, "-D", dataDir, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync"); err != nil {
+		return nil, err
+	}
+
+	// Listen on a unix socket in dir only, so parallel test runs never collide on TCP ports
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses='' -c fsync=off", port, dir)
+	if err := run(pgCtl, "-D", dataDir, "-o", options, "-l", filepath.Join(dir, "postgres.log"), "-w", "start"); err != nil {
+		return nil, err
+	}
+
+	return &postgresServer{
+		dsn: fmt.Sprintf("host=%s port=%d user=postgres dbname=postgres sslmode=disable", dir, port),
+		stop: func() error {
+			defer os.RemoveAll(dir)
+			return run(pgCtl, "-D", dataDir, "-m", "immediate", "-w", "stop")
+		},
+	}, nil
+}
+
+// startEmbeddedPostgres runs the Postgres binaries bundled by embedded-postgres
+func startEmbeddedPostgres(dir string, port int) (*postgresServer, error) {
+	var logs bytes.Buffer
+	cfg := embeddedpostgres.DefaultConfig().
+		Port(uint32(port)).
+		Database("tourism_test").
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		Logger(&logs)
+	if cache := os.Getenv(EnvPostgresCache); cache != "" {
+		cfg = cfg.CachePath(cache)
+	}
+
+	server := embeddedpostgres.NewDatabase(cfg)
+	if err := server.Start(); err != nil {
+		return nil, err
+	}
+
+	return &postgresServer{
+		dsn: fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=tourism_test sslmode=disable", port),
+		stop: func() error {
+			defer os.RemoveAll(dir)
+			return server.Stop()
+		},
+	}, nil
+}
+
+// postgresBinary looks up a Postgres tool in TEST_POSTGRES_BIN or PATH
+func postgresBinary(name string) (string, error) {
+	if dir := os.Getenv(EnvPostgresBin); dir != "" {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	return exec.LookPath(name)
+}
+
+// run executes a command and includes its output in the error
+func run(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", filepath.Base(name), err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// freePort asks the kernel for an unused TCP port
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	addr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok {
+		return 0, errors.New("unexpected listener address " + listener.Addr().String())
+	}
+	return addr.Port, nil
+}
@@ -0,0 +1,240 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"tourism_recommendor/utils"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Paths of the WeChat API endpoints served by WeChat
+const (
+	WeChatTokenPath    = "/cgi-bin/token"
+	WeChatWxaCodePath  = "/wxa/getwxacodeunlimit"
+	WeChatSessionPath  = "/sns/jscode2session"
+	fakeWeChatAppID    = "wx-test-app"
+	fakeWeChatSecret   = "wx-test-secret"
+	fakeWeChatLifetime = 7200
+)
+
+// WeChat is a fake WeChat API server. It issues access tokens, draws Mini Program codes
+// as plain QR codes of "page?scene", exchanges the login codes of LoginCode and answers
+// with injected failures on request.
+type WeChat struct {
+	// URL is the root of the API, for utils.WeChatConfig.BaseURL
+	URL string
+	// AppID and AppSecret are the credentials the server accepts
+	AppID     string
+	AppSecret string
+
+	mu           sync.Mutex
+	token        string
+	issued       int
+	latency      time.Duration
+	calls        map[string]int
+	scenes       []string
+	logins       map[string]string // unused login code -> openid
+	issuedLogins int
+	failures     map[string][]func(http.ResponseWriter)
+}
+
+// NewWeChat starts a fake WeChat API server that is closed when the test finishes
+func NewWeChat(t *testing.T) *WeChat {
+	t.Helper()
+
+	w := &WeChat{
+		AppID:     fakeWeChatAppID,
+		AppSecret: fakeWeChatSecret,
+		calls:     map[string]int{},
+		logins:    map[string]string{},
+		failures:  map[string][]func(http.ResponseWriter){},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(WeChatTokenPath, w.serveToken)
+	mux.HandleFunc(WeChatWxaCodePath, w.serveWxaCode)
+	mux.HandleFunc(WeChatSessionPath, w.serveSession)
+	server := httptest.NewServer(w.intercept(mux))
+	t.Cleanup(server.Close)
+
+	w.URL = server.URL
+	return w
+}
+
+// Client returns a client of the server that retries without waiting
+func (w *WeChat) Client() *utils.HTTPWeChatClient {
+	return utils.NewWeChatClient(utils.WeChatConfig{
+		BaseURL:    w.URL,
+		AppID:      w.AppID,
+		AppSecret:  w.AppSecret,
+		Timeout:    time.Second,
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+	})
+}
+
+// FailWithCode makes the next n calls to path answer with a WeChat error code
+func (w *WeChat) FailWithCode(path string, n, errcode int) {
+	w.fail(path, n, func(rw http.ResponseWriter) {
+		writeWeChatJSON(rw, utils.WeChatErrorResponse{ErrCode: errcode, ErrMsg: "injected failure"})
+	})
+}
+
+// FailWithStatus makes the next n calls to path answer with an HTTP status
+func (w *WeChat) FailWithStatus(path string, n, status int) {
+	w.fail(path, n, func(rw http.ResponseWriter) {
+		rw.WriteHeader(status)
+	})
+}
+
+// RevokeToken invalidates the issued access token, as WeChat does when another server
+// fetches a new one
+func (w *WeChat) RevokeToken() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.token = ""
+}
+
+// SetLatency delays every response by d
+func (w *WeChat) SetLatency(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.latency = d
+}
+
+// Calls returns how many requests path has received
+func (w *WeChat) Calls(path string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.calls[path]
+}
+
+// LoginCode returns a wx.login code of the Mini Program user openID; like real codes it
+// can be exchanged once
+func (w *WeChat) LoginCode(openID string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.issuedLogins++
+	code := fmt.Sprintf("login-%d", w.issuedLogins)
+	w.logins[code] = openID
+	return code
+}
+
+// Scenes returns the scenes of the Mini Program codes drawn so far, in order
+func (w *WeChat) Scenes() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.scenes...)
+}
+
+func (w *WeChat) fail(path string, n int, respond func(http.ResponseWriter)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i := 0; i < n; i++ {
+		w.failures[path] = append(w.failures[path], respond)
+	}
+}
+
+// intercept counts the calls and answers with the injected failures before the endpoints run
+func (w *WeChat) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		w.mu.Lock()
+		w.calls[r.URL.Path]++
+		latency := w.latency
+		var respond func(http.ResponseWriter)
+		if queue := w.failures[r.URL.Path]; len(queue) > 0 {
+			respond, w.failures[r.URL.Path] = queue[0], queue[1:]
+		}
+		w.mu.Unlock()
+
+		time.Sleep(latency)
+		if respond != nil {
+			respond(rw)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func (w *WeChat) serveToken(rw http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("grant_type") != "client_credential" || query.Get("appid") != w.AppID || query.Get("secret") != w.AppSecret {
+		writeWeChatJSON(rw, utils.WeChatErrorResponse{ErrCode: 40125, ErrMsg: "invalid appsecret"})
+		return
+	}
+
+	w.mu.Lock()
+	w.issued++
+	w.token = fmt.Sprintf("token-%d", w.issued)
+	token := w.token
+	w.mu.Unlock()
+
+	writeWeChatJSON(rw, utils.WeChatTokenResponse{AccessToken: token, ExpiresIn: fakeWeChatLifetime})
+}
+
+func (w *WeChat) serveWxaCode(rw http.ResponseWriter, r *http.Request) {
+	w.mu.Lock()
+	valid := w.token != "" && r.URL.Query().Get("access_token") == w.token
+	w.mu.Unlock()
+	if !valid {
+		writeWeChatJSON(rw, utils.WeChatErrorResponse{ErrCode: utils.WeChatErrInvalidToken, ErrMsg: "invalid credential, access_token is invalid or not latest"})
+		return
+	}
+
+	var req struct {
+		Page  string `json:"page"`
+		Scene string `json:"scene"`
+		Width int    `json:"width"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Width == 0 {
+		writeWeChatJSON(rw, utils.WeChatErrorResponse{ErrCode: 47001, ErrMsg: "data format error"})
+		return
+	}
+	if len(req.Scene) > utils.MaxWxappSceneLength {
+		writeWeChatJSON(rw, utils.WeChatErrorResponse{ErrCode: 40169, ErrMsg: "invalid length for scene"})
+		return
+	}
+	w.mu.Lock()
+	w.scenes = append(w.scenes, req.Scene)
+	w.mu.Unlock()
+
+	// Like WeChat, draw the code as JPEG
+	code, err := qrcode.New(req.Page+"?"+req.Scene, qrcode.Medium)
+	if err != nil {
+		writeWeChatJSON(rw, utils.WeChatErrorResponse{ErrCode: -1, ErrMsg: err.Error()})
+		return
+	}
+	rw.Header().Set("Content-Type", "image/jpeg")
+	jpeg.Encode(rw, code.Image(req.Width), nil)
+}
+
+func (w *WeChat) serveSession(rw http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("grant_type") != "authorization_code" || query.Get("appid") != w.AppID || query.Get("secret") != w.AppSecret {
+		writeWeChatJSON(rw, utils.WeChatErrorResponse{ErrCode: 40125, ErrMsg: "invalid appsecret"})
+		return
+	}
+
+	w.mu.Lock()
+	openID, ok := w.logins[query.Get("js_code")]
+	delete(w.logins, query.Get("js_code"))
+	w.mu.Unlock()
+	if !ok {
+		writeWeChatJSON(rw, utils.WeChatErrorResponse{ErrCode: utils.WeChatErrInvalidCode, ErrMsg: "invalid code"})
+		return
+	}
+	writeWeChatJSON(rw, utils.WeChatSession{OpenID: openID, SessionKey: "session-" + openID})
+}
+
+func writeWeChatJSON(rw http.ResponseWriter, body interface{}) {
+	// WeChat answers errors with 200 and a JSON body
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(body)
+}
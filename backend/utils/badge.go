@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	"image/png"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/draw"
+)
+
+// Badge card layout in millimetres; the card is printed on an A6 page
+const (
+	badgeWidth      = 105.0
+	badgeHeight     = 148.0
+	badgeMargin     = 5.0
+	badgeAvatarW    = 32.0
+	badgeAvatarH    = 40.0
+	badgeQRSize     = 34.0
+	badgeAvatarPx   = 320 // avatar raster width, 4:5 like the frame
+	badgeFontFamily = "badge"
+)
+
+// badgeFont is the default badge font; it covers Latin and Chinese text, see fonts/README.md
+//
+//go:embed fonts/wqy-microhei.ttf
+var badgeFont []byte
+
+// Badge is the content printed on a recommendor's ID badge
+type Badge struct {
+	Title      string      // printed in the header band
+	Name       string      // recommendor name
+	Region     string      // region address
+	Validity   string      // formatted validity period
+	Avatar     image.Image // optional; a placeholder is drawn without one
+	WebQR      []byte      // PNG or JPEG image
+	WebLabel   string
+	WxappQR    []byte // PNG or JPEG image
+	WxappLabel string
+	Accent     color.Color // header band color
+	FontPath   string      // TrueType font for the text; the embedded WenQuanYi Micro Hei when empty
+}
+
+// RenderBadgePDF lays out the badge as a card and returns it as a PDF document
+func RenderBadgePDF(b Badge) ([]byte, error) {
+	font := badgeFont
+	if b.FontPath != "" {
+		var err error
+		if font, err = os.ReadFile(b.FontPath); err != nil {
+			return nil, fmt.Errorf("failed to read badge font: %w", err)
+		}
+	}
+
+	pdf := fpdf.New("P", "mm", "A6", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(b.Name, true)
+	pdf.AddUTF8FontFromBytes(badgeFontFamily, "", font)
+	pdf.AddUTF8FontFromBytes(badgeFontFamily, "B", font)
+	pdf.AddPage()
+
+	inner := badgeWidth - 2*badgeMargin
+	accent := b.Accent
+	if accent == nil {
+		accent = color.Black
+	}
+	ar, ag, ab := rgb(accent)
+
+	// Card outline and header band
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.SetLineWidth(0.3)
+	pdf.RoundedRect(badgeMargin, badgeMargin, inner, badgeHeight-2*badgeMargin, 4, "1234", "D")
+	pdf.SetFillColor(ar, ag, ab)
+	pdf.RoundedRect(badgeMargin, badgeMargin, inner, 18, 4, "12", "F")
+	pdf.SetTextColor(255, 255, 255)
+	pdf.SetFont(badgeFontFamily, "B", 14)
+	pdf.SetXY(badgeMargin, badgeMargin)
+	pdf.CellFormat(inner, 18, b.Title, "", 0, "C", false, 0, "")
+
+	// Avatar
+	avatarX, avatarY := (badgeWidth-badgeAvatarW)/2, 29.0
+	if b.Avatar != nil {
+		var avatar bytes.Buffer
+		if err := png.Encode(&avatar, coverImage(b.Avatar, badgeAvatarPx, badgeAvatarPx*5/4)); err != nil {
+			return nil, err
+		}
+		pdf.RegisterImageOptionsReader("avatar", fpdf.ImageOptions{ImageType: "PNG"}, &avatar)
+		pdf.ImageOptions("avatar", avatarX, avatarY, badgeAvatarW, badgeAvatarH, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	} else {
+		pdf.SetFillColor(235, 235, 235)
+		pdf.Rect(avatarX, avatarY, badgeAvatarW, badgeAvatarH, "F")
+		pdf.SetTextColor(150, 150, 150)
+		pdf.SetFont(badgeFontFamily, "B", 28)
+		pdf.SetXY(avatarX, avatarY)
+		pdf.CellFormat(badgeAvatarW, badgeAvatarH, initial(b.Name), "", 0, "C", false, 0, "")
+	}
+	pdf.SetDrawColor(ar, ag, ab)
+	pdf.Rect(avatarX, avatarY, badgeAvatarW, badgeAvatarH, "D")
+
+	// Name, shrunk until it fits on one line
+	pdf.SetTextColor(30, 30, 30)
+	size := 18.0
+	pdf.SetFont(badgeFontFamily, "B", size)
+	for size > 10 && pdf.GetStringWidth(b.Name) > inner-6 {
+		size--
+		pdf.SetFontSize(size)
+	}
+	pdf.SetXY(badgeMargin, 72)
+	pdf.CellFormat(inner, 9, b.Name, "", 0, "C", false, 0, "")
+
+	// Region on up to two lines, then the validity period
+	pdf.SetTextColor(90, 90, 90)
+	pdf.SetFont(badgeFontFamily, "", 9)
+	y := 82.0
+	lines := pdf.SplitText(b.Region, inner-6)
+	if len(lines) > 2 {
+		lines = lines[:2]
+	}
+	for _, line := range lines {
+		pdf.SetXY(badgeMargin, y)
+		pdf.CellFormat(inner, 4.5, line, "", 0, "C", false, 0, "")
+		y += 4.5
+	}
+	pdf.SetXY(badgeMargin, 92)
+	pdf.CellFormat(inner, 4.5, b.Validity, "", 0, "C", false, 0, "")
+
+	// QR codes side by side with their labels
+	codes := []struct {
+		name, label string
+		image       []byte
+	}{{"web", b.WebLabel, b.WebQR}, {"wxapp", b.WxappLabel, b.WxappQR}}
+	gap := (inner - 2*badgeQRSize) / 3
+	pdf.SetTextColor(30, 30, 30)
+	pdf.SetFont(badgeFontFamily, "", 9)
+	for i, code := range codes {
+		x := badgeMargin + gap + float64(i)*(badgeQRSize+gap)
+		options := fpdf.ImageOptions{ImageType: imageType(code.image)}
+		pdf.RegisterImageOptionsReader(code.name, options, bytes.NewReader(code.image))
+		pdf.ImageOptions(code.name, x, 99, badgeQRSize, badgeQRSize, false, options, 0, "")
+		pdf.SetXY(x, 134)
+		pdf.CellFormat(badgeQRSize, 5, code.label, "", 0, "C", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render badge: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// coverImage scales and center-crops img to fill a w by h 8-bit RGBA image
+func coverImage(img image.Image, w, h int) *image.RGBA {
+	src := img.Bounds()
+	crop := src
+	if src.Dx()*h > src.Dy()*w {
+		width := src.Dy() * w / h
+		crop.Min.X += (src.Dx() - width) / 2
+		crop.Max.X = crop.Min.X + width
+	} else {
+		height := src.Dx() * h / w
+		crop.Min.Y += (src.Dy() - height) / 2
+		crop.Max.Y = crop.Min.Y + height
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	stddraw.Draw(dst, dst.Bounds(), image.White, image.Point{}, stddraw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Over, nil)
+	return dst
+}
+
+// imageType returns the fpdf image type of PNG or JPEG data
+func imageType(data []byte) string {
+	if http.DetectContentType(data) == "image/jpeg" {
+		return "JPG"
+	}
+	return "PNG"
+}
+
+// initial returns the first letter of name, upper-cased
+func initial(name string) string {
+	for _, r := range name {
+		return strings.ToUpper(string(r))
+	}
+	return ""
+}
+
+// rgb returns the 8-bit components of c
+func rgb(c color.Color) (int, int, int) {
+	r, g, b, _ := c.RGBA()
+	return int(r >> 8), int(g >> 8), int(b >> 8)
+}
@@ -0,0 +1,97 @@
+package utils_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"testing"
+
+	"tourism_recommendor/utils"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestRenderBadgePDFChineseName(t *testing.T) {
+	qr, err := utils.EncodeQRCode("https://example.com/r/1", utils.DefaultQRCodeOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "张三丰"
+	pdf, err := utils.RenderBadgePDF(utils.Badge{
+		Title:      "推荐官证",
+		Name:       name,
+		Region:     "北京市/北京市/东城区",
+		Validity:   "有效期 2026-01-01 至 2026-12-31",
+		WebQR:      qr,
+		WebLabel:   "网页二维码",
+		WxappQR:    qr,
+		WxappLabel: "小程序码",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every character of the name must map to a glyph with an outline in one of the embedded fonts
+	fonts := regexp.MustCompile(`/FontFile2 (\d+) 0 R`).FindAllSubmatch(pdf, -1)
+	maps := regexp.MustCompile(`/CIDToGIDMap (\d+) 0 R`).FindAllSubmatch(pdf, -1)
+	if len(fonts) == 0 || len(fonts) != len(maps) {
+		t.Fatalf("found %d embedded fonts and %d glyph maps", len(fonts), len(maps))
+	}
+	for i := range fonts {
+		font, err := sfnt.Parse(pdfStream(t, pdf, string(fonts[i][1])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cidToGID := pdfStream(t, pdf, string(maps[i][1]))
+		if badgeFontHasGlyphs(t, font, cidToGID, name) {
+			return
+		}
+	}
+	t.Fatalf("no embedded font has glyphs for %q", name)
+}
+
+// badgeFontHasGlyphs reports whether every rune of text maps to a non-empty glyph of the font
+func badgeFontHasGlyphs(t *testing.T, font *sfnt.Font, cidToGID []byte, text string) bool {
+	t.Helper()
+	var buf sfnt.Buffer
+	for _, r := range text {
+		// Text is written with the code point as CID; the map holds a big-endian glyph ID per CID
+		if int(r)*2+1 >= len(cidToGID) {
+			return false
+		}
+		gid := sfnt.GlyphIndex(cidToGID[r*2])<<8 | sfnt.GlyphIndex(cidToGID[r*2+1])
+		if gid == 0 {
+			return false
+		}
+		segments, err := font.LoadGlyph(&buf, gid, fixed.I(16), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(segments) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// pdfStream returns the inflated stream of a PDF object
+func pdfStream(t *testing.T, pdf []byte, object string) []byte {
+	t.Helper()
+	start := regexp.MustCompile(fmt.Sprintf(`(?s)\n%s 0 obj\n.*?stream\r?\n`, object)).FindIndex(pdf)
+	if start == nil {
+		t.Fatalf("object %s not found", object)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(pdf[start[1]:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor is returned for cursors that are malformed, tampered with or issued for another sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorSignatureSize is the number of HMAC bytes kept in a cursor
+const cursorSignatureSize = 16
+
+// Cursor marks a position in a keyset-paginated list. The zero value requests the first page.
+type Cursor struct {
+	Sort   string            `json:"s"`           // sort expression the cursor was issued for
+	Values []json.RawMessage `json:"v"`           // sort key values of the boundary row, ending with its id
+	Prev   bool              `json:"p,omitempty"` // page backwards from the boundary row
+}
+
+// DecodeCursor verifies and decodes an opaque cursor for the given sort; an empty string is the first page
+func DecodeCursor(raw string, sort []SortKey) (*Cursor, error) {
+	if raw == "" {
+		return &Cursor{}, nil
+	}
+
+	payload, signature, ok := strings.Cut(raw, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signCursor(data)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	keys := withIDTieBreaker(sort)
+	if cursor.Sort != SortExpression(keys) || len(cursor.Values) != len(keys) {
+		return nil, fmt.Errorf("%w: issued for sort %q", ErrInvalidCursor, cursor.Sort)
+	}
+
+	return &cursor, nil
+}
+
+// Encode returns the opaque, signed form of the cursor
+func (c *Cursor) Encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(signCursor(data)), nil
+}
+
+// signCursor signs cursor data with the application secret
+func signCursor(data []byte) []byte {
+	mac := hmac.New(sha256.New, JWTSecret)
+	mac.Write([]byte("cursor:"))
+	mac.Write(data)
+	return mac.Sum(nil)[:cursorSignatureSize]
+}
+
+// SortExpression formats sort keys as a sort expression such as "-rating,name"
+func SortExpression(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// paginateCursor fetches the page after (or before) pr.Cursor using keyset conditions instead of OFFSET
+func paginateCursor(db *gorm.DB, pr *PaginationRequest, dest interface{}, model interface{}) (*PaginationResponse, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, fmt.Errorf("failed to parse model: %v", err)
+	}
+	keys := withIDTieBreaker(pr.Sort)
+
+	page := &PaginationResponse{Data: dest, PageSize: pr.PageSize}
+	if pr.WithTotal {
+		total, err := CountTotal(db.Model(model))
+		if err != nil {
+			return nil, fmt.Errorf("failed to count total records: %v", err)
+		}
+		totalPages := int((total + int64(pr.PageSize) - 1) / int64(pr.PageSize))
+		page.Total, page.TotalPages = &total, &totalPages
+	}
+
+	// Walking backwards reverses every key; the rows are put back in order after the query
+	backward := pr.Cursor.Prev
+	order := keys
+	if backward {
+		order = make([]SortKey, len(keys))
+		for i, key := range keys {
+			order[i] = SortKey{Field: key.Field, Desc: !key.Desc}
+		}
+	}
+
+	query := db
+	if len(pr.Cursor.Values) > 0 {
+		values, err := cursorValues(stmt, keys, pr.Cursor.Values)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(keysetCondition(order, values))
+	}
+	for _, key := range order {
+		query = query.Order(orderByColumn(key))
+	}
+
+	// One extra row tells whether another page follows
+	if err := query.Limit(pr.PageSize + 1).Find(dest).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch paginated data: %v", err)
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	more := rows.Len() > pr.PageSize
+	if more {
+		rows.Set(rows.Slice(0, pr.PageSize))
+	}
+	if backward {
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			first, last := rows.Index(i).Interface(), rows.Index(j).Interface()
+			rows.Index(i).Set(reflect.ValueOf(last))
+			rows.Index(j).Set(reflect.ValueOf(first))
+		}
+	}
+	if rows.Len() == 0 {
+		return page, nil
+	}
+
+	started := len(pr.Cursor.Values) > 0
+	var err error
+	if more && !backward || backward && started {
+		if page.NextCursor, err = rowCursor(stmt, keys, rows.Index(rows.Len()-1), false); err != nil {
+			return nil, err
+		}
+	}
+	if more && backward || !backward && started {
+		if page.PrevCursor, err = rowCursor(stmt, keys, rows.Index(0), true); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// keysetCondition matches the rows that come after values in the order of keys:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for descending keys
+func keysetCondition(keys []SortKey, values []interface{}) clause.Expression {
+	conditions := make([]clause.Expression, 0, len(keys))
+	for i, key := range keys {
+		and := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, clause.Eq{Column: orderByColumn(keys[j]).Column, Value: values[j]})
+		}
+		if key.Desc {
+			and = append(and, clause.Lt{Column: orderByColumn(key).Column, Value: values[i]})
+		} else {
+			and = append(and, clause.Gt{Column: orderByColumn(key).Column, Value: values[i]})
+		}
+		conditions = append(conditions, clause.And(and...))
+	}
+	return clause.And(clause.Or(conditions...))
+}
+
+// cursorValues decodes the cursor values into the Go types of the sort columns
+func cursorValues(stmt *gorm.Statement, keys []SortKey, raw []json.RawMessage) ([]interface{}, error) {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		field := stmt.Schema.LookUpField(key.Field)
+		if field == nil {
+			return nil, fmt.Errorf("unknown sort column %q", key.Field)
+		}
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw[i], value.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = value.Elem().Interface()
+	}
+	return values, nil
+}
+
+// rowCursor builds the cursor positioned at row
+func rowCursor(stmt *gorm.Statement, keys []SortKey, row reflect.Value, prev bool) (string, error) {
+	cursor := Cursor{Sort: SortExpression(keys), Prev: prev}
+	for _, key := range keys {
+		field := stmt.Schema.LookUpField(key.Field)
+		if field == nil {
+			return "", fmt.Errorf("unknown sort column %q", key.Field)
+		}
+		value, _ := field.ValueOf(context.Background(), reflect.Indirect(row))
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		cursor.Values = append(cursor.Values, data)
+	}
+	return cursor.Encode()
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Include is a relationship a client may ask to embed with include=name
+type Include struct {
+	Name        string // query value and JSON key of the relationship
+	Association string // GORM association to preload
+	Column      string // column the association is joined on, selected even when not requested
+}
+
+// Fieldset declares the fields a client may select and the relationships it may include.
+// Field names are the JSON names of the model, which are also its column names.
+type Fieldset struct {
+	Fields   []string
+	Includes []Include
+}
+
+// Projection is a parsed fields/include selection
+type Projection struct {
+	Fields   []string // nil selects every field
+	Includes []Include
+}
+
+// InvalidFieldError reports a fields or include value outside the resource's allowlist
+type InvalidFieldError struct {
+	Param   string
+	Field   string
+	Allowed []string
+}
+
+// Error implements the error interface
+func (e *InvalidFieldError) Error() string {
+	return fmt.Sprintf("cannot %s %q, allowed: %s", e.Param, e.Field, strings.Join(e.Allowed, ", "))
+}
+
+// IncludeNames returns the names of the relationships that may be included
+func (s *Fieldset) IncludeNames() []string {
+	names := make([]string, len(s.Includes))
+	for i, include := range s.Includes {
+		names[i] = include.Name
+	}
+	return names
+}
+
+// Parse parses comma-separated fields and include values such as "id,name" and "destinations"
+func (s *Fieldset) Parse(fields, include string) (*Projection, error) {
+	p := &Projection{}
+
+	if fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if !slices.Contains(s.Fields, field) {
+				return nil, &InvalidFieldError{Param: "fields", Field: field, Allowed: s.Fields}
+			}
+			if !slices.Contains(p.Fields, field) {
+				p.Fields = append(p.Fields, field)
+			}
+		}
+	}
+
+	if include != "" {
+		for _, name := range strings.Split(include, ",") {
+			name = strings.TrimSpace(name)
+			i := slices.IndexFunc(s.Includes, func(inc Include) bool { return inc.Name == name })
+			if i < 0 {
+				return nil, &InvalidFieldError{Param: "include", Field: name, Allowed: s.IncludeNames()}
+			}
+			if !p.Included(name) {
+				p.Includes = append(p.Includes, s.Includes[i])
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// Included reports whether the named relationship was requested
+func (p *Projection) Included(name string) bool {
+	return slices.ContainsFunc(p.Includes, func(inc Include) bool { return inc.Name == name })
+}
+
+// Apply selects the requested columns and preloads the included relationships. The id,
+// the columns of the sort keys and the join columns are always selected, since pagination,
+// localization and preloading read them.
+func (p *Projection) Apply(db *gorm.DB, sort []SortKey) *gorm.DB {
+	for _, include := range p.Includes {
+		db = db.Preload(include.Association)
+	}
+	if p.Fields == nil {
+		return db
+	}
+
+	columns := []string{"id"}
+	add := func(column string) {
+		if column != "" && !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	for _, field := range p.Fields {
+		add(field)
+	}
+	for _, key := range sort {
+		add(key.Field)
+	}
+	for _, include := range p.Includes {
+		add(include.Column)
+	}
+	return db.Select(columns)
+}
+
+// Render trims a record or a slice of records to the requested fields and included
+// relationships, keeping the requested order. Without fields the data is returned as is.
+func (p *Projection) Render(data interface{}) (interface{}, error) {
+	if p.Fields == nil {
+		return data, nil
+	}
+
+	keys := append([]string(nil), p.Fields...)
+	for _, include := range p.Includes {
+		keys = append(keys, include.Name)
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(data))
+	if value.Kind() != reflect.Slice {
+		return renderObject(data, keys)
+	}
+
+	rows := make([]json.RawMessage, value.Len())
+	for i := range rows {
+		row, err := renderObject(value.Index(i).Interface(), keys)
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// renderObject encodes record as a JSON object holding only keys, in that order
+func renderObject(record interface{}, keys []string) (json.RawMessage, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, key := range keys {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(raw)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FilterType is the value type of a filterable field
+type FilterType string
+
+// Filter value types
+const (
+	FilterString FilterType = "string"
+	FilterInt    FilterType = "int"
+	FilterNumber FilterType = "number"
+	FilterTime   FilterType = "time" // RFC 3339 timestamp or YYYY-MM-DD date
+)
+
+// FilterOp is a filter operator, written as field[op]=value in the query string
+type FilterOp string
+
+// Filter operators. OpRange is only used in specs, where it allows gt, gte, lt and lte.
+const (
+	OpEq    FilterOp = "eq"
+	OpNe    FilterOp = "ne"
+	OpIn    FilterOp = "in" // comma-separated values
+	OpLike  FilterOp = "like"
+	OpGt    FilterOp = "gt"
+	OpGte   FilterOp = "gte"
+	OpLt    FilterOp = "lt"
+	OpLte   FilterOp = "lte"
+	OpRange FilterOp = "range"
+)
+
+// rangeOps are the operators allowed by OpRange
+var rangeOps = []FilterOp{OpGt, OpGte, OpLt, OpLte}
+
+// FilterField declares a query parameter that filters a list
+type FilterField struct {
+	Name        string     // query parameter name
+	Column      string     // filtered column, defaults to Name
+	Type        FilterType // value type, defaults to FilterString
+	Ops         []FilterOp // allowed operators; the first one applies to a bare name=value
+	Values      []string   // allowed values, if the field is an enum
+	Description string
+}
+
+// FilterSpec lists the filterable fields of a resource
+type FilterSpec []FilterField
+
+// Filter is a parsed, validated filter condition
+type Filter struct {
+	Field *FilterField
+	Op    FilterOp
+	Value interface{} // a []interface{} for OpIn
+}
+
+// FilterError reports a query parameter that does not match the filter spec
+type FilterError struct {
+	Param   string
+	Reason  string
+	Allowed []string
+}
+
+// Error implements the error interface
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter %s: %s", e.Param, e.Reason)
+}
+
+// column returns the filtered column
+func (f *FilterField) column() string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return f.Name
+}
+
+// Operators returns the operators the field accepts, with OpRange expanded
+func (f *FilterField) Operators() []FilterOp {
+	var ops []FilterOp
+	for _, op := range f.Ops {
+		if op == OpRange {
+			ops = append(ops, rangeOps...)
+		} else {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// field looks up a field by query parameter name
+func (s FilterSpec) field(name string) *FilterField {
+	for i := range s {
+		if s[i].Name == name {
+			return &s[i]
+		}
+	}
+	return nil
+}
+
+// Names returns the query parameter names of the spec's fields
+func (s FilterSpec) Names() []string {
+	names := make([]string, len(s))
+	for i, field := range s {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// Parse reads the filters in query, such as age[gte]=30 or gender[in]=male,female.
+// Parameters that are not in the spec are left to the caller, unless they use the
+// field[op] form, which is reserved for filters.
+func (s FilterSpec) Parse(query url.Values) ([]Filter, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var filters []Filter
+	for _, key := range keys {
+		name, op, bracketed := splitFilterKey(key)
+		field := s.field(name)
+		if field == nil {
+			if bracketed {
+				return nil, &FilterError{Param: key, Reason: "unknown field", Allowed: s.Names()}
+			}
+			continue
+		}
+
+		ops := field.Operators()
+		if !bracketed {
+			op = ops[0]
+		}
+		if !slices.Contains(ops, op) {
+			allowed := make([]string, len(ops))
+			for i, o := range ops {
+				allowed[i] = string(o)
+			}
+			return nil, &FilterError{Param: key, Reason: "unsupported operator", Allowed: allowed}
+		}
+
+		for _, raw := range query[key] {
+			if raw == "" {
+				continue
+			}
+			value, err := field.parseValue(op, raw)
+			if err != nil {
+				return nil, &FilterError{Param: key, Reason: err.Error(), Allowed: field.Values}
+			}
+			filters = append(filters, Filter{Field: field, Op: op, Value: value})
+		}
+	}
+
+	return filters, nil
+}
+
+// splitFilterKey splits "age[gte]" into "age" and "gte"
+func splitFilterKey(key string) (string, FilterOp, bool) {
+	name, rest, ok := strings.Cut(key, "[")
+	if !ok || !strings.HasSuffix(rest, "]") {
+		return key, "", false
+	}
+	return name, FilterOp(strings.TrimSuffix(rest, "]")), true
+}
+
+// parseValue converts a raw query value to the field type
+func (f *FilterField) parseValue(op FilterOp, raw string) (interface{}, error) {
+	if op != OpIn {
+		return f.parseScalar(raw)
+	}
+
+	var values []interface{}
+	for _, item := range strings.Split(raw, ",") {
+		value, err := f.parseScalar(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// parseScalar converts a single raw value to the field type
+func (f *FilterField) parseScalar(raw string) (interface{}, error) {
+	if len(f.Values) > 0 && !slices.Contains(f.Values, raw) {
+		return nil, fmt.Errorf("%q is not an allowed value", raw)
+	}
+
+	switch f.Type {
+	case FilterInt:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", raw)
+		}
+		return n, nil
+	case FilterNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return n, nil
+	case FilterTime:
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a date or RFC 3339 time", raw)
+		}
+		return t, nil
+	default:
+		return raw, nil
+	}
+}
+
+// ApplyFilters adds the filter conditions to the query
+func ApplyFilters(db *gorm.DB, filters []Filter) *gorm.DB {
+	for _, filter := range filters {
+		column := filter.Field.column()
+		switch filter.Op {
+		case OpEq:
+			db = ApplyEqualFilter(db, column, filter.Value)
+		case OpNe:
+			db = db.Where(fmt.Sprintf("%s <> ?", column), filter.Value)
+		case OpIn:
+			db = ApplyInFilter(db, column, filter.Value)
+		case OpLike:
+			db = ApplyFilter(db, column, filter.Value)
+		case OpGte:
+			db = ApplyDateRangeFilter(db, column, filter.Value, nil)
+		case OpLte:
+			db = ApplyDateRangeFilter(db, column, nil, filter.Value)
+		case OpGt:
+			db = db.Where(fmt.Sprintf("%s > ?", column), filter.Value)
+		case OpLt:
+			db = db.Where(fmt.Sprintf("%s < ?", column), filter.Value)
+		}
+	}
+	return db
+}
+
+// HasFilter reports whether any filter applies to the named field
+func HasFilter(filters []Filter, name string) bool {
+	for _, filter := range filters {
+		if filter.Field.Name == name {
+			return true
+		}
+	}
+	return false
+}
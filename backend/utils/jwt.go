@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,13 +15,28 @@ var (
 	// TokenExpiration is the default token expiration time
 	TokenExpiration = 24 * time.Hour
 
+	// TouristTokenExpiration is the expiration time of tourist tokens
+	TouristTokenExpiration = 30 * 24 * time.Hour
+
 	// ErrInvalidToken is returned when token is invalid
 	ErrInvalidToken = errors.New("invalid token")
 
 	// ErrExpiredToken is returned when token is expired
 	ErrExpiredToken = errors.New("token has expired")
+
+	// ErrWrongAudience is returned when a valid token was issued to another kind of user
+	ErrWrongAudience = errors.New("token was issued to another audience")
+)
+
+// Token audiences: admin tokens and tourist tokens are not interchangeable
+const (
+	AudienceAdmin   = "admin"
+	AudienceTourist = "tourist"
 )
 
+// tokenIssuer is the issuer of every token
+const tokenIssuer = "tourism-recommender"
+
 // Claims represents the JWT claims
 type Claims struct {
 	UserID   uint   `json:"user_id"`
@@ -29,6 +45,12 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// TouristClaims represents the JWT claims of a tourist signed in through the Mini Program
+type TouristClaims struct {
+	TouristID uint `json:"tourist_id"`
+	jwt.RegisteredClaims
+}
+
 // GenerateToken generates a new JWT token for a user
 func GenerateToken(userID uint, username, role string) (string, error) {
 	// Create claims with expiration time
@@ -40,7 +62,8 @@ func GenerateToken(userID uint, username, role string) (string, error) {
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExpiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "tourism-recommender",
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{AudienceAdmin},
 		},
 	}
 
@@ -74,12 +97,54 @@ func ValidateToken(tokenString string) (*Claims, error) {
 
 	// Extract claims
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		// Admin tokens issued before audiences were introduced have none
+		if len(claims.Audience) > 0 && !slices.Contains(claims.Audience, AudienceAdmin) {
+			return nil, ErrWrongAudience
+		}
 		return claims, nil
 	}
 
 	return nil, ErrInvalidToken
 }
 
+// GenerateTouristToken generates a new JWT token for a tourist
+func GenerateTouristToken(touristID uint) (string, error) {
+	now := time.Now()
+	claims := TouristClaims{
+		TouristID: touristID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(TouristTokenExpiration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    tokenIssuer,
+			Audience:  jwt.ClaimStrings{AudienceTourist},
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(JWTSecret)
+}
+
+// ValidateTouristToken validates a tourist JWT token and returns the claims
+func ValidateTouristToken(tokenString string) (*TouristClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TouristClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return JWTSecret, nil
+	}, jwt.WithAudience(AudienceTourist))
+	if errors.Is(err, jwt.ErrTokenInvalidAudience) || errors.Is(err, jwt.ErrTokenRequiredClaimMissing) {
+		// Admin tokens, including those issued before audiences were introduced
+		return nil, ErrWrongAudience
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*TouristClaims); ok && token.Valid && claims.TouristID != 0 {
+		return claims, nil
+	}
+	return nil, ErrInvalidToken
+}
+
 // RefreshToken generates a new token from an existing valid token
 func RefreshToken(tokenString string) (string, error) {
 	// Validate existing token
@@ -152,3 +217,10 @@ func SetTokenExpiration(expiration time.Duration) {
 		TokenExpiration = expiration
 	}
 }
+
+// SetTouristTokenExpiration sets the expiration time of tourist tokens
+func SetTouristTokenExpiration(expiration time.Duration) {
+	if expiration > 0 {
+		TouristTokenExpiration = expiration
+	}
+}
@@ -11,19 +11,25 @@ import (
 
 // PaginationRequest holds pagination parameters
 type PaginationRequest struct {
-	Page     int    `json:"page"`
-	PageSize int    `json:"page_size"`
-	SortBy   string `json:"sort_by"`
-	SortDesc bool   `json:"sort_desc"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+	Sort     []SortKey `json:"sort"`
+
+	// Cursor switches to keyset pagination; Page is ignored and the total is only counted with WithTotal
+	Cursor    *Cursor `json:"-"`
+	WithTotal bool    `json:"-"`
 }
 
-// PaginationResponse holds paginated results
+// PaginationResponse holds paginated results. Cursor pages have no page number and
+// carry a total only when it was requested.
 type PaginationResponse struct {
 	Data       interface{} `json:"data"`
-	Total      int64       `json:"total"`
-	Page       int         `json:"page"`
+	Total      *int64      `json:"total,omitempty"`
+	Page       int         `json:"page,omitempty"`
 	PageSize   int         `json:"page_size"`
-	TotalPages int         `json:"total_pages"`
+	TotalPages *int        `json:"total_pages,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
 }
 
 // NewPaginationRequest creates a default pagination request
@@ -31,13 +37,12 @@ func NewPaginationRequest() *PaginationRequest {
 	return &PaginationRequest{
 		Page:     1,
 		PageSize: 10,
-		SortBy:   "id",
-		SortDesc: false,
+		Sort:     []SortKey{{Field: "id"}},
 	}
 }
 
-// ParsePaginationRequest parses query parameters into PaginationRequest
-func ParsePaginationRequest(pageStr, pageSizeStr, sortBy, sortOrder string) *PaginationRequest {
+// ParsePaginationRequest parses the page query parameters into PaginationRequest; sorting is set by the caller
+func ParsePaginationRequest(pageStr, pageSizeStr string) *PaginationRequest {
 	pr := NewPaginationRequest()
 
 	// Parse page
@@ -50,16 +55,6 @@ func ParsePaginationRequest(pageStr, pageSizeStr, sortBy, sortOrder string) *Pag
 		pr.PageSize = pageSize
 	}
 
-	// Parse sort by
-	if sortBy != "" {
-		pr.SortBy = sortBy
-	}
-
-	// Parse sort order
-	if strings.ToLower(sortOrder) == "desc" {
-		pr.SortDesc = true
-	}
-
 	return pr
 }
 
@@ -67,14 +62,8 @@ func ParsePaginationRequest(pageStr, pageSizeStr, sortBy, sortOrder string) *Pag
 func ApplyPagination(db *gorm.DB, pr *PaginationRequest) *gorm.DB {
 	offset := (pr.Page - 1) * pr.PageSize
 
-	// Apply sorting
-	if pr.SortBy != "" {
-		orderDirection := "ASC"
-		if pr.SortDesc {
-			orderDirection = "DESC"
-		}
-		db = db.Order(fmt.Sprintf("%s %s", pr.SortBy, orderDirection))
-	}
+	// Apply sorting; fields were checked against the resource's whitelist when parsed
+	db = ApplySort(db, pr.Sort)
 
 	// Apply pagination
 	return db.Offset(offset).Limit(pr.PageSize)
@@ -96,10 +85,10 @@ func CreatePaginationResponse(data interface{}, total int64, page, pageSize int)
 
 	return &PaginationResponse{
 		Data:       data,
-		Total:      total,
+		Total:      &total,
 		Page:       page,
 		PageSize:   pageSize,
-		TotalPages: totalPages,
+		TotalPages: &totalPages,
 	}
 }
 
@@ -111,12 +100,42 @@ func ApplyFilter(db *gorm.DB, field string, value interface{}) *gorm.DB {
 
 	// Handle string filtering (case-insensitive LIKE)
 	if str, ok := value.(string); ok {
-		return db.Where(fmt.Sprintf("%s ILIKE ?", field), "%"+str+"%")
+		return db.Where(caseInsensitiveLike(db, field), "%"+str+"%")
 	}
 
 	return db.Where(fmt.Sprintf("%s = ?", field), value)
 }
 
+// ApplySegmentFilter matches rows where value is one whole sep-separated segment of field.
+// Spaces around separators are ignored and LIKE wildcards in value match literally.
+func ApplySegmentFilter(db *gorm.DB, field, sep, value string) *gorm.DB {
+	value = strings.ReplaceAll(value, " ", "")
+	if value == "" {
+		return db
+	}
+
+	condition := fmt.Sprintf(`? || REPLACE(%s, ' ', '') || ? LIKE ? ESCAPE '\'`, field)
+	return db.Where(condition, sep, sep, "%"+sep+escapeLike(value)+sep+"%")
+}
+
+// escapeLike escapes the LIKE wildcards in s using backslash as the escape character
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// caseInsensitiveLike returns a case-insensitive LIKE condition on field for the query's dialect
+func caseInsensitiveLike(db *gorm.DB, field string) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return fmt.Sprintf("%s ILIKE ?", field)
+	case "sqlite":
+		// SQLite's LIKE already ignores case (for ASCII, same as its LOWER)
+		return fmt.Sprintf("%s LIKE ?", field)
+	default:
+		return fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", field)
+	}
+}
+
 // ApplyEqualFilter applies an exact match filter condition to the query
 func ApplyEqualFilter(db *gorm.DB, field string, value interface{}) *gorm.DB {
 	if value == nil || value == "" {
@@ -154,6 +173,10 @@ func ApplyInFilter(db *gorm.DB, field string, values interface{}) *gorm.DB {
 
 // Paginate is a helper function that applies pagination and returns the result
 func Paginate(db *gorm.DB, pr *PaginationRequest, dest interface{}, model interface{}) (*PaginationResponse, error) {
+	if pr.Cursor != nil {
+		return paginateCursor(db, pr, dest, model)
+	}
+
 	// Count total records before pagination
 	total, err := CountTotal(db.Model(model))
 	if err != nil {
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// QR code types of a recommendor
+const (
+	QRCodeWeb   = "web"   // opens the recommendor's web page
+	QRCodeWxapp = "wxapp" // opens the recommendor in the Mini Program
+)
+
+// QR code sizes in pixels
+const (
+	DefaultQRCodeSize = 256
+	MinQRCodeSize     = 64
+	MaxQRCodeSize     = 1024
+)
+
+// QRCodeTypes lists the QR code types generated for every recommendor
+var QRCodeTypes = []string{QRCodeWeb, QRCodeWxapp}
+
+// qrCodeExtensions are the file extensions of stored QR codes in the order they are looked up.
+// Codes are stored as PNG; Mini Program codes were stored as the JPEG WeChat draws them in before.
+var qrCodeExtensions = []string{".png", ".jpg"}
+
+// qrCodeFileName returns the storage file name of a recommendor's QR code with extension ext
+func qrCodeFileName(recommendorID uint, kind, ext string) string {
+	return fmt.Sprintf("%d_%s%s", recommendorID, kind, ext)
+}
+
+// StoreQRCode saves a default-size QR code image as PNG, replacing the previous one, and
+// returns the stored image. Images in other formats, such as Mini Program codes, are converted.
+func StoreQRCode(recommendorID uint, kind string, image []byte) ([]byte, error) {
+	image, err := qrCodePNG(image)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := WriteFile(QRCodeDir, qrCodeFileName(recommendorID, kind, qrCodeExtensions[0]), image); err != nil {
+		return nil, err
+	}
+	// Drop the previous image when it was stored in another format
+	for _, ext := range qrCodeExtensions[1:] {
+		if err := os.Remove(filepath.Join(QRCodeDir, qrCodeFileName(recommendorID, kind, ext))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return image, nil
+}
+
+// qrCodePNG returns a QR code image encoded as PNG, converting it from the other stored formats
+func qrCodePNG(data []byte) ([]byte, error) {
+	switch contentType := http.DetectContentType(data); contentType {
+	case "image/png":
+		return data, nil
+	case "image/jpeg":
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode QR code: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported QR code image type %q", contentType)
+	}
+}
+
+// LoadQRCode reads a stored QR code image as PNG; the error wraps os.ErrNotExist when none is
+// stored. Codes stored in another format are converted and stored again.
+func LoadQRCode(recommendorID uint, kind string) ([]byte, error) {
+	for i, ext := range qrCodeExtensions {
+		image, err := os.ReadFile(filepath.Join(QRCodeDir, qrCodeFileName(recommendorID, kind, ext)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil || i == 0 {
+			return image, err
+		}
+		return StoreQRCode(recommendorID, kind, image)
+	}
+	return nil, fmt.Errorf("no QR code stored for recommendor %d (%s): %w", recommendorID, kind, os.ErrNotExist)
+}
+
+// maxQRCodeVariants bounds the number of rendered QR code variants kept in memory
+const maxQRCodeVariants = 256
+
+var (
+	// qrCodeVariants caches QR codes rendered with other options than the stored ones
+	qrCodeVariants   = map[string][]byte{}
+	qrCodeVariantsMu sync.Mutex
+)
+
+// QRCodeVariantKey identifies a QR code rendered with opts from the stored code with ETag etag
+func QRCodeVariantKey(recommendorID uint, kind string, opts QRCodeOptions, etag string) string {
+	return fmt.Sprintf("%d:%s:%s:%d:%d:%s:%s:%t:%s", recommendorID, kind, opts.Format, opts.Size, opts.Level,
+		HexColor(opts.Foreground), HexColor(opts.Background), opts.Logo != nil, etag)
+}
+
+// CachedQRCodeVariant returns the variant cached under key, rendering and caching it when missing.
+// When the cache is full an arbitrary variant is evicted.
+func CachedQRCodeVariant(key string, render func() ([]byte, error)) ([]byte, error) {
+	qrCodeVariantsMu.Lock()
+	image, ok := qrCodeVariants[key]
+	qrCodeVariantsMu.Unlock()
+	if ok {
+		return image, nil
+	}
+
+	image, err := render()
+	if err != nil {
+		return nil, err
+	}
+
+	qrCodeVariantsMu.Lock()
+	defer qrCodeVariantsMu.Unlock()
+	if len(qrCodeVariants) >= maxQRCodeVariants {
+		for evicted := range qrCodeVariants {
+			delete(qrCodeVariants, evicted)
+			break
+		}
+	}
+	qrCodeVariants[key] = image
+	return image, nil
+}
+
+// QRCodeETag returns the entity tag of a QR code image
+func QRCodeETag(image []byte) string {
+	sum := sha256.Sum256(image)
+	return hex.EncodeToString(sum[:8])
+}
+
+// QRCodeURL returns the absolute URL, under the API's baseURL, a stored QR code is served at.
+// The v parameter changes with the image, so the URL can be cached for as long as it is
+// referenced. Without an image the URL is unversioned and the code is drawn when first requested.
+func QRCodeURL(baseURL string, recommendorID uint, kind string, image []byte) string {
+	url := strings.TrimSuffix(baseURL, "/") + QRCodePath(recommendorID, kind)
+	if image == nil {
+		return url
+	}
+	return url + "&v=" + QRCodeETag(image)
+}
+
+// QRCodePath returns the host-relative path of the QR code image endpoint, the form QR code
+// URLs were stored in before they were made absolute
+func QRCodePath(recommendorID uint, kind string) string {
+	return fmt.Sprintf("/api/v1/recommendors/%d/qrcode.png?type=%s", recommendorID, kind)
+}
+
+// IsDataURL reports whether s is an inline data URL, the format QR codes were stored in before
+func IsDataURL(s string) bool {
+	return strings.HasPrefix(s, "data:")
+}
+
+// DecodeDataURL decodes a base64 data URL such as data:image/png;base64,iVBOR...
+func DecodeDataURL(s string) ([]byte, error) {
+	header, payload, ok := strings.Cut(s, ",")
+	if !ok || !IsDataURL(header) || !strings.HasSuffix(header, ";base64") {
+		return nil, errors.New("not a base64 data URL")
+	}
+	return base64.StdEncoding.DecodeString(payload)
+}
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	_ "image/gif"  // register decoders for logos and avatars
+	_ "image/jpeg" // register decoders for logos, avatars and Mini Program codes
+	"image/png"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register decoders for logos and avatars
+)
+
+// QR code output formats
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+// QRCodeLevels lists the error correction levels by name, from lowest to highest
+var QRCodeLevels = []string{"L", "M", "Q", "H"}
+
+// qrLogoRatio is the share of the QR code's width covered by a center logo
+const qrLogoRatio = 0.22
+
+// QRCodeOptions controls how a QR code is drawn
+type QRCodeOptions struct {
+	Size       int                  // side length in pixels
+	Level      qrcode.RecoveryLevel // error correction level, raised to Highest when a logo is drawn
+	Foreground color.Color
+	Background color.Color
+	Logo       image.Image // drawn in the center when set
+	Format     string      // QRFormatPNG or QRFormatSVG
+}
+
+// DefaultQRCodeOptions returns black-on-white PNG options at the default size and Medium correction
+func DefaultQRCodeOptions() QRCodeOptions {
+	return QRCodeOptions{
+		Size:       DefaultQRCodeSize,
+		Level:      qrcode.Medium,
+		Foreground: color.Black,
+		Background: color.White,
+		Format:     QRFormatPNG,
+	}
+}
+
+// ParseQRCodeLevel parses an error correction level name (L, M, Q or H)
+func ParseQRCodeLevel(name string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(name) {
+	case "L":
+		return qrcode.Low, nil
+	case "M":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	}
+	return 0, fmt.Errorf("unknown error correction level %q, allowed: %s", name, strings.Join(QRCodeLevels, ", "))
+}
+
+// ParseHexColor parses a color written as #rgb or #rrggbb; the # is optional
+func ParseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	var r, g, b uint8
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	if _, err := fmt.Sscanf(hex, "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 0xff}, nil
+}
+
+// HexColor formats c as #rrggbb
+func HexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+var (
+	logoCache   = map[string]image.Image{}
+	logoCacheMu sync.Mutex
+)
+
+// LoadQRCodeLogo reads a logo image from path, caching it for later calls
+func LoadQRCodeLogo(path string) (image.Image, error) {
+	logoCacheMu.Lock()
+	defer logoCacheMu.Unlock()
+
+	if logo, ok := logoCache[path]; ok {
+		return logo, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read QR code logo: %w", err)
+	}
+	logo, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode QR code logo: %w", err)
+	}
+	logoCache[path] = logo
+	return logo, nil
+}
+
+// EncodeQRCode encodes content as a QR code image in the format of opts
+func EncodeQRCode(content string, opts QRCodeOptions) ([]byte, error) {
+	level := opts.Level
+	if opts.Logo != nil {
+		// The logo hides modules in the center, which the error correction has to recover
+		level = qrcode.Highest
+	}
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Foreground != nil {
+		q.ForegroundColor = opts.Foreground
+	}
+	if opts.Background != nil {
+		q.BackgroundColor = opts.Background
+	}
+
+	if opts.Format == QRFormatSVG {
+		return qrCodeSVG(q, opts)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, opts.Size, opts.Size))
+	stddraw.Draw(img, img.Bounds(), q.Image(opts.Size), image.Point{}, stddraw.Src)
+	if opts.Logo != nil {
+		drawLogo(img, opts.Logo, q.BackgroundColor)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawLogo scales logo into the center of img on a padded square of the background color
+func drawLogo(img *image.RGBA, logo image.Image, background color.Color) {
+	size := img.Bounds().Dx()
+	side := int(float64(size) * qrLogoRatio)
+	pad := side / 10
+	plate := image.Rect((size-side)/2-pad, (size-side)/2-pad, (size+side)/2+pad, (size+side)/2+pad)
+	stddraw.Draw(img, plate, image.NewUniform(background), image.Point{}, stddraw.Src)
+	draw.CatmullRom.Scale(img, fitRect(logo.Bounds(), plate.Inset(pad)), logo, logo.Bounds(), draw.Over, nil)
+}
+
+// fitRect returns the largest rectangle with the aspect ratio of src centered in dst
+func fitRect(src, dst image.Rectangle) image.Rectangle {
+	w, h := dst.Dx(), dst.Dy()
+	if src.Dx()*h > src.Dy()*w {
+		h = src.Dy() * w / src.Dx()
+	} else {
+		w = src.Dx() * h / src.Dy()
+	}
+	origin := dst.Min.Add(image.Pt((dst.Dx()-w)/2, (dst.Dy()-h)/2))
+	return image.Rectangle{Min: origin, Max: origin.Add(image.Pt(w, h))}
+}
+
+// qrCodeSVG draws the modules of q as one path scaled to opts.Size, with the logo embedded as PNG
+func qrCodeSVG(q *qrcode.QRCode, opts QRCodeOptions) ([]byte, error) {
+	bitmap := q.Bitmap()
+	n := len(bitmap)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, n, n, HexColor(q.BackgroundColor))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, HexColor(q.ForegroundColor))
+	for y, row := range bitmap {
+		for x := 0; x < n; x++ {
+			if !row[x] {
+				continue
+			}
+			run := 1
+			for x+run < n && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", x, y, run, run)
+			x += run
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if opts.Logo != nil {
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, opts.Logo); err != nil {
+			return nil, err
+		}
+		side := float64(n) * qrLogoRatio
+		pad := side / 10
+		fmt.Fprintf(&buf, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`,
+			(float64(n)-side)/2-pad, (float64(n)-side)/2-pad, side+2*pad, side+2*pad, HexColor(q.BackgroundColor))
+		fmt.Fprintf(&buf, `<image x="%.2f" y="%.2f" width="%.2f" height="%.2f" href="data:image/png;base64,%s"/>`,
+			(float64(n)-side)/2, (float64(n)-side)/2, side, side, base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+
+	buf.WriteString("</svg>")
+	return buf.Bytes(), nil
+}
+
+// ResizeQRCode scales a QR code image, such as a Mini Program code from WeChat, to size and encodes it as PNG
+func ResizeQRCode(data []byte, size int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode QR code: %w", err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(img, img.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EmbedImageSVG wraps a raster image, such as a Mini Program code from WeChat, in an SVG document
+func EmbedImageSVG(data []byte, size int) []byte {
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d"><image width="%d" height="%d" href="data:%s;base64,%s"/></svg>`,
+		size, size, size, size, size, size, http.DetectContentType(data), base64.StdEncoding.EncodeToString(data)))
+}
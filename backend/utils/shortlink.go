@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ShortCodeLength is the number of characters of a generated short link code
+const ShortCodeLength = 8
+
+// shortCodeAlphabet leaves out characters that are easily confused when typed from print
+const shortCodeAlphabet = "23456789abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// Coarse sources of a QR code scan, derived from the user agent
+const (
+	ScanSourceWeChat  = "wechat"
+	ScanSourceAlipay  = "alipay"
+	ScanSourceMobile  = "mobile"
+	ScanSourceDesktop = "desktop"
+	ScanSourceBot     = "bot"
+	ScanSourceUnknown = "unknown"
+)
+
+// ScanSources lists the scan sources in the order they are documented
+var ScanSources = []string{ScanSourceWeChat, ScanSourceAlipay, ScanSourceMobile, ScanSourceDesktop, ScanSourceBot, ScanSourceUnknown}
+
+// NewShortCode returns a random short link code
+func NewShortCode() (string, error) {
+	code := make([]byte, ShortCodeLength)
+	max := big.NewInt(int64(len(shortCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = shortCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// ShortLinkURL returns the public URL, under baseURL, of a short link
+func ShortLinkURL(baseURL, code string) string {
+	return fmt.Sprintf("%s/r/%s", strings.TrimSuffix(baseURL, "/"), code)
+}
+
+// RecommendorWebURL returns the URL, under baseURL, of the web page of a recommendor
+func RecommendorWebURL(baseURL string, recommendorID uint) string {
+	return fmt.Sprintf("%s/recommendors/%d", strings.TrimSuffix(baseURL, "/"), recommendorID)
+}
+
+// ScanSource classifies the user agent of a scan into one of the ScanSources
+func ScanSource(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return ScanSourceUnknown
+	case strings.Contains(ua, "micromessenger"):
+		return ScanSourceWeChat
+	case strings.Contains(ua, "alipayclient"):
+		return ScanSourceAlipay
+	case strings.Contains(ua, "bot") || strings.Contains(ua, "spider") || strings.Contains(ua, "crawler") || strings.Contains(ua, "curl/"):
+		return ScanSourceBot
+	case strings.Contains(ua, "mobile") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		return ScanSourceMobile
+	case strings.Contains(ua, "mozilla"):
+		return ScanSourceDesktop
+	}
+	return ScanSourceUnknown
+}
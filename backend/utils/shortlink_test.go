@@ -0,0 +1,54 @@
+package utils_test
+
+import (
+	"testing"
+
+	"tourism_recommendor/utils"
+)
+
+func TestScanSource(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 MicroMessenger/8.0.40", utils.ScanSourceWeChat},
+		{"Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 AlipayClient/10.5.0", utils.ScanSourceAlipay},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", utils.ScanSourceBot},
+		{"curl/8.4.0", utils.ScanSourceBot},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Mobile Safari/537.36", utils.ScanSourceMobile},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", utils.ScanSourceDesktop},
+		{"", utils.ScanSourceUnknown},
+		{"Go-http-client/1.1", utils.ScanSourceUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := utils.ScanSource(tt.userAgent); got != tt.want {
+			t.Errorf("ScanSource(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestNewShortCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := utils.NewShortCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != utils.ShortCodeLength || seen[code] {
+			t.Fatalf("code %q is too short or repeated", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestShortLinkURLs(t *testing.T) {
+	for _, baseURL := range []string{"https://example.com", "https://example.com/"} {
+		if got, want := utils.ShortLinkURL(baseURL, "abc"), "https://example.com/r/abc"; got != want {
+			t.Errorf("ShortLinkURL(%q) = %q, want %q", baseURL, got, want)
+		}
+		if got, want := utils.RecommendorWebURL(baseURL, 7), "https://example.com/recommendors/7"; got != want {
+			t.Errorf("RecommendorWebURL(%q) = %q, want %q", baseURL, got, want)
+		}
+	}
+}
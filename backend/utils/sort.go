@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SortKey is one column of an ORDER BY clause
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// InvalidSortError reports a sort field outside the resource's whitelist
+type InvalidSortError struct {
+	Field   string
+	Allowed []string
+}
+
+// Error implements the error interface
+func (e *InvalidSortError) Error() string {
+	return fmt.Sprintf("cannot sort by %q, allowed fields: %s", e.Field, strings.Join(e.Allowed, ", "))
+}
+
+// ParseSort parses a sort expression such as "-rating,name" into sort keys.
+// A leading "-" sorts descending; every field must be in allowed.
+func ParseSort(expr string, allowed []string) ([]SortKey, error) {
+	var keys []SortKey
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{Field: strings.TrimPrefix(part, "+")}
+		if strings.HasPrefix(part, "-") {
+			key = SortKey{Field: part[1:], Desc: true}
+		}
+
+		if !slices.Contains(allowed, key.Field) {
+			return nil, &InvalidSortError{Field: key.Field, Allowed: allowed}
+		}
+		if seen[key.Field] {
+			continue
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// LegacySort converts the sort_by and sort_order parameters into a sort expression
+func LegacySort(sortBy, sortOrder string) string {
+	if sortBy == "" {
+		sortBy = "id"
+	}
+	if strings.ToLower(sortOrder) == "desc" {
+		return "-" + sortBy
+	}
+	return sortBy
+}
+
+// ApplySort orders the query by the sort keys, then by id so that rows with equal keys
+// keep a stable order between pages
+func ApplySort(db *gorm.DB, keys []SortKey) *gorm.DB {
+	for _, key := range withIDTieBreaker(keys) {
+		db = db.Order(orderByColumn(key))
+	}
+	return db
+}
+
+// withIDTieBreaker returns the keys up to id, appending id when it is missing.
+// Keys after id never change the order since ids are unique.
+func withIDTieBreaker(keys []SortKey) []SortKey {
+	for i, key := range keys {
+		if key.Field == "id" {
+			return keys[:i+1]
+		}
+	}
+	return append(keys[:len(keys):len(keys)], SortKey{Field: "id"})
+}
+
+// orderByColumn builds a quoted ORDER BY column on the query's table
+func orderByColumn(key SortKey) clause.OrderByColumn {
+	return clause.OrderByColumn{
+		Column: clause.Column{Table: clause.CurrentTable, Name: key.Field},
+		Desc:   key.Desc,
+	}
+}
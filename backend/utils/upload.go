@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,7 +32,12 @@ var (
 	// Upload directories
 	UploadDir   = "./uploads"
 	AvatarDir   = "./uploads/avatars"
+	ImageDir    = "./uploads/images"
 	DocumentDir = "./uploads/documents"
+	QRCodeDir   = "./uploads/qrcodes"
+
+	// ExportDir holds generated export archives; it lies outside UploadDir, which is served publicly
+	ExportDir = "./exports"
 )
 
 // UploadConfig holds configuration for file uploads
@@ -142,6 +149,55 @@ func SaveFile(fileHeader *multipart.FileHeader, file multipart.File, directory s
 	}, nil
 }
 
+// ReadUpload reads the uploaded file a /uploads/ URL points to
+func ReadUpload(url string) ([]byte, error) {
+	rel, ok := strings.CutPrefix(url, "/uploads/")
+	if !ok {
+		return nil, fmt.Errorf("%q is not an upload URL", url)
+	}
+	path := filepath.Join(UploadDir, filepath.FromSlash(rel))
+	if !strings.HasPrefix(path, filepath.Clean(UploadDir)+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%q is outside the upload directory", url)
+	}
+	return os.ReadFile(path)
+}
+
+// WriteFile stores generated content under directory, replacing an existing file atomically
+func WriteFile(directory, filename string, data []byte) (*UploadResult, error) {
+	if err := EnsureDirectory(directory); err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first so readers never see a partial file
+	tmp, err := os.CreateTemp(directory, "."+filename+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+
+	filePath := filepath.Join(directory, filename)
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return &UploadResult{
+		FileName:    filename,
+		FilePath:    filePath,
+		FileSize:    int64(len(data)),
+		ContentType: http.DetectContentType(data),
+		URL:         strings.ReplaceAll(strings.TrimPrefix(filePath, "."), "\\", "/"),
+	}, nil
+}
+
 // ValidateAndSaveFile validates and saves an uploaded file
 func ValidateAndSaveFile(fileHeader *multipart.FileHeader, file multipart.File, config UploadConfig) (*UploadResult, error) {
 	// Validate file type
@@ -229,7 +285,7 @@ func DeleteOldFile(oldFilePath string) error {
 
 	if err := DeleteFile(oldFilePath); err != nil {
 		// Log but don't fail if old file doesn't exist
-		fmt.Printf("Warning: failed to delete old file: %v\n", err)
+		slog.Warn("failed to delete old file", "path", oldFilePath, "error", err)
 	}
 
 	return nil
@@ -254,7 +310,10 @@ func InitUploadDirectories() error {
 	directories := []string{
 		UploadDir,
 		AvatarDir,
+		ImageDir,
 		DocumentDir,
+		QRCodeDir,
+		ExportDir,
 	}
 
 	for _, dir := range directories {
@@ -0,0 +1,435 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"tourism_recommendor/logging"
+	"tourism_recommendor/metrics"
+	"tourism_recommendor/tracing"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// WeChat client defaults
+const (
+	DefaultWeChatBaseURL    = "https://api.weixin.qq.com"
+	DefaultWeChatTimeout    = 10 * time.Second
+	DefaultWeChatRetryDelay = 300 * time.Millisecond
+)
+
+// WeChat error codes the client reacts to
+const (
+	WeChatErrSystemBusy    = -1    // transient, retried
+	WeChatErrInvalidToken  = 40001 // access token invalid or replaced by a newer one
+	WeChatErrInvalidAccess = 40014 // malformed access token
+	WeChatErrTokenExpired  = 42001 // access token expired
+	WeChatErrInvalidCode   = 40029 // wx.login code unknown or expired
+	WeChatErrCodeUsed      = 40163 // wx.login code already exchanged
+)
+
+// WeChatClient calls the WeChat server APIs used by the application
+type WeChatClient interface {
+	// AccessToken returns the cached access token, fetching a new one when it has expired
+	AccessToken(ctx context.Context) (string, error)
+	// WxaCodeUnlimit draws the Mini Program code of page with scene, width pixels wide
+	WxaCodeUnlimit(ctx context.Context, page, scene string, width int) ([]byte, error)
+	// Code2Session exchanges the code of a wx.login call for the user's session
+	Code2Session(ctx context.Context, code string) (*WeChatSession, error)
+}
+
+// WeChatConfig configures an HTTPWeChatClient
+type WeChatConfig struct {
+	BaseURL    string        // API root, DefaultWeChatBaseURL when empty
+	AppID      string        // Mini Program AppID
+	AppSecret  string        // Mini Program AppSecret
+	Timeout    time.Duration // per request, DefaultWeChatTimeout when zero
+	Retries    int           // extra attempts after a transient failure
+	RetryDelay time.Duration // grows linearly with each attempt, DefaultWeChatRetryDelay when zero
+}
+
+// WeChatTokenResponse represents the response from WeChat token API
+type WeChatTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+}
+
+// WeChatSession is the Mini Program user a wx.login code was issued to
+type WeChatSession struct {
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid,omitempty"` // only when the Mini Program is bound to an Open Platform account
+	SessionKey string `json:"session_key"`
+}
+
+// WeChatSessionResponse represents the response from WeChat jscode2session API
+type WeChatSessionResponse struct {
+	WeChatSession
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// WeChatErrorResponse represents error response from WeChat API
+type WeChatErrorResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// WeChatError is an error code returned by the WeChat API
+type WeChatError struct {
+	Code    int
+	Message string
+}
+
+func (e *WeChatError) Error() string {
+	return fmt.Sprintf("WeChat API error [%d]: %s", e.Code, e.Message)
+}
+
+// TokenRejected reports whether WeChat refused the access token of the request
+func (e *WeChatError) TokenRejected() bool {
+	return e.Code == WeChatErrInvalidToken || e.Code == WeChatErrInvalidAccess || e.Code == WeChatErrTokenExpired
+}
+
+// CodeRejected reports whether WeChat refused a wx.login code
+func (e *WeChatError) CodeRejected() bool {
+	return e.Code == WeChatErrInvalidCode || e.Code == WeChatErrCodeUsed
+}
+
+// transientError is a failure worth retrying: a network error or a 5xx/429 response
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// cachedToken stores the access token with expiration time
+type cachedToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// HTTPWeChatClient is the WeChatClient of the WeChat HTTP API. It caches the access token
+// and lets concurrent callers share one token refresh.
+type HTTPWeChatClient struct {
+	config  WeChatConfig
+	http    *http.Client
+	refresh singleflight.Group
+
+	mu    sync.RWMutex
+	token cachedToken
+}
+
+// NewWeChatClient creates a WeChat API client
+func NewWeChatClient(cfg WeChatConfig) *HTTPWeChatClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultWeChatBaseURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultWeChatTimeout
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultWeChatRetryDelay
+	}
+	return &HTTPWeChatClient{
+		config: cfg,
+		http:   tracing.NewHTTPClient(&http.Client{Timeout: cfg.Timeout}),
+	}
+}
+
+// AccessToken returns the cached access token, fetching a new one when it has expired.
+// Concurrent callers wait for the same fetch.
+func (c *HTTPWeChatClient) AccessToken(ctx context.Context) (string, error) {
+	if token, ok := c.cachedToken(); ok {
+		return token, nil
+	}
+
+	// The fetch is shared, so one caller giving up must not cancel it for the others
+	fetch := c.refresh.DoChan("token", func() (interface{}, error) {
+		return c.fetchToken(context.WithoutCancel(ctx))
+	})
+	select {
+	case res := <-fetch:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		return res.Val.(string), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// WxaCodeUnlimit draws the Mini Program code of page with scene, width pixels wide
+// WeChat API endpoint: /wxa/getwxacodeunlimit
+func (c *HTTPWeChatClient) WxaCodeUnlimit(ctx context.Context, page, scene string, width int) ([]byte, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"page":       page,
+		"scene":      scene,
+		"width":      width,
+		"check_path": false, // Don't check if page exists (faster)
+		"auto_color": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %v", err)
+	}
+
+	logger := slog.With("component", "wechat", "endpoint", "getwxacodeunlimit")
+	logger.Debug("calling WeChat API", "page", page, "scene", scene)
+
+	code, err := c.withToken(ctx, func(token string) ([]byte, error) {
+		data, contentType, err := c.call(ctx, "getwxacodeunlimit", http.MethodPost, "/wxa/getwxacodeunlimit",
+			url.Values{"access_token": {token}}, body)
+		if err != nil {
+			return nil, err
+		}
+		// WeChat returns JSON on failure and the image on success
+		if err := weChatError(data); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(contentType, "image/") {
+			return nil, fmt.Errorf("unexpected content type: %s, expected image", contentType)
+		}
+		return data, nil
+	})
+	if err != nil {
+		logger.Error("WeChat API call failed", "error", err)
+		return nil, err
+	}
+
+	logger.Info("generated mini program QR code", "bytes", len(code))
+	return code, nil
+}
+
+// Code2Session exchanges the code of a wx.login call for the user's session. The code is
+// single-use, so the request is never repeated: WeChat may have spent the code on an attempt
+// whose response was lost, and the client has to call wx.login again.
+// WeChat API endpoint: /sns/jscode2session
+func (c *HTTPWeChatClient) Code2Session(ctx context.Context, code string) (*WeChatSession, error) {
+	logger := slog.With("component", "wechat", "endpoint", "jscode2session")
+
+	body, _, err := c.callOnce(ctx, "jscode2session", http.MethodGet, "/sns/jscode2session", url.Values{
+		"appid":      {c.config.AppID},
+		"secret":     {c.config.AppSecret},
+		"js_code":    {code},
+		"grant_type": {"authorization_code"},
+	}, nil)
+	if err != nil {
+		logger.Error("WeChat session request failed", "error", err)
+		return nil, err
+	}
+
+	// WeChat answers with JSON served as text/plain
+	var sessionResp WeChatSessionResponse
+	if err := json.Unmarshal(body, &sessionResp); err != nil {
+		logger.Error("failed to parse WeChat session response", "error", err)
+		return nil, fmt.Errorf("failed to parse session response: %v", err)
+	}
+	if sessionResp.ErrCode != 0 {
+		logger.Warn("WeChat session API returned error", "errcode", sessionResp.ErrCode, "errmsg", sessionResp.ErrMsg)
+		return nil, &WeChatError{Code: sessionResp.ErrCode, Message: sessionResp.ErrMsg}
+	}
+	if sessionResp.OpenID == "" {
+		return nil, errors.New("WeChat session response has no openid")
+	}
+	return &sessionResp.WeChatSession, nil
+}
+
+// withToken calls fn with the access token. When WeChat rejects the token, a new one is
+// fetched and fn is called once more.
+func (c *HTTPWeChatClient) withToken(ctx context.Context, fn func(token string) ([]byte, error)) ([]byte, error) {
+	token, err := c.AccessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	data, err := fn(token)
+	var apiErr *WeChatError
+	if !errors.As(err, &apiErr) || !apiErr.TokenRejected() {
+		return data, err
+	}
+
+	slog.Warn("WeChat rejected the access token, fetching a new one", "component", "wechat", "errcode", apiErr.Code)
+	c.invalidateToken(token)
+	if token, err = c.AccessToken(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	return fn(token)
+}
+
+// cachedToken returns the cached access token while it is valid
+func (c *HTTPWeChatClient) cachedToken() (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.token.token != "" && time.Now().Before(c.token.expiresAt) {
+		return c.token.token, true
+	}
+	return "", false
+}
+
+// invalidateToken drops token from the cache unless it has been replaced already
+func (c *HTTPWeChatClient) invalidateToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token.token == token {
+		c.token = cachedToken{}
+	}
+}
+
+// fetchToken retrieves an access token using the AppID and AppSecret and caches it
+// WeChat API endpoint: /cgi-bin/token
+func (c *HTTPWeChatClient) fetchToken(ctx context.Context) (string, error) {
+	// A caller that waited for the previous fetch may start a new one right after it
+	if token, ok := c.cachedToken(); ok {
+		return token, nil
+	}
+
+	logger := slog.With("component", "wechat", "endpoint", "token")
+	logger.Info("fetching new WeChat access token")
+
+	body, _, err := c.call(ctx, "token", http.MethodGet, "/cgi-bin/token", url.Values{
+		"grant_type": {"client_credential"},
+		"appid":      {c.config.AppID},
+		"secret":     {c.config.AppSecret},
+	}, nil)
+	if err != nil {
+		logger.Error("WeChat token request failed", "error", err)
+		return "", err
+	}
+
+	var tokenResp WeChatTokenResponse
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		logger.Error("failed to parse WeChat token response", "error", err)
+		return "", fmt.Errorf("failed to parse token response: %v", err)
+	}
+	if tokenResp.ErrCode != 0 {
+		logger.Error("WeChat token API returned error", "errcode", tokenResp.ErrCode, "errmsg", tokenResp.ErrMsg)
+		return "", &WeChatError{Code: tokenResp.ErrCode, Message: tokenResp.ErrMsg}
+	}
+
+	// Cache the token with a 5-minute buffer before expiration
+	expiresInSeconds := tokenResp.ExpiresIn
+	if expiresInSeconds <= 0 {
+		// Fallback if WeChat doesn't return expiration time
+		expiresInSeconds = 7200 // Default to 2 hours
+		logger.Warn("WeChat did not return expires_in, using default", "expires_in", expiresInSeconds)
+	}
+	expiresInSeconds -= 300
+	if expiresInSeconds < 60 {
+		// Ensure minimum cache time of 1 minute
+		expiresInSeconds = 60
+	}
+	expiresIn := time.Duration(expiresInSeconds) * time.Second
+
+	c.mu.Lock()
+	c.token = cachedToken{token: tokenResp.AccessToken, expiresAt: time.Now().Add(expiresIn)}
+	c.mu.Unlock()
+
+	logger.Info("fetched new WeChat access token",
+		"expires_in", tokenResp.ExpiresIn,
+		"cache_duration", expiresIn.String(),
+	)
+	return tokenResp.AccessToken, nil
+}
+
+// call sends a request to the WeChat API and returns the response body and content type,
+// retrying transient failures and WeChat's "system busy" error
+func (c *HTTPWeChatClient) call(ctx context.Context, endpoint, method, path string, query url.Values, body []byte) (data []byte, contentType string, err error) {
+	for attempt := 1; ; attempt++ {
+		data, contentType, err = c.callOnce(ctx, endpoint, method, path, query, body)
+		if err == nil || attempt > c.config.Retries || !retryable(err) || ctx.Err() != nil {
+			return data, contentType, err
+		}
+		slog.Warn("retrying WeChat API call", "component", "wechat", "endpoint", endpoint, "attempt", attempt, "error", err)
+
+		select {
+		case <-time.After(time.Duration(attempt) * c.config.RetryDelay):
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+}
+
+// callOnce sends a request to the WeChat API without retrying it and records the call's metrics.
+// WeChat's "system busy" error is returned as a *WeChatError.
+func (c *HTTPWeChatClient) callOnce(ctx context.Context, endpoint, method, path string, query url.Values, body []byte) ([]byte, string, error) {
+	start := time.Now()
+	data, contentType, err := c.send(ctx, method, path, query, body)
+	if err == nil {
+		err = weChatBusy(data)
+	}
+	metrics.ObserveWeChatCall(endpoint, start, err)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, contentType, nil
+}
+
+// send makes one request to the WeChat API
+func (c *HTTPWeChatClient) send(ctx context.Context, method, path string, query url.Values, body []byte) ([]byte, string, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+path+"?"+query.Encode(), reader)
+	if err != nil {
+		// The error embeds the request URL, which carries the access token or app secret
+		return nil, "", fmt.Errorf("failed to create WeChat API request: %s", logging.RedactString(err.Error()))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, "", &transientError{fmt.Errorf("failed to call WeChat API: %s", logging.RedactString(err.Error()))}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", &transientError{fmt.Errorf("failed to read response body: %v", err)}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, "", &transientError{fmt.Errorf("WeChat API returned HTTP %d", resp.StatusCode)}
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// retryable reports whether a failed call may succeed when repeated
+func retryable(err error) bool {
+	var transient *transientError
+	var apiErr *WeChatError
+	return errors.As(err, &transient) || (errors.As(err, &apiErr) && apiErr.Code == WeChatErrSystemBusy)
+}
+
+// weChatError returns the error of a JSON error response, or nil for any other body
+func weChatError(body []byte) error {
+	var errResp WeChatErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.ErrCode != 0 {
+		return &WeChatError{Code: errResp.ErrCode, Message: errResp.ErrMsg}
+	}
+	return nil
+}
+
+// weChatBusy returns the error of a "system busy" response, which is the only error code retried
+func weChatBusy(body []byte) error {
+	var apiErr *WeChatError
+	if err := weChatError(body); errors.As(err, &apiErr) && apiErr.Code == WeChatErrSystemBusy {
+		return err
+	}
+	return nil
+}
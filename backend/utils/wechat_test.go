@@ -0,0 +1,234 @@
+package utils_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/jpeg"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"tourism_recommendor/testutil"
+	"tourism_recommendor/utils"
+)
+
+func TestWeChatClientWxaCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(*testutil.WeChat)
+		errCode int  // expected WeChat error code, 0 when the call succeeds
+		failed  bool // the call fails without a WeChat error code
+		tokens  int
+		codes   int
+	}{
+		{name: "success", setup: func(*testutil.WeChat) {}, tokens: 1, codes: 1},
+		{
+			name:   "token endpoint unavailable",
+			setup:  func(w *testutil.WeChat) { w.FailWithStatus(testutil.WeChatTokenPath, 2, http.StatusServiceUnavailable) },
+			tokens: 3,
+			codes:  1,
+		},
+		{
+			name:   "system busy",
+			setup:  func(w *testutil.WeChat) { w.FailWithCode(testutil.WeChatWxaCodePath, 1, utils.WeChatErrSystemBusy) },
+			tokens: 1,
+			codes:  2,
+		},
+		{
+			name: "retries exhausted",
+			setup: func(w *testutil.WeChat) {
+				w.FailWithStatus(testutil.WeChatWxaCodePath, 3, http.StatusInternalServerError)
+			},
+			failed: true,
+			tokens: 1,
+			codes:  3,
+		},
+		{
+			name:   "invalid token",
+			setup:  func(w *testutil.WeChat) { w.FailWithCode(testutil.WeChatWxaCodePath, 1, utils.WeChatErrInvalidToken) },
+			tokens: 2,
+			codes:  2,
+		},
+		{
+			name:    "token rejected twice",
+			setup:   func(w *testutil.WeChat) { w.FailWithCode(testutil.WeChatWxaCodePath, 2, utils.WeChatErrTokenExpired) },
+			errCode: utils.WeChatErrTokenExpired,
+			tokens:  2,
+			codes:   2,
+		},
+		{
+			name:    "not retried",
+			setup:   func(w *testutil.WeChat) { w.FailWithCode(testutil.WeChatWxaCodePath, 1, 45009) },
+			errCode: 45009,
+			tokens:  1,
+			codes:   1,
+		},
+		{
+			name:    "wrong secret",
+			setup:   func(w *testutil.WeChat) { w.AppSecret = "other" },
+			errCode: 40125,
+			tokens:  1,
+			codes:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wechat := testutil.NewWeChat(t)
+			client := wechat.Client()
+			tt.setup(wechat)
+
+			code, err := client.WxaCodeUnlimit(context.Background(), "pages/recommendor/detail", "id=1", 200)
+
+			var apiErr *utils.WeChatError
+			switch {
+			case tt.errCode != 0:
+				if !errors.As(err, &apiErr) || apiErr.Code != tt.errCode {
+					t.Fatalf("err = %v, want WeChat error %d", err, tt.errCode)
+				}
+			case tt.failed:
+				if err == nil || errors.As(err, &apiErr) {
+					t.Fatalf("err = %v, want a transport error", err)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				config, err := jpeg.DecodeConfig(bytes.NewReader(code))
+				if err != nil || config.Width != 200 {
+					t.Fatalf("code is not a 200px JPEG: %v %+v", err, config)
+				}
+			}
+			if calls := wechat.Calls(testutil.WeChatTokenPath); calls != tt.tokens {
+				t.Fatalf("token calls = %d, want %d", calls, tt.tokens)
+			}
+			if calls := wechat.Calls(testutil.WeChatWxaCodePath); calls != tt.codes {
+				t.Fatalf("code calls = %d, want %d", calls, tt.codes)
+			}
+		})
+	}
+}
+
+func TestWeChatClientCode2Session(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     func(*testutil.WeChat) string
+		errCode  int  // expected WeChat error code, 0 when the call succeeds
+		rejected bool // the error reports an invalid code
+		failed   bool // the call fails without a WeChat error code
+		sessions int
+	}{
+		{name: "success", code: func(w *testutil.WeChat) string { return w.LoginCode("openid-1") }, sessions: 1},
+		// Login codes are single-use, so failed calls are not repeated
+		{
+			name: "system busy",
+			code: func(w *testutil.WeChat) string {
+				w.FailWithCode(testutil.WeChatSessionPath, 1, utils.WeChatErrSystemBusy)
+				return w.LoginCode("openid-1")
+			},
+			errCode:  utils.WeChatErrSystemBusy,
+			sessions: 1,
+		},
+		{
+			name: "server error",
+			code: func(w *testutil.WeChat) string {
+				w.FailWithStatus(testutil.WeChatSessionPath, 1, http.StatusInternalServerError)
+				return w.LoginCode("openid-1")
+			},
+			failed:   true,
+			sessions: 1,
+		},
+		{name: "unknown code", code: func(*testutil.WeChat) string { return "forged" }, errCode: utils.WeChatErrInvalidCode, rejected: true, sessions: 1},
+		{
+			name: "code used twice",
+			code: func(w *testutil.WeChat) string {
+				code := w.LoginCode("openid-1")
+				w.Client().Code2Session(context.Background(), code)
+				return code
+			},
+			errCode:  utils.WeChatErrInvalidCode,
+			rejected: true,
+			sessions: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wechat := testutil.NewWeChat(t)
+			code := tt.code(wechat)
+
+			session, err := wechat.Client().Code2Session(context.Background(), code)
+			var apiErr *utils.WeChatError
+			switch {
+			case tt.errCode != 0:
+				if !errors.As(err, &apiErr) || apiErr.Code != tt.errCode || apiErr.CodeRejected() != tt.rejected {
+					t.Fatalf("err = %v, want WeChat error %d (rejected code: %t)", err, tt.errCode, tt.rejected)
+				}
+			case tt.failed:
+				if err == nil || errors.As(err, &apiErr) {
+					t.Fatalf("err = %v, want a transport error", err)
+				}
+			default:
+				if err != nil || session.OpenID != "openid-1" || session.SessionKey == "" {
+					t.Fatalf("session = %+v, %v", session, err)
+				}
+			}
+			if calls := wechat.Calls(testutil.WeChatSessionPath); calls != tt.sessions {
+				t.Fatalf("session calls = %d, want %d", calls, tt.sessions)
+			}
+			// The session API is authenticated with the AppSecret, not an access token
+			if calls := wechat.Calls(testutil.WeChatTokenPath); calls != 0 {
+				t.Fatalf("token calls = %d, want 0", calls)
+			}
+		})
+	}
+}
+
+func TestWeChatClientSharesTokenRefresh(t *testing.T) {
+	wechat := testutil.NewWeChat(t)
+	wechat.SetLatency(50 * time.Millisecond)
+	client := wechat.Client()
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	errs := make([]error, len(tokens))
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = client.AccessToken(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range tokens {
+		if errs[i] != nil || tokens[i] != tokens[0] {
+			t.Fatalf("caller %d got %q, %v; want %q", i, tokens[i], errs[i], tokens[0])
+		}
+	}
+	if calls := wechat.Calls(testutil.WeChatTokenPath); calls != 1 {
+		t.Fatalf("token calls = %d, want 1", calls)
+	}
+}
+
+func TestWeChatClientCanceledCaller(t *testing.T) {
+	wechat := testutil.NewWeChat(t)
+	wechat.SetLatency(100 * time.Millisecond)
+	client := wechat.Client()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.AccessToken(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+
+	// The shared fetch carries on for the callers still waiting
+	if _, err := client.AccessToken(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if calls := wechat.Calls(testutil.WeChatTokenPath); calls != 1 {
+		t.Fatalf("token calls = %d, want 1", calls)
+	}
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"fmt"
+)
+
+// Mini Program pages QR codes lead to
+const (
+	WxappRecommendorPage = "pages/recommendor/detail"
+	WxappDestinationPage = "pages/destination/detail"
+)
+
+// MaxWxappSceneLength is the longest scene WeChat accepts in a Mini Program code
+const MaxWxappSceneLength = 32
+
+// RecommendorWxappPath returns the Mini Program path of the detail page of a recommendor
+func RecommendorWxappPath(minAppPath string, recommendorID uint) string {
+	return fmt.Sprintf("%s/%s?id=%d", minAppPath, WxappRecommendorPage, recommendorID)
+}
+
+// WxappScenePath returns the Mini Program path of a page opened with a scene; the part
+// after ? is passed to WeChat as the scene
+func WxappScenePath(minAppPath, page, scene string) string {
+	return fmt.Sprintf("%s/%s?%s", minAppPath, page, scene)
+}
+
+// WxappFallbackURL returns the web URL plain QR codes of a Mini Program path point to
+// when no WeChat API client can draw a real Mini Program code
+func WxappFallbackURL(appID, path string) string {
+	return fmt.Sprintf("https://open.weixin.qq.com/connect/qrconnect?appid=%s&path=%s", appID, path)
+}
@@ -26,7 +26,7 @@ const uploadFile = async (file, endpoint, fieldName = 'file') => {
     return response.data.data;
   } catch (error) {
     const errorMessage =
-      error.response?.data?.error ||
+      error.response?.data?.error?.message ||
       error.response?.data?.message ||
       '文件上传失败';
     message.error(errorMessage);
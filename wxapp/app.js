@@ -5,6 +5,8 @@ App({
     apiBaseUrl: "https://tourism-recommender-api.onrender.com/api",
     // 系统信息
     systemInfo: null,
+    // 游客 Token（wx.login 登录后获得）
+    touristToken: null,
   },
 
   onLaunch(options) {
@@ -15,6 +17,11 @@ App({
 
     // 处理启动参数（二维码扫码等）
     this.handleLaunchOptions(options);
+
+    // 游客登录，失败时不影响浏览公开内容
+    this.login().catch((error) => {
+      console.error("游客登录失败:", error);
+    });
   },
 
   onShow(options) {
@@ -37,6 +44,7 @@ App({
   handleLaunchOptions(options) {
     // 检查是否有推荐官详情页的参数
     // 二维码链接格式：/pages/recommendor/detail?id={id}
+    // 小程序码只带场景值 token（query.scene），由详情页解析
     const { scene, query, path } = options;
 
     let recommendorId = null;
@@ -96,9 +104,11 @@ App({
       ? url
       : this.globalData.apiBaseUrl + url;
 
-    // 构建请求头
+    // 构建请求头，登录后携带游客 Token
+    const { touristToken } = this.globalData;
     const header = {
       "content-type": "application/json",
+      ...(touristToken ? { Authorization: `Bearer ${touristToken}` } : {}),
       ...options.header,
     };
 
@@ -119,6 +129,11 @@ App({
             }
             resolve(res.data);
           } else {
+            // 游客 Token 失效时清除缓存，下次登录重新获取
+            if (res.statusCode === 401 && touristToken) {
+              this.clearTouristToken();
+            }
+
             const errorMsg = res.data.error || res.data.message || "请求失败";
 
             if (showToast) {
@@ -194,6 +209,64 @@ App({
     });
   },
 
+  // 获取目的地详情（公开接口）
+  getPublicDestinationDetail(id, options = {}) {
+    return this.request(`/destinations/${id}`, {
+      method: "GET",
+      ...options,
+    });
+  },
+
+  // 解析小程序码的场景值（扫码进入时 options.scene 为场景值 token）
+  resolveScene(scene, options = {}) {
+    const token = decodeURIComponent(scene);
+    // 早期印刷的小程序码直接以 id=123 作为场景值
+    const legacy = /^id=(\d+)$/.exec(token);
+    if (legacy) {
+      return Promise.resolve({
+        target: "recommendor",
+        page: "pages/recommendor/detail",
+        params: { id: legacy[1] },
+      });
+    }
+    return this.request(`/v1/wxapp/scene/${token}`, {
+      method: "GET",
+      ...options,
+    }).then((res) => res.data);
+  },
+
+  // 游客登录：用 wx.login 的 code 换取游客 Token，Token 过期前复用本地缓存
+  login() {
+    const cached = wx.getStorageSync("touristToken");
+    if (cached && cached.expires_at * 1000 > Date.now()) {
+      this.globalData.touristToken = cached.token;
+      return Promise.resolve(cached.token);
+    }
+    return new Promise((resolve, reject) => {
+      wx.login({ success: resolve, fail: reject });
+    })
+      .then(({ code }) =>
+        this.request("/v1/wxapp/login", {
+          method: "POST",
+          data: { code },
+          showLoading: false,
+          showToast: false,
+        })
+      )
+      .then((res) => {
+        const { token, expires_at } = res.data;
+        wx.setStorageSync("touristToken", { token, expires_at });
+        this.globalData.touristToken = token;
+        return token;
+      });
+  },
+
+  // 清除游客 Token
+  clearTouristToken() {
+    this.globalData.touristToken = null;
+    wx.removeStorageSync("touristToken");
+  },
+
   // 格式化日期
   formatDate(dateString) {
     const date = new Date(dateString);
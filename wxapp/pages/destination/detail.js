@@ -0,0 +1,173 @@
+// pages/destination/detail.js
+const app = getApp();
+
+Page({
+  /**
+   * 页面的初始数据
+   */
+  data: {
+    // 目的地 ID
+    destinationId: null,
+    // 目的地详情
+    destination: null,
+    // 目的地图片列表
+    images: [],
+    // 是否正在加载
+    loading: true,
+  },
+
+  /**
+   * 生命周期函数--监听页面加载
+   */
+  onLoad(options) {
+    console.log("目的地详情页加载", options);
+
+    if (options.id) {
+      this.showDestination(options.id);
+    } else if (options.scene) {
+      // 扫小程序码进入：场景值是服务端登记的 token，需先解析出页面参数
+      app
+        .resolveScene(options.scene)
+        .then((scene) => {
+          if (scene.target !== "destination") {
+            // 推荐官场景打开推荐官详情页
+            wx.redirectTo({
+              url: `/pages/recommendor/detail?id=${scene.params.id}`,
+            });
+            return;
+          }
+          this.showDestination(scene.params.id);
+        })
+        .catch(() => this.onInvalidParams());
+    } else {
+      this.onInvalidParams();
+    }
+  },
+
+  /**
+   * 加载指定目的地的详情
+   */
+  showDestination(id) {
+    this.setData({ destinationId: id });
+    this.loadDestinationDetail();
+  },
+
+  /**
+   * 参数错误时提示并返回
+   */
+  onInvalidParams() {
+    wx.showToast({
+      title: "参数错误",
+      icon: "none",
+    });
+    setTimeout(() => {
+      wx.navigateBack();
+    }, 1500);
+  },
+
+  /**
+   * 下拉刷新
+   */
+  onPullDownRefresh() {
+    this.loadDestinationDetail(false).then(() => {
+      wx.stopPullDownRefresh();
+    });
+  },
+
+  /**
+   * 加载目的地详情
+   */
+  loadDestinationDetail(showLoading = true) {
+    this.setData({ loading: true });
+
+    return app
+      .getPublicDestinationDetail(this.data.destinationId, { showLoading })
+      .then((destination) => {
+        this.setData({
+          destination: {
+            ...destination,
+            stars: this.getStarsArray(destination.rating || 0),
+          },
+          images: this.parseImages(destination.image),
+        });
+
+        // 设置导航栏标题
+        wx.setNavigationBarTitle({
+          title: destination.name || "目的地详情",
+        });
+      })
+      .catch((error) => {
+        console.error("加载目的地详情失败:", error);
+        wx.showToast({
+          title: "加载失败",
+          icon: "none",
+        });
+        // 返回上一页
+        setTimeout(() => {
+          wx.navigateBack();
+        }, 1500);
+      })
+      .finally(() => {
+        this.setData({ loading: false });
+      });
+  },
+
+  /**
+   * 解析图片字段（JSON 数组或单个地址）
+   * @param {string} image - 图片字段
+   * @returns {Array} 图片地址数组
+   */
+  parseImages(image) {
+    if (!image) return [];
+    try {
+      const images = JSON.parse(image);
+      return Array.isArray(images) ? images : [image];
+    } catch (error) {
+      return [image];
+    }
+  },
+
+  /**
+   * 获取星星数组
+   * @param {number} rating - 评分
+   * @returns {Array} 星星状态数组
+   */
+  getStarsArray(rating) {
+    const stars = [];
+    for (let i = 1; i <= 5; i++) {
+      if (rating >= i) {
+        stars.push({ filled: true, half: false });
+      } else if (rating >= i - 0.5) {
+        stars.push({ filled: false, half: true });
+      } else {
+        stars.push({ filled: false, half: false });
+      }
+    }
+    return stars;
+  },
+
+  /**
+   * 预览目的地图片
+   */
+  onPreviewImage(e) {
+    const { url } = e.currentTarget.dataset;
+    if (!url) return;
+
+    wx.previewImage({
+      current: url,
+      urls: this.data.images,
+    });
+  },
+
+  /**
+   * 查看推荐该目的地的推荐官
+   */
+  onViewRecommendor() {
+    const id = this.data.destination?.recommendor_id;
+    if (!id) return;
+
+    wx.navigateTo({
+      url: `/pages/recommendor/detail?id=${id}`,
+    });
+  },
+});
@@ -34,20 +34,49 @@ Page({
 
     // 获取推荐官 ID
     if (options.id) {
-      this.setData({ recommendorId: options.id });
-      this.loadRecommendorDetail();
-      this.loadDestinations();
+      this.showRecommendor(options.id);
+    } else if (options.scene) {
+      // 扫小程序码进入：场景值是服务端登记的 token，需先解析出页面参数
+      app
+        .resolveScene(options.scene)
+        .then((scene) => {
+          if (scene.target === "destination") {
+            // 目的地场景打开目的地详情页
+            wx.redirectTo({
+              url: `/pages/destination/detail?id=${scene.params.id}`,
+            });
+            return;
+          }
+          this.showRecommendor(scene.params.id);
+        })
+        .catch(() => this.onInvalidParams());
     } else {
-      wx.showToast({
-        title: "参数错误",
-        icon: "none",
-      });
-      setTimeout(() => {
-        wx.navigateBack();
-      }, 1500);
+      this.onInvalidParams();
     }
   },
 
+  /**
+   * 加载指定推荐官的详情和目的地
+   */
+  showRecommendor(id) {
+    this.setData({ recommendorId: id });
+    this.loadRecommendorDetail();
+    this.loadDestinations();
+  },
+
+  /**
+   * 参数错误时提示并返回
+   */
+  onInvalidParams() {
+    wx.showToast({
+      title: "参数错误",
+      icon: "none",
+    });
+    setTimeout(() => {
+      wx.navigateBack();
+    }, 1500);
+  },
+
   /**
    * 生命周期函数--监听页面初次渲染完成
    */
@@ -279,17 +308,9 @@ Page({
     const { id } = e.currentTarget.dataset;
     if (!id) return;
 
-    // 如果需要跳转到目的地详情页，可以使用以下代码
-    // 目前暂时只显示提示
-    wx.showToast({
-      title: "目的地详情功能开发中",
-      icon: "none",
+    wx.navigateTo({
+      url: `/pages/destination/detail?id=${id}`,
     });
-
-    // 如果有目的地详情页，使用以下代码
-    // wx.navigateTo({
-    //   url: `/pages/destination/detail?id=${id}`
-    // });
   },
 
   /**
@@ -14,6 +14,8 @@ Page({
     currentPage: 1,
     // 每页数量
     pageSize: 12,
+    // 下一页游标（游标分页，加载更多时不会因新增数据而错位）
+    nextCursor: "",
     // 是否正在加载
     loading: false,
     // 是否还有更多数据
@@ -97,7 +99,8 @@ Page({
         .then((response) => {
           const recommendors = response.data || [];
           const total = response.total || 0;
-          const hasMore = recommendors.length >= this.data.pageSize;
+          const nextCursor = response.next_cursor || "";
+          const hasMore = !!nextCursor;
 
           // 为每个推荐官计算星星状态
           const recommendorsWithStars = recommendors.map((item) => {
@@ -114,6 +117,7 @@ Page({
           this.setData({
             recommendors: recommendorsWithStars,
             total,
+            nextCursor,
             hasMore,
           });
           resolve(response);
@@ -143,13 +147,16 @@ Page({
     this.setData({ loading: true });
 
     const params = this.buildRequestParams();
-    params.page = nextPage;
+    params.cursor = this.data.nextCursor;
+    // 总数已在首页获取
+    params.with_total = false;
 
     app
       .getPublicRecommendors(params, { showLoading: false })
       .then((response) => {
         const newRecommendors = response.data || [];
-        const hasMore = newRecommendors.length >= this.data.pageSize;
+        const nextCursor = response.next_cursor || "";
+        const hasMore = !!nextCursor;
 
         // 为新加载的推荐官计算星星状态
         const recommendorsWithStars = newRecommendors.map((item) => {
@@ -166,6 +173,7 @@ Page({
         this.setData({
           recommendors: [...this.data.recommendors, ...recommendorsWithStars],
           currentPage: nextPage,
+          nextCursor,
           hasMore,
         });
       })
@@ -181,10 +189,14 @@ Page({
    * 构建请求参数
    */
   buildRequestParams() {
+    // 游标分页：首页不带游标，之后使用上一页返回的 next_cursor
     const params = {
-      page: this.data.currentPage,
+      pagination: "cursor",
+      with_total: true,
       page_size: this.data.pageSize,
       status: this.data.filters.status,
+      // 只取列表展示的字段，不下载二维码等大字段
+      fields: "id,name,avatar,bio,rating,region_address,status",
     };
 
     // 添加名称筛选